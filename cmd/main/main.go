@@ -1,23 +1,77 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/aventhis/avito_pvz_service/internal/api"
 	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/config"
+	"github.com/aventhis/avito_pvz_service/internal/events"
+	"github.com/aventhis/avito_pvz_service/internal/metrics"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/ratelimit"
+	"github.com/aventhis/avito_pvz_service/internal/scheduler"
 	"github.com/aventhis/avito_pvz_service/internal/storage/postgres"
+	"github.com/aventhis/avito_pvz_service/internal/webhooks"
 )
 
 func main() {
-	// Получаем переменные окружения
-	dbURL := getEnv("DB_URL", "postgres://postgres:postgres@db:5432/postgres?sslmode=disable")
-	jwtSecret := getEnv("JWT_SECRET", "your-secret-key")
-	port := getEnv("PORT", "8080")
+	// Файл конфигурации задается флагом -config или переменной CONFIG_FILE
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "путь к YAML-файлу конфигурации")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Использование: %s [-config файл] [config print]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	printConfig := len(args) == 2 && args[0] == "config" && args[1] == "print"
+	if len(args) > 0 && !printConfig {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*configPath)
+	var validationErr *config.ValidationError
+	if err != nil && !(printConfig && errors.As(err, &validationErr)) {
+		log.Fatalf("Ошибка в конфигурации: %v", err)
+	}
+
+	if printConfig {
+		// Итоговая конфигурация с учетом файла и переменных окружения, без секретов; ошибки проверки
+		// выводятся после нее
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Ошибка при выводе конфигурации: %v", err)
+		}
+		if validationErr != nil {
+			log.Fatalf("Ошибка в конфигурации: %v", validationErr)
+		}
+		return
+	}
 
+	run(cfg)
+}
+
+// run запускает сервис с настройками cfg
+func run(cfg *config.Config) {
 	// Инициализируем хранилище
-	storage, err := postgres.New(dbURL)
+	storage, err := postgres.New(cfg.Database.URL, postgres.PoolConfig{
+		MaxOpenConns:      cfg.Database.MaxOpenConns,
+		MaxIdleConns:      cfg.Database.MaxIdleConns,
+		ConnMaxLifetime:   cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime:   cfg.Database.ConnMaxIdleTime,
+		StatementTimeout:  cfg.Database.StatementTimeout,
+		ConnectAttempts:   cfg.Database.ConnectAttempts,
+		ConnectBackoff:    cfg.Database.ConnectBackoff,
+		ConnectMaxBackoff: cfg.Database.ConnectMaxBackoff,
+	})
 	if err != nil {
 		log.Fatalf("Ошибка при инициализации хранилища: %v", err)
 	}
@@ -28,24 +82,117 @@ func main() {
 		log.Fatalf("Ошибка при инициализации базы данных: %v", err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Запускаем публикацию событий из outbox: события распределяются по подпискам на вебхуки
+	// и, если задан издатель, передаются ему
+	var publishers []events.Publisher
+	if cfg.Features.Webhooks {
+		publishers = append(publishers, webhooks.NewDispatcher(storage))
+	}
+	publisher, err := newEventPublisher(cfg.Events)
+	if err != nil {
+		log.Fatalf("Ошибка при настройке публикации событий: %v", err)
+	}
+	if publisher != nil {
+		publishers = append(publishers, publisher)
+	}
+	relay := scheduler.NewOutboxRelay(storage, events.Multi(publishers...), scheduler.SystemClock{}, cfg.Events.OutboxRelayInterval)
+	go relay.Run(ctx)
+
+	// Запускаем отправку вебхуков с повторами
+	if cfg.Features.Webhooks {
+		deliveryWorker := scheduler.NewWebhookDeliveryWorker(storage, webhooks.NewSender(cfg.Webhooks.Timeout), scheduler.SystemClock{}, cfg.Webhooks.DeliveryInterval)
+		go deliveryWorker.Run(ctx)
+	}
+
+	// Запускаем удаление истекших ключей идемпотентности
+	if cfg.Features.Idempotency {
+		idempotencyCleaner := scheduler.NewIdempotencyKeyCleaner(storage, scheduler.SystemClock{}, cfg.Idempotency.CleanupInterval)
+		go idempotencyCleaner.Run(ctx)
+	}
+
 	// Инициализируем сервис аутентификации
-	authService := auth.New(jwtSecret)
+	authService := auth.New(cfg.Auth.JWTSecret)
+	authService.SetTokenLifetimes(cfg.Auth.TokenTTL, cfg.Auth.DummyTokenTTL)
 
 	// Инициализируем API
 	apiService := api.New(storage, authService)
+	apiService.Configure(api.Settings{
+		AllowedCities:     cfg.AllowedCities,
+		IdempotencyKeyTTL: cfg.Idempotency.KeyTTL,
+		DummyLogin:        cfg.Features.DummyLogin,
+		LiveStream:        cfg.Features.LiveStream,
+		Webhooks:          cfg.Features.Webhooks,
+		Idempotency:       cfg.Features.Idempotency,
+	})
+
+	// Запускаем автоматическое закрытие зависших приемок; нулевой возраст отключает его.
+	// Закрытые приемки публикуются в поток активности, как и закрытые сотрудником
+	if cfg.Receptions.StaleAge > 0 {
+		systemStorage := storage.WithActor(models.Actor{UserID: "system", Role: "system"})
+		closer := scheduler.NewStaleReceptionCloser(systemStorage, scheduler.SystemClock{}, cfg.Receptions.StaleAge, cfg.Receptions.StaleCheckInterval)
+		closer.SetLivePublisher(apiService)
+		go closer.Run(ctx)
+	}
+
+	// Включаем ограничение частоты запросов; правила уже проверены при загрузке конфигурации
+	if cfg.RateLimit.Enabled {
+		rules, err := ratelimit.ParseRules(cfg.RateLimit.Rules)
+		if err != nil {
+			log.Fatalf("Ошибка в rate_limit.rules: %v", err)
+		}
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if cfg.RateLimit.Store == "postgres" {
+			store = storage
+		}
+		apiService.SetRateLimiter(ratelimit.NewLimiter(store, rules))
+	}
+
+	// Метрики отдаются рядом с API, в обход ограничения частоты запросов
+	var handler http.Handler = apiService
+	if cfg.Metrics.Enabled {
+		registry := metrics.NewRegistry()
+		metrics.RegisterDBStats(registry, storage.Stats)
+
+		mux := http.NewServeMux()
+		mux.Handle(cfg.Metrics.Path, registry)
+		mux.Handle("/", apiService)
+		handler = mux
+	}
 
 	// Запускаем сервер
-	log.Printf("Сервер запущен на http://localhost:%s", port)
-	if err := http.ListenAndServe(":"+port, apiService); err != nil {
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:           handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	log.Printf("Сервер запущен на http://localhost:%d", cfg.Server.Port)
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatalf("Ошибка при запуске сервера: %v", err)
 	}
 }
 
-// getEnv получает значение переменной окружения или возвращает значение по умолчанию
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// newEventPublisher создает издателя событий по настройкам: none, stdout, file или webhook
+func newEventPublisher(cfg config.EventsConfig) (events.Publisher, error) {
+	switch cfg.Publisher {
+	case "none":
+		return nil, nil
+	case "stdout":
+		return events.NewWriterPublisher(os.Stdout), nil
+	case "file":
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		return events.NewWriterPublisher(file), nil
+	case "webhook":
+		return events.NewWebhookPublisher(cfg.WebhookURL, cfg.WebhookTimeout), nil
+	default:
+		return nil, fmt.Errorf("неизвестный издатель событий %q", cfg.Publisher)
 	}
-	return value
-} 
\ No newline at end of file
+}
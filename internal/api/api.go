@@ -2,15 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/events"
 	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/ratelimit"
 	"github.com/aventhis/avito_pvz_service/internal/storage"
 )
 
@@ -19,6 +20,9 @@ type API struct {
 	router  *mux.Router
 	storage storage.Storage
 	auth    *auth.Auth
+	live    *events.Bus // поток активности приемок для табло
+	limiter  *ratelimit.Limiter
+	settings Settings
 }
 
 // New создает новый экземпляр API
@@ -27,6 +31,8 @@ func New(storage storage.Storage, auth *auth.Auth) *API {
 		router:  mux.NewRouter(),
 		storage: storage,
 		auth:    auth,
+		live:     events.NewBus(liveHistorySize),
+		settings: DefaultSettings(),
 	}
 
 	api.setupRoutes()
@@ -36,24 +42,81 @@ func New(storage storage.Storage, auth *auth.Auth) *API {
 // setupRoutes настраивает маршруты API
 func (a *API) setupRoutes() {
 	// Аутентификация
-	a.router.HandleFunc("/dummyLogin", a.handleDummyLogin).Methods(http.MethodPost)
+	if a.settings.DummyLogin {
+		a.router.HandleFunc("/dummyLogin", a.handleDummyLogin).Methods(http.MethodPost)
+	}
 	a.router.HandleFunc("/register", a.handleRegister).Methods(http.MethodPost)
 	a.router.HandleFunc("/login", a.handleLogin).Methods(http.MethodPost)
 
 	// ПВЗ
 	a.router.HandleFunc("/pvz", a.handleCreatePVZ).Methods(http.MethodPost)
 	a.router.HandleFunc("/pvz", a.handleGetPVZList).Methods(http.MethodGet)
+	a.router.HandleFunc("/pvz/nearby", a.handleGetNearbyPVZ).Methods(http.MethodGet)
+	a.router.HandleFunc("/pvz/export", a.handleExportPVZ).Methods(http.MethodGet)
+	a.router.HandleFunc("/pvz/{pvzId}", a.handleGetPVZ).Methods(http.MethodGet)
+	a.router.HandleFunc("/pvz/{pvzId}", a.handleUpdatePVZ).Methods(http.MethodPatch)
 	a.router.HandleFunc("/pvz/{pvzId}/close_last_reception", a.handleCloseLastReception).Methods(http.MethodPost)
 	a.router.HandleFunc("/pvz/{pvzId}/delete_last_product", a.handleDeleteLastProduct).Methods(http.MethodPost)
+	a.router.HandleFunc("/pvz/{pvzId}/status", a.handleUpdatePVZStatus).Methods(http.MethodPost)
 
 	// Приемки и товары
 	a.router.HandleFunc("/receptions", a.handleCreateReception).Methods(http.MethodPost)
+	if a.settings.LiveStream {
+		a.router.HandleFunc("/receptions/live", a.handleLiveStream).Methods(http.MethodGet)
+	}
+	a.router.HandleFunc("/receptions/{receptionId}", a.handleGetReception).Methods(http.MethodGet)
+	a.router.HandleFunc("/receptions/{receptionId}/product_changes", a.handleGetProductChanges).Methods(http.MethodGet)
+	a.router.HandleFunc("/receptions/{receptionId}/reopen", a.handleReopenReception).Methods(http.MethodPost)
+	a.router.HandleFunc("/receptions/{receptionId}/cancel", a.handleCancelReception).Methods(http.MethodPost)
 	a.router.HandleFunc("/products", a.handleCreateProduct).Methods(http.MethodPost)
+	a.router.HandleFunc("/products/batch", a.handleCreateProductBatch).Methods(http.MethodPost)
+	a.router.HandleFunc("/products/{productId}", a.handleGetProduct).Methods(http.MethodGet)
+	a.router.HandleFunc("/products/{productId}", a.handleUpdateProduct).Methods(http.MethodPatch)
+	a.router.HandleFunc("/products/{productId}", a.handleDeleteProduct).Methods(http.MethodDelete)
+
+	// Возвраты
+	a.router.HandleFunc("/return_batches", a.handleCreateReturnBatch).Methods(http.MethodPost)
+	a.router.HandleFunc("/return_batches", a.handleGetReturnBatches).Methods(http.MethodGet)
+	a.router.HandleFunc("/pvz/{pvzId}/close_last_return_batch", a.handleCloseLastReturnBatch).Methods(http.MethodPost)
+	a.router.HandleFunc("/returns", a.handleCreateReturn).Methods(http.MethodPost)
+
+	// Ячейки хранения
+	a.router.HandleFunc("/pvz/{pvzId}/cells", a.handleCreateCell).Methods(http.MethodPost)
+	a.router.HandleFunc("/pvz/{pvzId}/cells", a.handleGetCells).Methods(http.MethodGet)
+	a.router.HandleFunc("/pvz/{pvzId}/cells/suggest", a.handleSuggestCell).Methods(http.MethodGet)
+	a.router.HandleFunc("/products/{productId}/cell", a.handleAssignProductCell).Methods(http.MethodPost)
+	a.router.HandleFunc("/products/{productId}/location", a.handleGetProductLocation).Methods(http.MethodGet)
+
+	// Перемещения между ПВЗ
+	a.router.HandleFunc("/transfers", a.handleCreateTransfer).Methods(http.MethodPost)
+	a.router.HandleFunc("/transfers/{transferId}", a.handleGetTransfer).Methods(http.MethodGet)
+	a.router.HandleFunc("/transfers/{transferId}/ship", a.handleShipTransfer).Methods(http.MethodPost)
+	a.router.HandleFunc("/transfers/{transferId}/receive", a.handleReceiveTransfer).Methods(http.MethodPost)
+
+	// Статистика
+	a.router.HandleFunc("/stats", a.handleGetStats).Methods(http.MethodGet)
+
+	// Журнал аудита
+	a.router.HandleFunc("/audit", a.handleGetAuditLog).Methods(http.MethodGet)
+
+	// Вебхуки
+	if a.settings.Webhooks {
+		a.router.HandleFunc("/webhooks", a.handleCreateWebhook).Methods(http.MethodPost)
+		a.router.HandleFunc("/webhooks", a.handleGetWebhooks).Methods(http.MethodGet)
+		a.router.HandleFunc("/webhooks/dead_letters", a.handleGetWebhookDeadLetters).Methods(http.MethodGet)
+		a.router.HandleFunc("/webhooks/deliveries/{deliveryId}/redeliver", a.handleRedeliverWebhook).Methods(http.MethodPost)
+		a.router.HandleFunc("/webhooks/{webhookId}", a.handleDeleteWebhook).Methods(http.MethodDelete)
+		a.router.HandleFunc("/webhooks/{webhookId}/deliveries", a.handleGetWebhookDeliveries).Methods(http.MethodGet)
+	}
 }
 
 // ServeHTTP обслуживает HTTP-запросы
 func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	a.router.ServeHTTP(w, r)
+	r = withRequestID(w, r)
+	if !a.allowRequest(w, r) {
+		return
+	}
+	a.serveIdempotent(w, r, a.router)
 }
 
 // getTokenFromHeader извлекает токен из заголовка Authorization
@@ -90,6 +153,11 @@ func (a *API) respondWithError(w http.ResponseWriter, code int, message string)
 	a.respondWithJSON(w, code, models.Error{Message: message})
 }
 
+// respondWithValidationError отправляет 400 с ошибками по отдельным полям запроса
+func (a *API) respondWithValidationError(w http.ResponseWriter, fieldErrors []models.FieldError) {
+	a.respondWithJSON(w, http.StatusBadRequest, models.Error{Message: "Неверные параметры запроса", Details: fieldErrors})
+}
+
 // handleDummyLogin обрабатывает запрос на тестовую авторизацию
 func (a *API) handleDummyLogin(w http.ResponseWriter, r *http.Request) {
 	var req models.DummyLoginRequest
@@ -98,7 +166,7 @@ func (a *API) handleDummyLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := a.auth.GenerateDummyToken(req.Role)
+	token, err := a.auth.GenerateDummyTokenForPVZ(req.Role, req.PVZID)
 	if err != nil {
 		a.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
@@ -121,14 +189,27 @@ func (a *API) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// К ПВЗ можно привязать только сотрудника
+	if req.PVZID != "" {
+		if req.Role != "employee" {
+			a.respondWithError(w, http.StatusBadRequest, "К ПВЗ можно привязать только сотрудника")
+			return
+		}
+		if _, err := a.storage.GetPVZByID(req.PVZID); err != nil {
+			a.respondWithError(w, http.StatusBadRequest, "ПВЗ не найден")
+			return
+		}
+	}
+
 	// Создаем пользователя
 	user := &models.User{
 		Email:    req.Email,
 		Password: a.auth.HashPassword(req.Password),
 		Role:     req.Role,
+		PVZID:    req.PVZID,
 	}
 
-	if err := a.storage.CreateUser(user); err != nil {
+	if err := a.storageFor(r).CreateUser(user); err != nil {
 		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при создании пользователя")
 		return
 	}
@@ -185,13 +266,19 @@ func (a *API) handleCreatePVZ(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Проверяем город
-	if pvz.City != "Москва" && pvz.City != "Санкт-Петербург" && pvz.City != "Казань" {
-		a.respondWithError(w, http.StatusBadRequest, "ПВЗ можно создать только в городах: Москва, Санкт-Петербург, Казань")
+	if !a.isAllowedCity(pvz.City) {
+		a.respondWithError(w, http.StatusBadRequest, "ПВЗ можно создать только в городах: "+a.allowedCitiesList())
+		return
+	}
+
+	// Проверяем адрес, координаты и часы работы
+	if err := validatePVZLocation(&pvz); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Создаем ПВЗ
-	if err := a.storage.CreatePVZ(&pvz); err != nil {
+	if err := a.storageFor(r).CreatePVZ(&pvz); err != nil {
 		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при создании ПВЗ")
 		return
 	}
@@ -208,48 +295,47 @@ func (a *API) handleGetPVZList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Параметры пагинации и фильтрации
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-	startDateStr := r.URL.Query().Get("startDate")
-	endDateStr := r.URL.Query().Get("endDate")
-
-	page := 1
-	limit := 10
-
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+	query := r.URL.Query()
+	filter, fieldErrors := a.parsePVZListQuery(query)
+	if len(fieldErrors) > 0 {
+		a.respondWithValidationError(w, fieldErrors)
+		return
 	}
 
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 30 {
-			limit = l
-		}
+	// Параметр cursor включает обход по курсору; для первой страницы он передается пустым
+	if query.Has("cursor") {
+		a.respondWithPVZCursorPage(w, filter)
+		return
 	}
 
-	var startDate, endDate *time.Time
-	if startDateStr != "" {
-		if t, err := time.Parse(time.RFC3339, startDateStr); err == nil {
-			startDate = &t
-		}
+	pvzList, err := a.storage.GetPVZList(filter)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении списка ПВЗ")
+		return
+	}
+	if pvzList == nil {
+		pvzList = []models.PVZListItem{}
 	}
 
-	if endDateStr != "" {
-		if t, err := time.Parse(time.RFC3339, endDateStr); err == nil {
-			endDate = &t
-		}
+	// Без явного запроса конверта отвечаем массивом, как раньше
+	if !wantsPVZListEnvelope(r) {
+		a.respondWithJSON(w, http.StatusOK, pvzList)
+		return
 	}
 
-	// Получаем список ПВЗ
-	pvzList, err := a.storage.GetPVZList(startDate, endDate, page, limit)
+	total, err := a.storage.CountPVZList(filter)
 	if err != nil {
 		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении списка ПВЗ")
 		return
 	}
 
-	a.respondWithJSON(w, http.StatusOK, pvzList)
+	a.respondWithJSON(w, http.StatusOK, models.PVZListPage{
+		Items:   pvzList,
+		Page:    filter.Page,
+		Limit:   filter.Limit,
+		Total:   total,
+		HasMore: filter.Page*filter.Limit < total,
+	})
 }
 
 // handleCreateReception обрабатывает запрос на создание приемки
@@ -267,10 +353,9 @@ func (a *API) handleCreateReception(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем существование ПВЗ
-	pvz, err := a.storage.GetPVZByID(req.PVZID)
-	if err != nil {
-		a.respondWithError(w, http.StatusBadRequest, "ПВЗ не найден")
+	// Проверяем, что ПВЗ существует и работает
+	pvz, ok := a.requireActivePVZ(w, req.PVZID)
+	if !ok {
 		return
 	}
 
@@ -279,10 +364,11 @@ func (a *API) handleCreateReception(w http.ResponseWriter, r *http.Request) {
 		PVZID: pvz.ID,
 	}
 
-	if err := a.storage.CreateReception(reception); err != nil {
+	if err := a.storageFor(r).CreateReception(reception); err != nil {
 		a.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	a.publishLive("ReceptionOpened", reception.ID)
 
 	a.respondWithJSON(w, http.StatusCreated, reception)
 }
@@ -308,10 +394,11 @@ func (a *API) handleCloseLastReception(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Закрываем приемку
-	if err := a.storage.CloseReception(reception.ID); err != nil {
+	if err := a.storageFor(r).CloseReception(reception.ID); err != nil {
 		a.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	a.publishLive("ReceptionClosed", reception.ID)
 
 	// Обновляем статус в объекте
 	reception.Status = "close"
@@ -339,6 +426,11 @@ func (a *API) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Проверяем, что ПВЗ работает
+	if _, ok := a.requireActivePVZ(w, req.PVZID); !ok {
+		return
+	}
+
 	// Получаем последнюю приемку для ПВЗ
 	reception, err := a.storage.GetLastReceptionByPVZID(req.PVZID)
 	if err != nil {
@@ -352,16 +444,27 @@ func (a *API) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Создаем товар
+	// Создаем товар; если указана ячейка, товар размещается в ней в той же транзакции
 	product := &models.Product{
 		Type:        req.Type,
 		ReceptionID: reception.ID,
+		CellID:      req.CellID,
 	}
 
-	if err := a.storage.CreateProduct(product); err != nil {
+	if err := a.storageFor(r).CreateProduct(product); err != nil {
+		if isCellPlacementError(err) {
+			a.respondWithCellPlacementError(w, err)
+			return
+		}
+		// Приемку могли закрыть между проверкой и добавлением товара
+		if errors.Is(err, storage.ErrReceptionClosed) {
+			a.respondWithError(w, http.StatusBadRequest, "Приемка уже закрыта")
+			return
+		}
 		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при добавлении товара")
 		return
 	}
+	a.publishLive("ProductAdded", reception.ID, *product)
 
 	a.respondWithJSON(w, http.StatusCreated, product)
 }
@@ -379,6 +482,11 @@ func (a *API) handleDeleteLastProduct(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	pvzID := vars["pvzId"]
 
+	// Проверяем, что ПВЗ работает
+	if _, ok := a.requireActivePVZ(w, pvzID); !ok {
+		return
+	}
+
 	// Получаем последнюю приемку
 	reception, err := a.storage.GetLastReceptionByPVZID(pvzID)
 	if err != nil {
@@ -393,10 +501,12 @@ func (a *API) handleDeleteLastProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Удаляем последний товар
-	if err := a.storage.DeleteLastProductInReception(reception.ID); err != nil {
+	product, err := a.storageFor(r).DeleteLastProductInReception(reception.ID)
+	if err != nil {
 		a.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	a.publishLive("ProductDeleted", reception.ID, *product)
 
-	a.respondWithJSON(w, http.StatusOK, struct{}{})
+	a.respondWithJSON(w, http.StatusOK, product)
 } 
\ No newline at end of file
@@ -252,8 +252,13 @@ func TestGetPVZList_InvalidParams(t *testing.T) {
 	// Обрабатываем запрос
 	api.ServeHTTP(rr, req)
 
-	// Проверяем статус код - должен быть 200, так как используются значения по умолчанию
-	assert.Equal(t, http.StatusOK, rr.Code)
+	// Проверяем статус код - некорректные параметры отклоняются с ошибками по полям
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var response models.Error
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Details, 2)
 }
 
 // TestGetPVZList_WithDateFilter проверяет получение списка ПВЗ с фильтрацией по дате
@@ -803,8 +808,47 @@ func TestDeleteLastProduct(t *testing.T) {
 	// Обрабатываем запрос
 	api.ServeHTTP(rr, req)
 
-	// Проверяем статус код
+	// Проверяем статус код и удаленный товар в ответе
 	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var deleted models.Product
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deleted))
+	assert.Equal(t, product.ID, deleted.ID)
+}
+
+// TestDeleteLastProduct_LIFOOrder проверяет удаление товаров в обратном порядке добавления,
+// даже если время добавления совпадает
+func TestDeleteLastProduct_LIFOOrder(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+
+	// Пакет получает одно время добавления, порядок задают номера товаров
+	products := []*models.Product{{Type: "электроника"}, {Type: "одежда"}, {Type: "обувь"}}
+	mockStorage.CreateProducts(reception.ID, products)
+	single := &models.Product{Type: "одежда", ReceptionID: reception.ID}
+	mockStorage.CreateProduct(single)
+	assert.Equal(t, 4, single.Sequence)
+
+	expected := []string{single.ID, products[2].ID, products[1].ID, products[0].ID}
+	for _, id := range expected {
+		req := httptest.NewRequest(http.MethodPost, "/pvz/"+pvz.ID+"/delete_last_product", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var deleted models.Product
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deleted))
+		assert.Equal(t, id, deleted.ID)
+	}
 }
 
 // TestDeleteLastProduct_Forbidden проверяет запрет удаления товара для неавторизованного пользователя
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	// requestIDHeader заголовок с идентификатором запроса
+	requestIDHeader = "X-Request-ID"
+
+	// defaultAuditLimit и maxAuditLimit число записей журнала в ответе по умолчанию и максимум
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// requestIDKey ключ идентификатора запроса в контексте
+type requestIDKey struct{}
+
+// withRequestID берет идентификатор запроса из заголовка или создает новый и возвращает его в ответе
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	requestID := r.Header.Get(requestIDHeader)
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	w.Header().Set(requestIDHeader, requestID)
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID))
+}
+
+// requestIDFromContext возвращает идентификатор текущего запроса
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// storageFor возвращает хранилище, записывающее изменения от имени пользователя запроса в журнал аудита
+func (a *API) storageFor(r *http.Request) storage.Storage {
+	actor := models.Actor{RequestID: requestIDFromContext(r.Context())}
+	if claims, err := a.auth.ValidateToken(a.getTokenFromHeader(r)); err == nil {
+		actor.UserID = claims.UserID
+		actor.Role = claims.Role
+	}
+	return a.storage.WithActor(actor)
+}
+
+// handleGetAuditLog обрабатывает запрос модератора на просмотр журнала аудита
+func (a *API) handleGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		EntityType: query.Get("entityType"),
+		EntityID:   query.Get("entityId"),
+		UserID:     query.Get("userId"),
+		Limit:      defaultAuditLimit,
+	}
+
+	var fieldErrors []models.FieldError
+	if value := query.Get("startDate"); value != "" {
+		startDate, err := parseFilterDate(value, false)
+		if err != nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "startDate", Message: "ожидается дата в формате RFC3339 или ГГГГ-ММ-ДД"})
+		} else {
+			filter.StartDate = &startDate
+		}
+	}
+	if value := query.Get("endDate"); value != "" {
+		endDate, err := parseFilterDate(value, true)
+		if err != nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "endDate", Message: "ожидается дата в формате RFC3339 или ГГГГ-ММ-ДД"})
+		} else {
+			filter.EndDate = &endDate
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "limit", Message: "должен быть целым числом от 1 до " + strconv.Itoa(maxAuditLimit)})
+		} else {
+			filter.Limit = limit
+		}
+	}
+	if len(fieldErrors) > 0 {
+		a.respondWithValidationError(w, fieldErrors)
+		return
+	}
+
+	entries, err := a.storage.GetAuditLog(filter)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении журнала аудита")
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	a.respondWithJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// getAuditLog запрашивает журнал аудита с указанными параметрами
+func getAuditLog(api *API, token, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/audit"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestAuditLog_RecordsMutations проверяет запись изменений с пользователем, ролью и идентификатором запроса
+func TestAuditLog_RecordsMutations(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+
+	// Создание ПВЗ с заданным идентификатором запроса
+	body, _ := json.Marshal(models.PVZ{City: "Москва"})
+	req := httptest.NewRequest(http.MethodPost, "/pvz", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+	req.Header.Set(requestIDHeader, "request-1")
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "request-1", rr.Header().Get(requestIDHeader))
+	var pvz models.PVZ
+	json.Unmarshal(rr.Body.Bytes(), &pvz)
+
+	// Открытие приемки без заголовка - идентификатор создается сервером
+	body, _ = json.Marshal(models.ReceptionRequest{PVZID: pvz.ID})
+	req = httptest.NewRequest(http.MethodPost, "/receptions", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	generatedID := rr.Header().Get(requestIDHeader)
+	assert.NotEmpty(t, generatedID)
+
+	rr = getAuditLog(api, moderatorToken, "?entityType=pvz&entityId="+pvz.ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var entries []models.AuditEntry
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &entries))
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "pvz.create", entries[0].Action)
+	assert.Equal(t, "moderator", entries[0].Role)
+	assert.Equal(t, "dummy-user", entries[0].UserID)
+	assert.Equal(t, "request-1", entries[0].RequestID)
+	assert.Empty(t, entries[0].Before)
+	assert.NotEmpty(t, entries[0].After)
+
+	rr = getAuditLog(api, moderatorToken, "?entityType=reception")
+	entries = nil
+	json.Unmarshal(rr.Body.Bytes(), &entries)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "reception.create", entries[0].Action)
+	assert.Equal(t, "employee", entries[0].Role)
+	assert.Equal(t, generatedID, entries[0].RequestID)
+
+	// Новые записи первыми, limit ограничивает выдачу
+	rr = getAuditLog(api, moderatorToken, "?limit=1")
+	entries = nil
+	json.Unmarshal(rr.Body.Bytes(), &entries)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "reception.create", entries[0].Action)
+
+	// Фильтр по периоду
+	rr = getAuditLog(api, moderatorToken, "?endDate=2000-01-01")
+	entries = nil
+	json.Unmarshal(rr.Body.Bytes(), &entries)
+	assert.Empty(t, entries)
+}
+
+// TestAuditLog_Validation проверяет доступ только для модераторов и проверку параметров
+func TestAuditLog_Validation(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+
+	rr := getAuditLog(api, employeeToken, "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = getAuditLog(api, moderatorToken, "?limit=0&startDate=вчера")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var response models.Error
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Len(t, response.Details, 2)
+
+	rr = getAuditLog(api, moderatorToken, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "[]", rr.Body.String())
+}
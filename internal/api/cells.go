@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// handleCreateCell обрабатывает запрос на создание ячейки хранения
+func (a *API) handleCreateCell(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	pvzID := mux.Vars(r)["pvzId"]
+
+	var req models.StorageCellRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	req.Code = strings.TrimSpace(req.Code)
+	if req.Code == "" || req.Capacity <= 0 {
+		a.respondWithError(w, http.StatusBadRequest, "Необходимо указать код ячейки и положительную вместимость")
+		return
+	}
+
+	if _, err := a.storage.GetPVZByID(pvzID); err != nil {
+		a.respondWithLookupError(w, err, "ПВЗ не найден")
+		return
+	}
+
+	cell := &models.StorageCell{
+		PVZID:    pvzID,
+		Code:     req.Code,
+		Capacity: req.Capacity,
+	}
+
+	if err := a.storageFor(r).CreateCell(cell); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Ошибка при создании ячейки: возможно, код уже занят")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusCreated, cell)
+}
+
+// handleGetCells обрабатывает запрос на получение ячеек хранения ПВЗ
+func (a *API) handleGetCells(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRoleAny(token, "employee", "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	cells, err := a.storage.GetCellsByPVZID(mux.Vars(r)["pvzId"])
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении ячеек")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, cells)
+}
+
+// handleSuggestCell обрабатывает запрос на подбор свободной ячейки
+func (a *API) handleSuggestCell(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "employee"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	cell, err := a.storage.SuggestCell(mux.Vars(r)["pvzId"])
+	if err != nil {
+		a.respondWithError(w, http.StatusNotFound, "Нет свободных ячеек")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, cell)
+}
+
+// handleAssignProductCell обрабатывает запрос на размещение товара в ячейке
+func (a *API) handleAssignProductCell(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "employee"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	productID := mux.Vars(r)["productId"]
+
+	var req models.CellAssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CellID == "" {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	if err := a.storageFor(r).AssignProductToCell(productID, req.CellID); err != nil {
+		a.respondWithCellPlacementError(w, err)
+		return
+	}
+
+	a.respondWithProductLocation(w, productID)
+}
+
+// isCellPlacementError сообщает, что товар нельзя разместить в выбранной ячейке
+func isCellPlacementError(err error) bool {
+	return errors.Is(err, storage.ErrCellNotFound) || errors.Is(err, storage.ErrCellOtherPVZ) || errors.Is(err, storage.ErrCellFull)
+}
+
+// respondWithCellPlacementError отправляет ответ на ошибку размещения товара в ячейке
+func (a *API) respondWithCellPlacementError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrCellNotFound), errors.Is(err, storage.ErrCellOtherPVZ):
+		a.respondWithError(w, http.StatusBadRequest, "Ячейка не найдена")
+	case errors.Is(err, storage.ErrCellFull):
+		a.respondWithError(w, http.StatusBadRequest, "Ячейка заполнена")
+	case errors.Is(err, storage.ErrNotFound):
+		a.respondWithError(w, http.StatusNotFound, "Товар не найден")
+	default:
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при размещении товара")
+	}
+}
+
+// handleGetProductLocation обрабатывает запрос на поиск товара в ПВЗ
+func (a *API) handleGetProductLocation(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRoleAny(token, "employee", "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	a.respondWithProductLocation(w, mux.Vars(r)["productId"])
+}
+
+// respondWithProductLocation отправляет местонахождение товара
+func (a *API) respondWithProductLocation(w http.ResponseWriter, productID string) {
+	product, err := a.storage.GetProductByID(productID)
+	if err != nil {
+		a.respondWithLookupError(w, err, "Товар не найден")
+		return
+	}
+
+	cell, err := a.storage.GetProductCell(productID)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при поиске ячейки товара")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, models.ProductLocation{
+		Product: *product,
+		Cell:    cell,
+	})
+}
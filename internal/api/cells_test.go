@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStorageCellsFlow проверяет создание ячеек, подбор свободной ячейки и поиск товара
+func TestStorageCellsFlow(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+
+	// Модератор создает две ячейки разной вместимости
+	var cells []models.StorageCell
+	for _, req := range []models.StorageCellRequest{{Code: "A-01", Capacity: 1}, {Code: "B-01", Capacity: 3}} {
+		body, _ := json.Marshal(req)
+		httpReq := httptest.NewRequest(http.MethodPost, "/pvz/"+pvz.ID+"/cells", bytes.NewReader(body))
+		httpReq.Header.Set("Authorization", "Bearer "+moderatorToken)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httpReq)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		var cell models.StorageCell
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &cell))
+		cells = append(cells, cell)
+	}
+
+	// Добавляем товар сразу в ячейку A-01
+	body, _ := json.Marshal(models.ProductRequest{Type: "обувь", PVZID: pvz.ID, CellID: cells[0].ID})
+	req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var product models.Product
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &product))
+
+	// Ячейка A-01 заполнена - второй товар туда не поместится
+	req = httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Ячейка заполнена")
+
+	// Товар, не поместившийся в ячейку, не добавлен в приемку
+	products, _ := mockStorage.GetProductsByReceptionID(reception.ID)
+	assert.Len(t, products, 1)
+
+	// Подбор предлагает ячейку с наибольшим свободным местом
+	req = httptest.NewRequest(http.MethodGet, "/pvz/"+pvz.ID+"/cells/suggest", nil)
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var suggested models.StorageCell
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &suggested))
+	assert.Equal(t, "B-01", suggested.Code)
+
+	// Поиск товара на стойке выдачи
+	req = httptest.NewRequest(http.MethodGet, "/products/"+product.ID+"/location", nil)
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var location models.ProductLocation
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &location))
+	assert.Equal(t, product.ID, location.Product.ID)
+	if assert.NotNil(t, location.Cell) {
+		assert.Equal(t, "A-01", location.Cell.Code)
+		assert.Equal(t, 1, location.Cell.Occupied)
+	}
+
+	// Перемещаем товар в другую ячейку
+	body, _ = json.Marshal(models.CellAssignmentRequest{CellID: cells[1].ID})
+	req = httptest.NewRequest(http.MethodPost, "/products/"+product.ID+"/cell", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &location))
+	assert.Equal(t, "B-01", location.Cell.Code)
+}
+
+// TestCreateCell_Forbidden проверяет, что ячейки создает только модератор
+func TestCreateCell_Forbidden(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+
+	body, _ := json.Marshal(models.StorageCellRequest{Code: "A-01", Capacity: 5})
+	req := httptest.NewRequest(http.MethodPost, "/pvz/"+pvz.ID+"/cells", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/export"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// exportBatchSize количество ПВЗ, читаемых из хранилища за один запрос при выгрузке
+const exportBatchSize = 100
+
+// exportHeader заголовок выгрузки истории приемок
+var exportHeader = []string{
+	"pvz_id", "pvz_city", "pvz_status", "pvz_registration_date",
+	"reception_id", "reception_date", "reception_status",
+	"product_id", "product_date", "product_type",
+}
+
+// handleExportPVZ обрабатывает запрос на выгрузку ПВЗ с приемками и товарами в CSV или XLSX
+func (a *API) handleExportPVZ(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRoleAny(token, "employee", "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	query := r.URL.Query()
+	filter, fieldErrors := a.parsePVZListQuery(query)
+
+	format, ok := exportFormat(r)
+	if !ok {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "format", Message: "допустимые значения: csv, xlsx"})
+	}
+
+	if len(fieldErrors) > 0 {
+		a.respondWithValidationError(w, fieldErrors)
+		return
+	}
+
+	// Выгружаются все ПВЗ под фильтром: пагинация списка не применяется
+	filter.After = nil
+	filter.Page = 1
+	filter.Limit = exportBatchSize
+
+	contentType := export.ContentTypeCSV
+	if format == export.FormatXLSX {
+		contentType = export.ContentTypeXLSX
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="pvz_export_`+time.Now().Format("20060102")+`.`+format+`"`)
+
+	writer, err := export.NewWriter(format, w)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при подготовке выгрузки")
+		return
+	}
+
+	if err := a.streamPVZExport(w, writer, filter); err != nil {
+		// Заголовки уже отправлены, поэтому прерываем выгрузку без смены статуса
+		log.Printf("Ошибка при выгрузке ПВЗ: %v", err)
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Printf("Ошибка при завершении выгрузки ПВЗ: %v", err)
+	}
+}
+
+// streamPVZExport пишет строки выгрузки, читая ПВЗ из хранилища порциями по курсору
+func (a *API) streamPVZExport(w http.ResponseWriter, writer export.RowWriter, filter models.PVZListFilter) error {
+	if err := writer.WriteRow(exportHeader); err != nil {
+		return err
+	}
+
+	flusher, _ := w.(http.Flusher)
+
+	for {
+		pvzList, err := a.storage.GetPVZList(filter)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range pvzList {
+			if err := writePVZRows(writer, item); err != nil {
+				return err
+			}
+		}
+
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if len(pvzList) < filter.Limit {
+			return nil
+		}
+
+		last := pvzList[len(pvzList)-1].PVZ
+		filter.After = &models.PVZCursor{RegistrationDate: last.RegistrationDate, ID: last.ID}
+	}
+}
+
+// writePVZRows пишет строки одного ПВЗ: по строке на товар, а для приемок без товаров и ПВЗ без приемок - одну строку
+func writePVZRows(writer export.RowWriter, item models.PVZListItem) error {
+	pvz := []string{item.PVZ.ID, item.PVZ.City, item.PVZ.Status, item.PVZ.RegistrationDate.Format(time.RFC3339)}
+
+	if len(item.Receptions) == 0 {
+		return writer.WriteRow(append(pvz, "", "", "", "", "", ""))
+	}
+
+	for _, reception := range item.Receptions {
+		receptionColumns := append(append([]string{}, pvz...),
+			reception.Reception.ID, reception.Reception.DateTime.Format(time.RFC3339), reception.Reception.Status)
+
+		if len(reception.Products) == 0 {
+			if err := writer.WriteRow(append(receptionColumns, "", "", "")); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, product := range reception.Products {
+			row := append(append([]string{}, receptionColumns...), product.ID, product.DateTime.Format(time.RFC3339), product.Type)
+			if err := writer.WriteRow(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// exportFormat определяет формат выгрузки по параметру format или заголовку Accept; по умолчанию CSV
+func exportFormat(r *http.Request) (string, bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format, format == export.FormatCSV || format == export.FormatXLSX
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, export.ContentTypeXLSX) {
+		return export.FormatXLSX, true
+	}
+	return export.FormatCSV, true
+}
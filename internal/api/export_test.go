@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/export"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExportPVZ проверяет выгрузку истории приемок в CSV и XLSX
+func TestExportPVZ(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("moderator")
+
+	// Больше ПВЗ, чем помещается в одну порцию чтения
+	for i := 0; i < exportBatchSize+5; i++ {
+		mockStorage.CreatePVZ(&models.PVZ{City: "Москва"})
+	}
+
+	kazan := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(kazan)
+	reception := &models.Reception{PVZID: kazan.ID}
+	mockStorage.CreateReception(reception)
+	mockStorage.CreateProduct(&models.Product{Type: "обувь", ReceptionID: reception.ID})
+	mockStorage.CreateProduct(&models.Product{Type: "одежда", ReceptionID: reception.ID})
+
+	exportRequest := func(query, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/pvz/export?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := exportRequest("", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, export.ContentTypeCSV, rr.Header().Get("Content-Type"))
+
+	rows, err := csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	assert.NoError(t, err)
+	// Заголовок, по строке на каждый ПВЗ без приемок и по строке на каждый товар
+	assert.Len(t, rows, 1+exportBatchSize+5+2)
+	assert.Equal(t, exportHeader, rows[0])
+
+	// Фильтры списка применяются и к выгрузке
+	rr = exportRequest("city=Казань&productType=обувь", "")
+	rows, err = csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "обувь", rows[1][9])
+
+	// Формат выбирается через Accept
+	rr = exportRequest("city=Казань", export.ContentTypeXLSX)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, export.ContentTypeXLSX, rr.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rr.Body.String(), "PK"))
+
+	rr = exportRequest("format=pdf", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
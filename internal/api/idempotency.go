@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+const (
+	// idempotencyKeyHeader заголовок с ключом идемпотентности запроса
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotentReplayedHeader помечает ответ, повторенный из сохраненного
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength максимальная длина ключа идемпотентности
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyLockTimeout время, на которое ключ закрепляется за выполняющимся запросом. Если запрос не сохранил
+	// ответ и не освободил ключ (например, процесс завершился), повтор занимает ключ после истечения этого времени
+	idempotencyLockTimeout = time.Minute
+)
+
+// idempotencyRecorder пишет ответ клиенту и одновременно запоминает его для сохранения
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader запоминает код ответа
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Write запоминает тело ответа
+func (rec *idempotencyRecorder) Write(data []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
+
+// idempotencyRequestHash хэширует метод, путь и тело запроса, чтобы отличать повтор от другого запроса с тем же ключом
+func idempotencyRequestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// serveIdempotent обслуживает POST-запрос с ключом идемпотентности: первый ответ сохраняется и повторяется
+// для повторов с тем же ключом и телом. Запросы без ключа или без действительного токена обслуживаются как обычно
+func (a *API) serveIdempotent(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if !a.settings.Idempotency || r.Method != http.MethodPost || key == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	// Ключи хранятся отдельно для каждого пользователя
+	claims, err := a.auth.ValidateToken(a.getTokenFromHeader(r))
+	if err != nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		a.respondWithValidationError(w, []models.FieldError{{Field: idempotencyKeyHeader, Message: "ключ не длиннее 255 символов"}})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	now := time.Now()
+	record := &models.IdempotencyRecord{
+		UserID:      claims.UserID,
+		Key:         key,
+		RequestHash: idempotencyRequestHash(r, body),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(a.settings.IdempotencyKeyTTL),
+		LockedUntil: now.Add(idempotencyLockTimeout),
+	}
+
+	existing, reserved, err := a.storage.ReserveIdempotencyKey(record)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при проверке ключа идемпотентности")
+		return
+	}
+	if !reserved {
+		switch {
+		case existing.RequestHash != record.RequestHash:
+			a.respondWithError(w, http.StatusUnprocessableEntity, "Ключ идемпотентности уже использован для другого запроса")
+		case existing.StatusCode == 0:
+			a.respondWithError(w, http.StatusConflict, "Запрос с этим ключом идемпотентности еще выполняется")
+		default:
+			if existing.ContentType != "" {
+				w.Header().Set("Content-Type", existing.ContentType)
+			}
+			w.Header().Set(idempotentReplayedHeader, "true")
+			w.WriteHeader(existing.StatusCode)
+			w.Write(existing.Body)
+		}
+		return
+	}
+
+	// Если обработчик не дошел до сохранения ответа, ключ освобождается, чтобы запрос можно было повторить
+	completed := false
+	defer func() {
+		if !completed {
+			if err := a.storage.DeleteIdempotencyKey(record); err != nil {
+				log.Printf("Ошибка при освобождении ключа идемпотентности: %v", err)
+			}
+		}
+	}()
+
+	rec := &idempotencyRecorder{ResponseWriter: w}
+	next.ServeHTTP(rec, r)
+
+	// Ошибки сервера не сохраняются: повтор запроса может завершиться успешно
+	if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+		return
+	}
+
+	record.StatusCode = rec.status
+	record.ContentType = rec.Header().Get("Content-Type")
+	record.Body = rec.body.Bytes()
+	if err := a.storage.CompleteIdempotencyKey(record); err != nil {
+		log.Printf("Ошибка при сохранении ответа по ключу идемпотентности: %v", err)
+		return
+	}
+	completed = true
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// postWithIdempotencyKey отправляет POST-запрос с ключом идемпотентности
+func postWithIdempotencyKey(api *API, token, path, key string, payload interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestIdempotency_ReplaysResponse проверяет, что повтор с тем же ключом и телом возвращает первый ответ без повторного создания
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	first := postWithIdempotencyKey(api, moderatorToken, "/pvz", "key-1", models.PVZ{City: "Москва"})
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(idempotentReplayedHeader))
+
+	second := postWithIdempotencyKey(api, moderatorToken, "/pvz", "key-1", models.PVZ{City: "Москва"})
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, "true", second.Header().Get(idempotentReplayedHeader))
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+
+	pvzList, _ := mockStorage.GetPVZList(models.PVZListFilter{Page: 1, Limit: 10})
+	assert.Len(t, pvzList, 1)
+
+	// Другой ключ создает новый ПВЗ, запрос без ключа обслуживается как обычно
+	third := postWithIdempotencyKey(api, moderatorToken, "/pvz", "key-2", models.PVZ{City: "Москва"})
+	assert.Equal(t, http.StatusCreated, third.Code)
+	assert.NotEqual(t, first.Body.String(), third.Body.String())
+	fourth := postWithIdempotencyKey(api, moderatorToken, "/pvz", "", models.PVZ{City: "Москва"})
+	assert.Equal(t, http.StatusCreated, fourth.Code)
+
+	pvzList, _ = mockStorage.GetPVZList(models.PVZListFilter{Page: 1, Limit: 10})
+	assert.Len(t, pvzList, 3)
+}
+
+// TestIdempotency_DifferentBody проверяет ответ 422 на другой запрос с уже использованным ключом
+func TestIdempotency_DifferentBody(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	rr := postWithIdempotencyKey(api, moderatorToken, "/pvz", "key-1", models.PVZ{City: "Москва"})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	rr = postWithIdempotencyKey(api, moderatorToken, "/pvz", "key-1", models.PVZ{City: "Казань"})
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	// Тот же ключ на другом пути - тоже другой запрос
+	rr = postWithIdempotencyKey(api, moderatorToken, "/receptions", "key-1", models.ReceptionRequest{PVZID: "pvz-id"})
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
+
+// TestIdempotency_StoresClientErrors проверяет повтор ошибок клиента, выполняющиеся и истекшие ключи
+func TestIdempotency_StoresClientErrors(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+
+	// Приемка для несуществующего ПВЗ - ответ сохраняется и повторяется
+	first := postWithIdempotencyKey(api, employeeToken, "/receptions", "key-1", models.ReceptionRequest{PVZID: "unknown"})
+	assert.True(t, first.Code >= 400 && first.Code < 500)
+	second := postWithIdempotencyKey(api, employeeToken, "/receptions", "key-1", models.ReceptionRequest{PVZID: "unknown"})
+	assert.Equal(t, first.Code, second.Code)
+	assert.Equal(t, "true", second.Header().Get(idempotentReplayedHeader))
+
+	// Ключ, за которым еще выполняется такой же запрос, отклоняется с 409
+	body, _ := json.Marshal(models.ReceptionRequest{PVZID: "unknown"})
+	req := httptest.NewRequest(http.MethodPost, "/receptions", bytes.NewReader(body))
+	now := time.Now()
+	mockStorage.ReserveIdempotencyKey(&models.IdempotencyRecord{
+		UserID:      "dummy-user",
+		Key:         "key-2",
+		RequestHash: idempotencyRequestHash(req, body),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+		LockedUntil: now.Add(time.Minute),
+	})
+	rr := postWithIdempotencyKey(api, employeeToken, "/receptions", "key-2", models.ReceptionRequest{PVZID: "unknown"})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	// Ключ запроса, который не завершился до истечения резерва, занимает повтор
+	mockStorage.ReserveIdempotencyKey(&models.IdempotencyRecord{
+		UserID:      "dummy-user",
+		Key:         "key-3",
+		RequestHash: idempotencyRequestHash(req, body),
+		CreatedAt:   now.Add(-2 * time.Minute),
+		ExpiresAt:   now.Add(time.Hour),
+		LockedUntil: now.Add(-time.Minute),
+	})
+	rr = postWithIdempotencyKey(api, employeeToken, "/receptions", "key-3", models.ReceptionRequest{PVZID: "unknown"})
+	assert.Equal(t, first.Code, rr.Code)
+	assert.Empty(t, rr.Header().Get(idempotentReplayedHeader))
+
+	// Истекший ключ занимается заново
+	mockStorage.DeleteExpiredIdempotencyKeys(now.Add(2 * time.Hour))
+	rr = postWithIdempotencyKey(api, employeeToken, "/receptions", "key-2", models.ReceptionRequest{PVZID: "unknown"})
+	assert.Equal(t, first.Code, rr.Code)
+	assert.Empty(t, rr.Header().Get(idempotentReplayedHeader))
+}
+
+// TestIdempotency_Validation проверяет слишком длинный ключ и запросы без токена
+func TestIdempotency_Validation(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	rr := postWithIdempotencyKey(api, moderatorToken, "/pvz", strings.Repeat("k", maxIdempotencyKeyLength+1), models.PVZ{City: "Москва"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Без действительного токена ключ не учитывается, обработчик отвечает сам
+	rr = postWithIdempotencyKey(api, "invalid", "/pvz", "key-1", models.PVZ{City: "Москва"})
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = postWithIdempotencyKey(api, "invalid", "/pvz", "key-1", models.PVZ{City: "Казань"})
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/events"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+const (
+	// liveHistorySize число последних событий, доступных для возобновления потока
+	liveHistorySize = 1000
+
+	// liveHeartbeatInterval период комментариев, не дающих прокси закрыть неактивное соединение
+	liveHeartbeatInterval = 15 * time.Second
+)
+
+// publishLive отправляет событие приемки или ее товаров в поток активности.
+// Табло не должно влиять на операцию, поэтому ошибки только записываются в лог
+func (a *API) publishLive(eventType, receptionID string, products ...models.Product) {
+	reception, err := a.storage.GetReceptionByID(receptionID)
+	if err != nil {
+		log.Printf("Ошибка при публикации события %s приемки %s: %v", eventType, receptionID, err)
+		return
+	}
+
+	event := models.LiveEvent{
+		Type:      eventType,
+		DateTime:  time.Now(),
+		PVZID:     reception.PVZID,
+		Reception: reception,
+		Products:  products,
+	}
+	if pvz, err := a.storage.GetPVZByID(reception.PVZID); err == nil {
+		event.City = pvz.City
+	}
+	if current, err := a.storage.GetProductsByReceptionID(reception.ID); err == nil {
+		event.ProductCount = len(current)
+	}
+
+	a.live.Publish(event)
+}
+
+// PublishLive отправляет событие приемки в поток активности; используется фоновыми задачами, изменяющими приемки
+func (a *API) PublishLive(eventType, receptionID string) {
+	a.publishLive(eventType, receptionID)
+}
+
+// handleLiveStream обрабатывает подписку на поток активности приемок (Server-Sent Events)
+func (a *API) handleLiveStream(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRoleAny(token, "employee", "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	query := r.URL.Query()
+	pvzID := query.Get("pvzId")
+	city := query.Get("city")
+
+	var fieldErrors []models.FieldError
+	if city != "" && !a.isAllowedCity(city) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "city", Message: "допустимые значения: " + a.allowedCitiesList()})
+	}
+
+	// Браузер передает номер последнего полученного события в заголовке при переподключении
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("lastEventId")
+	}
+	var afterID uint64
+	if lastEventID != "" {
+		var err error
+		if afterID, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "Last-Event-ID", Message: "ожидается номер события"})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		a.respondWithValidationError(w, fieldErrors)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		a.respondWithError(w, http.StatusInternalServerError, "Потоковая передача не поддерживается")
+		return
+	}
+
+	replay, messages, unsubscribe := a.live.Subscribe(afterID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	matches := func(event models.LiveEvent) bool {
+		return (pvzID == "" || event.PVZID == pvzID) && (city == "" || event.City == city)
+	}
+
+	for _, message := range replay {
+		if matches(message.Event) {
+			writeLiveMessage(w, message)
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case message, ok := <-messages:
+			// Канал закрыт: клиент не успевал читать и переподключится с Last-Event-ID
+			if !ok {
+				return
+			}
+			if matches(message.Event) {
+				writeLiveMessage(w, message)
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// writeLiveMessage записывает событие в формате Server-Sent Events
+func writeLiveMessage(w http.ResponseWriter, message events.LiveMessage) {
+	data, err := json.Marshal(message.Event)
+	if err != nil {
+		log.Printf("Ошибка при маршалинге события: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.ID, message.Event.Type, data)
+}
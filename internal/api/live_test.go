@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/scheduler"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// liveMessage событие, прочитанное из потока
+type liveMessage struct {
+	id    string
+	event string
+	data  models.LiveEvent
+}
+
+// openLiveStream подключается к потоку активности и возвращает канал прочитанных событий
+func openLiveStream(t *testing.T, server *httptest.Server, token, query, lastEventID string) (<-chan liveMessage, func()) {
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/receptions/live"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка при подключении к потоку: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	messages := make(chan liveMessage, 16)
+	go func() {
+		defer close(messages)
+		scanner := bufio.NewScanner(resp.Body)
+		var message liveMessage
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				message.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				message.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &message.data)
+			case line == "" && message.id != "":
+				messages <- message
+				message = liveMessage{}
+			}
+		}
+	}()
+
+	return messages, func() { resp.Body.Close() }
+}
+
+// nextLiveMessage ждет очередное событие потока
+func nextLiveMessage(t *testing.T, messages <-chan liveMessage) liveMessage {
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("событие не получено")
+		return liveMessage{}
+	}
+}
+
+// postJSON отправляет POST-запрос к API
+func postJSON(api *API, token, path string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestLiveStream проверяет поток событий приемок с фильтром по городу и возобновление по Last-Event-ID
+func TestLiveStream(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+
+	moscow := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(moscow)
+	kazan := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(kazan)
+
+	messages, closeStream := openLiveStream(t, server, moderatorToken, "?city=Москва", "")
+
+	// События ПВЗ в Казани в поток не попадают
+	postJSON(api, employeeToken, "/receptions", models.ReceptionRequest{PVZID: kazan.ID})
+	postJSON(api, employeeToken, "/receptions", models.ReceptionRequest{PVZID: moscow.ID})
+	postJSON(api, employeeToken, "/products", models.ProductRequest{PVZID: moscow.ID, Type: "обувь"})
+	postJSON(api, employeeToken, "/products/batch", models.ProductBatchRequest{PVZID: moscow.ID,
+		Products: []models.ProductBatchItem{{Type: "одежда"}, {Type: "электроника"}}})
+
+	opened := nextLiveMessage(t, messages)
+	assert.Equal(t, "ReceptionOpened", opened.event)
+	assert.Equal(t, moscow.ID, opened.data.PVZID)
+	assert.Equal(t, "in_progress", opened.data.Reception.Status)
+
+	added := nextLiveMessage(t, messages)
+	assert.Equal(t, "ProductAdded", added.event)
+	assert.Equal(t, 1, added.data.ProductCount)
+
+	batch := nextLiveMessage(t, messages)
+	assert.Len(t, batch.data.Products, 2)
+	assert.Equal(t, 3, batch.data.ProductCount)
+	closeStream()
+
+	// Пока клиент отключен, приемка закрывается; при переподключении событие приходит из истории
+	postJSON(api, employeeToken, "/pvz/"+moscow.ID+"/close_last_reception", nil)
+
+	messages, closeStream = openLiveStream(t, server, moderatorToken, "?pvzId="+moscow.ID, batch.id)
+	defer closeStream()
+	closed := nextLiveMessage(t, messages)
+	assert.Equal(t, "ReceptionClosed", closed.event)
+	assert.Equal(t, "close", closed.data.Reception.Status)
+	assert.Equal(t, 3, closed.data.ProductCount)
+}
+
+// TestLiveStream_StaleReceptionClosed проверяет, что приемка, закрытая планировщиком, публикуется в поток
+func TestLiveStream_StaleReceptionClosed(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+	reception.DateTime = time.Now().Add(-13 * time.Hour)
+
+	messages, closeStream := openLiveStream(t, server, moderatorToken, "?pvzId="+pvz.ID, "")
+	defer closeStream()
+
+	closer := scheduler.NewStaleReceptionCloser(mockStorage, scheduler.SystemClock{}, 12*time.Hour, time.Hour)
+	closer.SetLivePublisher(api)
+	_, err := closer.RunOnce()
+	assert.NoError(t, err)
+
+	closed := nextLiveMessage(t, messages)
+	assert.Equal(t, "ReceptionClosed", closed.event)
+	assert.Equal(t, reception.ID, closed.data.Reception.ID)
+	assert.Equal(t, "close", closed.data.Reception.Status)
+}
+
+// TestLiveStream_Validation проверяет авторизацию и параметры потока
+func TestLiveStream_Validation(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	req := httptest.NewRequest(http.MethodGet, "/receptions/live", nil)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/receptions/live?city=Омск", nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+	req.Header.Set("Last-Event-ID", "abc")
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var response models.Error
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Len(t, response.Details, 2)
+}
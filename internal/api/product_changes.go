@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// handleDeleteProduct обрабатывает запрос на удаление произвольного товара из открытой приемки
+func (a *API) handleDeleteProduct(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "employee"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+	claims, _ := a.auth.ValidateToken(token)
+
+	productID := mux.Vars(r)["productId"]
+	if !a.requireProductInActivePVZ(w, productID) {
+		return
+	}
+
+	product, err := a.storageFor(r).DeleteProduct(productID, claims.UserID)
+	if err != nil {
+		a.respondWithProductChangeError(w, err)
+		return
+	}
+	a.publishLive("ProductDeleted", product.ReceptionID, *product)
+
+	a.respondWithJSON(w, http.StatusOK, product)
+}
+
+// handleUpdateProduct обрабатывает запрос на исправление типа товара в открытой приемке
+func (a *API) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "employee"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+	claims, _ := a.auth.ValidateToken(token)
+
+	var req models.ProductUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	// Проверяем тип товара
+	if req.Type != "электроника" && req.Type != "одежда" && req.Type != "обувь" {
+		a.respondWithError(w, http.StatusBadRequest, "Недопустимый тип товара")
+		return
+	}
+
+	productID := mux.Vars(r)["productId"]
+	if !a.requireProductInActivePVZ(w, productID) {
+		return
+	}
+
+	product, err := a.storageFor(r).UpdateProductType(productID, req.Type, claims.UserID)
+	if err != nil {
+		a.respondWithProductChangeError(w, err)
+		return
+	}
+	a.publishLive("ProductUpdated", product.ReceptionID, *product)
+
+	a.respondWithJSON(w, http.StatusOK, product)
+}
+
+// handleGetProductChanges обрабатывает запрос на получение журнала исправлений товаров приемки
+func (a *API) handleGetProductChanges(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRoleAny(token, "employee", "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	reception, err := a.storage.GetReceptionByID(mux.Vars(r)["receptionId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "Приемка не найдена")
+		return
+	}
+
+	changes, err := a.storage.GetProductChanges(reception.ID)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении журнала исправлений")
+		return
+	}
+	if changes == nil {
+		changes = []models.ProductChange{}
+	}
+
+	a.respondWithJSON(w, http.StatusOK, changes)
+}
+
+// requireProductInActivePVZ проверяет, что товар существует и его ПВЗ работает; иначе отправляет ошибку
+func (a *API) requireProductInActivePVZ(w http.ResponseWriter, productID string) bool {
+	product, err := a.storage.GetProductByID(productID)
+	if err != nil {
+		a.respondWithLookupError(w, err, "Товар не найден")
+		return false
+	}
+
+	reception, err := a.storage.GetReceptionByID(product.ReceptionID)
+	if err != nil {
+		a.respondWithLookupError(w, err, "Приемка не найдена")
+		return false
+	}
+
+	_, ok := a.requireActivePVZ(w, reception.PVZID)
+	return ok
+}
+
+// respondWithProductChangeError отправляет ошибку изменения товара
+func (a *API) respondWithProductChangeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrReceptionClosed):
+		a.respondWithError(w, http.StatusBadRequest, "Приемка уже закрыта")
+	case errors.Is(err, storage.ErrNotFound):
+		a.respondWithError(w, http.StatusNotFound, "Товар не найден")
+	case errors.Is(err, storage.ErrProductInTransfer):
+		a.respondWithError(w, http.StatusConflict, "Товар участвует в перемещении")
+	case errors.Is(err, storage.ErrProductReturned):
+		a.respondWithError(w, http.StatusBadRequest, "Товар возвращен")
+	default:
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при изменении товара")
+	}
+}
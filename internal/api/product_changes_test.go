@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestProductCorrections проверяет удаление и исправление произвольного товара с записью в журнал
+func TestProductCorrections(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+	products := []*models.Product{{Type: "электроника"}, {Type: "одежда"}, {Type: "обувь"}}
+	mockStorage.CreateProducts(reception.ID, products)
+
+	send := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Модератор не может менять товары
+	rr := send(http.MethodDelete, "/products/"+products[0].ID, moderatorToken, nil)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Исправляем тип первого товара
+	rr = send(http.MethodPatch, "/products/"+products[0].ID, employeeToken, models.ProductUpdateRequest{Type: "обувь"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var updated models.Product
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &updated))
+	assert.Equal(t, "обувь", updated.Type)
+
+	rr = send(http.MethodPatch, "/products/"+products[0].ID, employeeToken, models.ProductUpdateRequest{Type: "мебель"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Удаляем товар из середины приемки
+	rr = send(http.MethodDelete, "/products/"+products[1].ID, employeeToken, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	remaining, _ := mockStorage.GetProductsByReceptionID(reception.ID)
+	assert.Len(t, remaining, 2)
+	assert.Equal(t, products[0].ID, remaining[0].ID)
+	assert.Equal(t, products[2].ID, remaining[1].ID)
+
+	rr = send(http.MethodDelete, "/products/"+products[1].ID, employeeToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Журнал исправлений доступен модератору
+	rr = send(http.MethodGet, "/receptions/"+reception.ID+"/product_changes", moderatorToken, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var changes []models.ProductChange
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &changes))
+	assert.Len(t, changes, 2)
+	assert.Equal(t, "change_type", changes[0].Action)
+	assert.Equal(t, "электроника", changes[0].OldType)
+	assert.Equal(t, "обувь", changes[0].NewType)
+	assert.Equal(t, "dummy-user", changes[0].UserID)
+	assert.Equal(t, "delete", changes[1].Action)
+	assert.Equal(t, products[1].ID, changes[1].ProductID)
+
+	// Товары закрытой приемки менять нельзя
+	mockStorage.CloseReception(reception.ID)
+	rr = send(http.MethodDelete, "/products/"+products[2].ID, employeeToken, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+)
+
+// maxProductBatchSize максимальное число товаров в одном пакетном запросе
+const maxProductBatchSize = 100
+
+// handleCreateProductBatch обрабатывает запрос на пакетное добавление товаров в открытую приемку
+func (a *API) handleCreateProductBatch(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "employee"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	var req models.ProductBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	// Проверяем размер пакета и типы всех товаров до обращения к хранилищу
+	var fieldErrors []models.FieldError
+	if len(req.Products) == 0 {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "products", Message: "список товаров пуст"})
+	} else if len(req.Products) > maxProductBatchSize {
+		fieldErrors = append(fieldErrors, models.FieldError{
+			Field:   "products",
+			Message: fmt.Sprintf("не больше %d товаров в запросе", maxProductBatchSize),
+		})
+	}
+	for i, item := range req.Products {
+		if item.Type != "электроника" && item.Type != "одежда" && item.Type != "обувь" {
+			fieldErrors = append(fieldErrors, models.FieldError{
+				Field:   fmt.Sprintf("products[%d].type", i),
+				Message: "недопустимый тип товара",
+			})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		a.respondWithValidationError(w, fieldErrors)
+		return
+	}
+
+	// Проверяем, что ПВЗ работает
+	if _, ok := a.requireActivePVZ(w, req.PVZID); !ok {
+		return
+	}
+
+	// Получаем последнюю приемку для ПВЗ
+	reception, err := a.storage.GetLastReceptionByPVZID(req.PVZID)
+	if err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Активная приемка не найдена")
+		return
+	}
+
+	if reception.Status != "in_progress" {
+		a.respondWithError(w, http.StatusBadRequest, "Приемка уже закрыта")
+		return
+	}
+
+	products := make([]*models.Product, 0, len(req.Products))
+	for _, item := range req.Products {
+		products = append(products, &models.Product{Type: item.Type})
+	}
+
+	// Статус приемки повторно проверяется внутри транзакции: если ее успели закрыть, не добавляется ни один товар
+	if err := a.storageFor(r).CreateProducts(reception.ID, products); err != nil {
+		if errors.Is(err, storage.ErrReceptionClosed) {
+			a.respondWithError(w, http.StatusBadRequest, "Приемка уже закрыта")
+			return
+		}
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при добавлении товаров")
+		return
+	}
+
+	added := make([]models.Product, 0, len(products))
+	for _, product := range products {
+		added = append(added, *product)
+	}
+	a.publishLive("ProductAdded", reception.ID, added...)
+
+	a.respondWithJSON(w, http.StatusCreated, products)
+}
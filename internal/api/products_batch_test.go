@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// createProductBatch отправляет запрос на пакетное добавление товаров
+func createProductBatch(api *API, token string, request models.ProductBatchRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(request)
+	req := httptest.NewRequest(http.MethodPost, "/products/batch", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestCreateProductBatch проверяет пакетное добавление товаров в открытую приемку
+func TestCreateProductBatch(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+
+	request := models.ProductBatchRequest{
+		PVZID:    pvz.ID,
+		Products: []models.ProductBatchItem{{Type: "электроника"}, {Type: "одежда"}, {Type: "обувь"}},
+	}
+
+	// Модератор не может добавлять товары
+	rr := createProductBatch(api, moderatorToken, request)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = createProductBatch(api, employeeToken, request)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var products []models.Product
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &products))
+	assert.Len(t, products, 3)
+	for i, product := range products {
+		assert.Equal(t, request.Products[i].Type, product.Type)
+		assert.Equal(t, reception.ID, product.ReceptionID)
+	}
+
+	stored, _ := mockStorage.GetProductsByReceptionID(reception.ID)
+	assert.Len(t, stored, 3)
+}
+
+// TestCreateProductBatch_Invalid проверяет, что пакет с ошибками отклоняется целиком
+func TestCreateProductBatch_Invalid(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+
+	// Пустой пакет
+	rr := createProductBatch(api, token, models.ProductBatchRequest{PVZID: pvz.ID})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Недопустимый тип одного из товаров
+	rr = createProductBatch(api, token, models.ProductBatchRequest{
+		PVZID:    pvz.ID,
+		Products: []models.ProductBatchItem{{Type: "одежда"}, {Type: "мебель"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var errorResponse models.Error
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errorResponse))
+	assert.Len(t, errorResponse.Details, 1)
+	assert.Equal(t, "products[1].type", errorResponse.Details[0].Field)
+
+	stored, _ := mockStorage.GetProductsByReceptionID(reception.ID)
+	assert.Empty(t, stored)
+
+	// Закрытая приемка
+	mockStorage.CloseReception(reception.ID)
+	rr = createProductBatch(api, token, models.ProductBatchRequest{
+		PVZID:    pvz.ID,
+		Products: []models.ProductBatchItem{{Type: "одежда"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// pvzStatusTransitions допустимые переходы между статусами ПВЗ
+var pvzStatusTransitions = map[string][]string{
+	"active":    {"suspended", "decommissioned"},
+	"suspended": {"active", "decommissioned"},
+}
+
+// handleUpdatePVZStatus обрабатывает запрос на смену статуса ПВЗ
+func (a *API) handleUpdatePVZStatus(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	var req models.PVZStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Status != "active" && req.Reason == "" {
+		a.respondWithError(w, http.StatusBadRequest, "Необходимо указать причину приостановки или закрытия ПВЗ")
+		return
+	}
+
+	pvz, err := a.storage.GetPVZByID(mux.Vars(r)["pvzId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "ПВЗ не найден")
+		return
+	}
+
+	allowed := false
+	for _, status := range pvzStatusTransitions[pvz.Status] {
+		if status == req.Status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		a.respondWithError(w, http.StatusBadRequest, "Недопустимый переход статуса ПВЗ: "+pvz.Status+" -> "+req.Status)
+		return
+	}
+
+	// ПВЗ с незакрытой приемкой нельзя приостановить или закрыть: сначала приемку нужно закрыть или отменить
+	if err := a.storageFor(r).UpdatePVZStatus(pvz.ID, req.Status, req.Reason); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrPVZHasOpenReception):
+			a.respondWithError(w, http.StatusConflict, "В ПВЗ есть незакрытая приемка")
+		case errors.Is(err, storage.ErrNotFound):
+			a.respondWithError(w, http.StatusNotFound, "ПВЗ не найден")
+		default:
+			a.respondWithError(w, http.StatusInternalServerError, "Ошибка при смене статуса ПВЗ")
+		}
+		return
+	}
+
+	pvz.Status = req.Status
+	pvz.StatusReason = req.Reason
+	a.respondWithJSON(w, http.StatusOK, pvz)
+}
+
+// requireActivePVZ проверяет, что ПВЗ существует и работает; иначе отправляет ошибку
+func (a *API) requireActivePVZ(w http.ResponseWriter, pvzID string) (*models.PVZ, bool) {
+	pvz, err := a.storage.GetPVZByID(pvzID)
+	if err != nil {
+		a.respondWithLookupError(w, err, "ПВЗ не найден")
+		return nil, false
+	}
+
+	if pvz.Status != "active" {
+		a.respondWithError(w, http.StatusBadRequest, "ПВЗ не работает: "+pvz.Status)
+		return nil, false
+	}
+
+	return pvz, true
+}
+
+// parsePVZStatuses разбирает фильтр статусов ПВЗ; по умолчанию закрытые ПВЗ скрыты
+func parsePVZStatuses(value string) ([]string, bool) {
+	if value == "" {
+		return []string{"active", "suspended"}, true
+	}
+
+	var statuses []string
+	for _, status := range strings.Split(value, ",") {
+		status = strings.TrimSpace(status)
+		if status != "active" && status != "suspended" && status != "decommissioned" {
+			return nil, false
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, true
+}
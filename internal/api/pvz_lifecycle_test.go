@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// updatePVZStatus отправляет запрос на смену статуса ПВЗ
+func updatePVZStatus(api *API, token, pvzID, status, reason string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(models.PVZStatusRequest{Status: status, Reason: reason})
+	req := httptest.NewRequest(http.MethodPost, "/pvz/"+pvzID+"/status", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestUpdatePVZStatus_Transitions проверяет допустимые и недопустимые переходы статусов ПВЗ
+func TestUpdatePVZStatus_Transitions(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+
+	// Сотрудник не может менять статус
+	rr := updatePVZStatus(api, employeeToken, pvz.ID, "suspended", "ремонт")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Приостановка без причины запрещена
+	rr = updatePVZStatus(api, moderatorToken, pvz.ID, "suspended", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = updatePVZStatus(api, moderatorToken, pvz.ID, "suspended", "ремонт")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response models.PVZ
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "suspended", response.Status)
+	assert.Equal(t, "ремонт", response.StatusReason)
+
+	rr = updatePVZStatus(api, moderatorToken, pvz.ID, "decommissioned", "аренда не продлена")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Закрытый ПВЗ нельзя вернуть в работу
+	rr = updatePVZStatus(api, moderatorToken, pvz.ID, "active", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+// TestCreateReception_SuspendedPVZ проверяет запрет приемки в приостановленном ПВЗ
+func TestCreateReception_SuspendedPVZ(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	mockStorage.UpdatePVZStatus(pvz.ID, "suspended", "ремонт")
+
+	body, _ := json.Marshal(models.ReceptionRequest{PVZID: pvz.ID})
+	req := httptest.NewRequest(http.MethodPost, "/receptions", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+// TestGetPVZList_StatusFilter проверяет, что закрытые ПВЗ по умолчанию скрыты
+func TestGetPVZList_StatusFilter(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("moderator")
+
+	active := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(active)
+	closed := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(closed)
+	mockStorage.UpdatePVZStatus(closed.ID, "decommissioned", "закрыт")
+
+	req := httptest.NewRequest(http.MethodGet, "/pvz", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response []models.PVZListItem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(t, response, 1)
+	assert.Equal(t, active.ID, response[0].PVZ.ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/pvz?status=decommissioned", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(t, response, 1)
+	assert.Equal(t, closed.ID, response[0].PVZ.ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/pvz?status=unknown", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+// TestUpdatePVZStatus_OpenReception проверяет, что ПВЗ с незакрытой приемкой нельзя приостановить или закрыть
+func TestUpdatePVZStatus_OpenReception(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+
+	rr := updatePVZStatus(api, moderatorToken, pvz.ID, "suspended", "ремонт")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	rr = updatePVZStatus(api, moderatorToken, pvz.ID, "decommissioned", "закрытие")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	stored, _ := mockStorage.GetPVZByID(pvz.ID)
+	assert.Equal(t, "active", stored.Status)
+
+	// После закрытия приемки ПВЗ можно приостановить
+	mockStorage.CloseReception(reception.ID)
+	rr = updatePVZStatus(api, moderatorToken, pvz.ID, "suspended", "ремонт")
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// encodePVZCursor кодирует позицию в списке ПВЗ в непрозрачную строку
+func encodePVZCursor(pvz models.PVZ) string {
+	data, _ := json.Marshal(models.PVZCursor{RegistrationDate: pvz.RegistrationDate, ID: pvz.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePVZCursor разбирает курсор, полученный клиентом в nextCursor
+func decodePVZCursor(value string) (*models.PVZCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("некорректный курсор")
+	}
+
+	var cursor models.PVZCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" || cursor.RegistrationDate.IsZero() {
+		return nil, errors.New("некорректный курсор")
+	}
+
+	return &cursor, nil
+}
+
+// pvzListEnvelopeMediaType версия ответа GET /pvz с конвертом вместо массива
+const pvzListEnvelopeMediaType = "application/vnd.pvz.v2+json"
+
+// wantsPVZListEnvelope проверяет, запросил ли клиент ответ в конверте
+func wantsPVZListEnvelope(r *http.Request) bool {
+	if envelope, err := strconv.ParseBool(r.URL.Query().Get("envelope")); err == nil && envelope {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), pvzListEnvelopeMediaType)
+}
+
+// respondWithPVZCursorPage отправляет страницу списка ПВЗ при обходе по курсору
+func (a *API) respondWithPVZCursorPage(w http.ResponseWriter, filter models.PVZListFilter) {
+	total, err := a.storage.CountPVZList(filter)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении списка ПВЗ")
+		return
+	}
+
+	// Запрашиваем на один ПВЗ больше, чтобы узнать, есть ли следующая страница
+	limit := filter.Limit
+	filter.Page = 1
+	filter.Limit = limit + 1
+	pvzList, err := a.storage.GetPVZList(filter)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении списка ПВЗ")
+		return
+	}
+
+	result := models.PVZListPage{
+		Items: pvzList,
+		Limit: limit,
+		Total: total,
+	}
+	if len(pvzList) > limit {
+		result.Items = pvzList[:limit]
+		result.HasMore = true
+		result.NextCursor = encodePVZCursor(result.Items[limit-1].PVZ)
+	}
+	if result.Items == nil {
+		result.Items = []models.PVZListItem{}
+	}
+
+	a.respondWithJSON(w, http.StatusOK, result)
+}
+
+// parseFilterDate разбирает дату фильтра в формате RFC3339 или ГГГГ-ММ-ДД;
+// дата без времени для конца диапазона включает весь день
+func parseFilterDate(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// parsePVZListQuery разбирает и проверяет параметры фильтрации и пагинации списка ПВЗ
+func (a *API) parsePVZListQuery(query url.Values) (models.PVZListFilter, []models.FieldError) {
+	// Параметры пагинации и фильтрации
+	pageStr := query.Get("page")
+	limitStr := query.Get("limit")
+	startDateStr := query.Get("startDate")
+	endDateStr := query.Get("endDate")
+
+	var fieldErrors []models.FieldError
+
+	statuses, ok := parsePVZStatuses(query.Get("status"))
+	if !ok {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "status", Message: "допустимые значения: active, suspended, decommissioned"})
+	}
+
+	page := 1
+	limit := 10
+
+	if pageStr != "" {
+		p, err := strconv.Atoi(pageStr)
+		if err != nil || p <= 0 {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "page", Message: "должен быть положительным целым числом"})
+		}
+		page = p
+	}
+
+	if limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 || l > 30 {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "limit", Message: "должен быть целым числом от 1 до 30"})
+		}
+		limit = l
+	}
+
+	var startDate, endDate *time.Time
+	if startDateStr != "" {
+		t, err := parseFilterDate(startDateStr, false)
+		if err != nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "startDate", Message: "ожидается дата в формате RFC3339 или ГГГГ-ММ-ДД"})
+		} else {
+			startDate = &t
+		}
+	}
+
+	if endDateStr != "" {
+		t, err := parseFilterDate(endDateStr, true)
+		if err != nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "endDate", Message: "ожидается дата в формате RFC3339 или ГГГГ-ММ-ДД"})
+		} else {
+			endDate = &t
+		}
+	}
+
+	if startDate != nil && endDate != nil && startDate.After(*endDate) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "startDate", Message: "не может быть позже endDate"})
+	}
+
+	var after *models.PVZCursor
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := decodePVZCursor(cursorStr)
+		if err != nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "cursor", Message: "некорректный курсор"})
+		}
+		after = cursor
+	}
+
+	// Фильтры по городу, приемкам и товарам
+	city := query.Get("city")
+	if city != "" && !a.isAllowedCity(city) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "city", Message: "допустимые значения: " + a.allowedCitiesList()})
+	}
+
+	receptionStatus := query.Get("receptionStatus")
+	if receptionStatus != "" && receptionStatus != "in_progress" && receptionStatus != "close" && receptionStatus != "cancelled" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "receptionStatus", Message: "допустимые значения: in_progress, close, cancelled"})
+	}
+
+	productType := query.Get("productType")
+	if productType != "" && productType != "электроника" && productType != "одежда" && productType != "обувь" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "productType", Message: "допустимые значения: электроника, одежда, обувь"})
+	}
+
+	include := query.Get("include")
+	if include != "" && include != "receptions" && include != "products" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "include", Message: "допустимые значения: receptions, products"})
+	}
+
+	if query.Has("cursor") && pageStr != "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "page", Message: "не используется вместе с cursor"})
+	}
+
+	return models.PVZListFilter{
+		StartDate:       startDate,
+		EndDate:         endDate,
+		Statuses:        statuses,
+		City:            city,
+		ReceptionStatus: receptionStatus,
+		ProductType:     productType,
+		OmitProducts:    include == "receptions",
+		After:           after,
+		Page:            page,
+		Limit:           limit,
+	}, fieldErrors
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// getPVZList выполняет запрос GET /pvz с указанной строкой параметров
+func getPVZList(api *API, token, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/pvz?"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestGetPVZList_Cursor проверяет обход списка ПВЗ по курсору
+func TestGetPVZList_Cursor(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	for i := 0; i < 5; i++ {
+		mockStorage.CreatePVZ(&models.PVZ{City: "Москва"})
+	}
+
+	rr := getPVZList(api, token, "limit=2&cursor=")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var page models.PVZListPage
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	assert.Len(t, page.Items, 2)
+	assert.NotEmpty(t, page.NextCursor)
+
+	seen := map[string]bool{}
+	for _, item := range page.Items {
+		seen[item.PVZ.ID] = true
+	}
+
+	// Новый ПВЗ, созданный между запросами, не сдвигает следующие страницы
+	mockStorage.CreatePVZ(&models.PVZ{City: "Казань"})
+
+	for page.NextCursor != "" {
+		rr = getPVZList(api, token, "limit=2&cursor="+page.NextCursor)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		page = models.PVZListPage{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+		for _, item := range page.Items {
+			assert.False(t, seen[item.PVZ.ID], "ПВЗ не должен повторяться")
+			seen[item.PVZ.ID] = true
+		}
+	}
+
+	assert.Len(t, seen, 5)
+
+	// Поврежденный курсор
+	rr = getPVZList(api, token, "cursor=not-a-cursor")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+// TestGetPVZList_Envelope проверяет ответ в конверте с метаданными пагинации
+func TestGetPVZList_Envelope(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("moderator")
+
+	for i := 0; i < 7; i++ {
+		mockStorage.CreatePVZ(&models.PVZ{City: "Казань"})
+	}
+
+	rr := getPVZList(api, token, "page=2&limit=3&envelope=true")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var page models.PVZListPage
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	assert.Len(t, page.Items, 3)
+	assert.Equal(t, 2, page.Page)
+	assert.Equal(t, 3, page.Limit)
+	assert.Equal(t, 7, page.Total)
+	assert.True(t, page.HasMore)
+
+	// Конверт можно запросить через Accept
+	req := httptest.NewRequest(http.MethodGet, "/pvz?page=3&limit=3", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", pvzListEnvelopeMediaType)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	page = models.PVZListPage{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	assert.Len(t, page.Items, 1)
+	assert.False(t, page.HasMore)
+
+	// Пустая страница отдается как пустой массив, а не null
+	rr = getPVZList(api, token, "page=5&limit=3&envelope=true")
+	assert.Contains(t, rr.Body.String(), `"items":[]`)
+
+	// Без конверта ответ остается массивом
+	rr = getPVZList(api, token, "page=1&limit=3")
+	var items []models.PVZListItem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &items))
+	assert.Len(t, items, 3)
+}
+
+// TestGetPVZList_Validation проверяет ошибки по полям для некорректных параметров
+func TestGetPVZList_Validation(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	cases := map[string]string{
+		"limit=100":           "limit",
+		"page=0":              "page",
+		"startDate=yesterday": "startDate",
+		"endDate=2024-13-01":  "endDate",
+		"startDate=2024-05-02&endDate=2024-05-01": "startDate",
+		"status=closed":  "status",
+		"cursor=&page=2": "page",
+	}
+	for query, field := range cases {
+		rr := getPVZList(api, token, query)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+
+		var response models.Error
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		if assert.Len(t, response.Details, 1, query) {
+			assert.Equal(t, field, response.Details[0].Field, query)
+		}
+	}
+}
+
+// TestGetPVZList_OpenDateRange проверяет фильтр только по начальной дате
+func TestGetPVZList_OpenDateRange(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	withReception := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(withReception)
+	mockStorage.CreateReception(&models.Reception{PVZID: withReception.ID})
+	mockStorage.CreatePVZ(&models.PVZ{City: "Москва"})
+
+	startDate := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	rr := getPVZList(api, token, "startDate="+startDate)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var items []models.PVZListItem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &items))
+	assert.Len(t, items, 1)
+	assert.Equal(t, withReception.ID, items[0].PVZ.ID)
+}
+
+// TestGetPVZList_NestedFilters проверяет фильтрацию вложенных приемок и товаров
+func TestGetPVZList_NestedFilters(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	kazan := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(kazan)
+	moscow := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(moscow)
+
+	// Закрытая приемка с обувью и открытая с одеждой в Казани, приемка в Москве
+	closed := &models.Reception{PVZID: kazan.ID}
+	mockStorage.CreateReception(closed)
+	mockStorage.CreateProduct(&models.Product{Type: "обувь", ReceptionID: closed.ID})
+	mockStorage.CreateProduct(&models.Product{Type: "одежда", ReceptionID: closed.ID})
+	mockStorage.CloseReception(closed.ID)
+
+	open := &models.Reception{PVZID: kazan.ID}
+	mockStorage.CreateReception(open)
+	mockStorage.CreateProduct(&models.Product{Type: "одежда", ReceptionID: open.ID})
+
+	mockStorage.CreateReception(&models.Reception{PVZID: moscow.ID})
+
+	var items []models.PVZListItem
+
+	rr := getPVZList(api, token, "city=Казань")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &items))
+	assert.Len(t, items, 1)
+	assert.Len(t, items[0].Receptions, 2)
+
+	// Внутри ПВЗ остаются только закрытые приемки и товары нужного типа
+	rr = getPVZList(api, token, "receptionStatus=close&productType=обувь")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &items))
+	assert.Len(t, items, 1)
+	assert.Equal(t, kazan.ID, items[0].PVZ.ID)
+	assert.Len(t, items[0].Receptions, 1)
+	assert.Equal(t, closed.ID, items[0].Receptions[0].Reception.ID)
+	assert.Len(t, items[0].Receptions[0].Products, 1)
+	assert.Equal(t, "обувь", items[0].Receptions[0].Products[0].Type)
+
+	// include=receptions убирает товары из ответа
+	rr = getPVZList(api, token, "city=Казань&include=receptions")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), `"products"`)
+
+	rr = getPVZList(api, token, "include=everything")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
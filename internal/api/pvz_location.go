@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+const (
+	// defaultNearbyRadiusKm радиус поиска ближайших ПВЗ по умолчанию
+	defaultNearbyRadiusKm = 5.0
+	// maxNearbyRadiusKm максимальный радиус поиска ближайших ПВЗ
+	maxNearbyRadiusKm = 100.0
+	// nearbyLimit максимальное количество ПВЗ в ответе поиска
+	nearbyLimit = 50
+	// maxAddressLength максимальная длина адреса ПВЗ
+	maxAddressLength = 255
+)
+
+// openingHoursPattern формат часов работы: ЧЧ:ММ-ЧЧ:ММ; время окончания может быть 24:00
+var openingHoursPattern = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d-(([01]\d|2[0-3]):[0-5]\d|24:00)$`)
+
+// validatePVZLocation проверяет адрес, координаты и часы работы ПВЗ
+func validatePVZLocation(pvz *models.PVZ) error {
+	pvz.Address = strings.TrimSpace(pvz.Address)
+	if len([]rune(pvz.Address)) > maxAddressLength {
+		return fmt.Errorf("адрес не может быть длиннее %d символов", maxAddressLength)
+	}
+
+	if (pvz.Latitude == nil) != (pvz.Longitude == nil) {
+		return fmt.Errorf("широта и долгота указываются вместе")
+	}
+	if pvz.Latitude != nil {
+		if !inRange(*pvz.Latitude, -90, 90) {
+			return fmt.Errorf("широта должна быть в диапазоне от -90 до 90")
+		}
+		if !inRange(*pvz.Longitude, -180, 180) {
+			return fmt.Errorf("долгота должна быть в диапазоне от -180 до 180")
+		}
+	}
+
+	pvz.OpeningHours = strings.TrimSpace(pvz.OpeningHours)
+	if pvz.OpeningHours != "" {
+		if !openingHoursPattern.MatchString(pvz.OpeningHours) {
+			return fmt.Errorf("часы работы указываются в формате ЧЧ:ММ-ЧЧ:ММ")
+		}
+		parts := strings.Split(pvz.OpeningHours, "-")
+		if parts[0] >= parts[1] {
+			return fmt.Errorf("время открытия должно быть раньше времени закрытия")
+		}
+	}
+
+	return nil
+}
+
+// inRange проверяет, что value - конечное число от min до max. NaN не проходит ни одно сравнение,
+// поэтому без отдельной проверки попал бы в запрос
+func inRange(value, min, max float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0) && value >= min && value <= max
+}
+
+// handleGetNearbyPVZ обрабатывает запрос на поиск ближайших ПВЗ
+func (a *API) handleGetNearbyPVZ(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRoleAny(token, "employee", "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	query := r.URL.Query()
+
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || !inRange(lat, -90, 90) {
+		a.respondWithError(w, http.StatusBadRequest, "Параметр lat должен быть числом от -90 до 90")
+		return
+	}
+
+	lon, err := strconv.ParseFloat(query.Get("lon"), 64)
+	if err != nil || !inRange(lon, -180, 180) {
+		a.respondWithError(w, http.StatusBadRequest, "Параметр lon должен быть числом от -180 до 180")
+		return
+	}
+
+	radius := defaultNearbyRadiusKm
+	if radiusStr := query.Get("radius"); radiusStr != "" {
+		radius, err = strconv.ParseFloat(radiusStr, 64)
+		if err != nil || radius <= 0 || !inRange(radius, 0, maxNearbyRadiusKm) {
+			a.respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Параметр radius должен быть числом от 0 до %.0f км", maxNearbyRadiusKm))
+			return
+		}
+	}
+
+	result, err := a.storage.GetNearbyPVZ(lat, lon, radius, nearbyLimit)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при поиске ближайших ПВЗ")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, result)
+}
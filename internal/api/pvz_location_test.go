@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// createPVZWithLocation создает ПВЗ с координатами через API
+func createPVZWithLocation(api *API, token string, pvz models.PVZ) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(pvz)
+	req := httptest.NewRequest(http.MethodPost, "/pvz", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestCreatePVZ_InvalidLocation проверяет валидацию адреса, координат и часов работы
+func TestCreatePVZ_InvalidLocation(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("moderator")
+
+	lat, lon, badLat := 55.75, 37.61, 95.0
+
+	cases := []models.PVZ{
+		{City: "Москва", Latitude: &lat},
+		{City: "Москва", Latitude: &badLat, Longitude: &lon},
+		{City: "Москва", OpeningHours: "9-21"},
+		{City: "Москва", OpeningHours: "21:00-09:00"},
+		{City: "Москва", OpeningHours: "09:00-24:30"},
+	}
+	for _, pvz := range cases {
+		rr := createPVZWithLocation(api, token, pvz)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	}
+
+	rr := createPVZWithLocation(api, token, models.PVZ{
+		City:         "Москва",
+		Address:      "ул. Тверская, 1",
+		Latitude:     &lat,
+		Longitude:    &lon,
+		OpeningHours: "09:00-21:00",
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var response models.PVZ
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "ул. Тверская, 1", response.Address)
+	assert.Equal(t, "09:00-21:00", response.OpeningHours)
+
+	// Круглосуточный режим заканчивается в 24:00
+	rr = createPVZWithLocation(api, token, models.PVZ{City: "Москва", OpeningHours: "00:00-24:00"})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+}
+
+// TestGetNearbyPVZ проверяет поиск ближайших ПВЗ
+func TestGetNearbyPVZ(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	// Два ПВЗ в центре Москвы, один в Санкт-Петербурге и один без координат
+	nearLat, nearLon := 55.7558, 37.6173
+	farLat, farLon := 55.7600, 37.6300
+	spbLat, spbLon := 59.9343, 30.3351
+
+	near := &models.PVZ{City: "Москва", Latitude: &nearLat, Longitude: &nearLon}
+	far := &models.PVZ{City: "Москва", Latitude: &farLat, Longitude: &farLon}
+	spb := &models.PVZ{City: "Санкт-Петербург", Latitude: &spbLat, Longitude: &spbLon}
+	mockStorage.CreatePVZ(near)
+	mockStorage.CreatePVZ(far)
+	mockStorage.CreatePVZ(spb)
+	mockStorage.CreatePVZ(&models.PVZ{City: "Москва"})
+
+	req := httptest.NewRequest(http.MethodGet, "/pvz/nearby?lat=55.7558&lon=37.6173&radius=10", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response []models.PVZWithDistance
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(t, response, 2)
+	assert.Equal(t, near.ID, response[0].PVZ.ID)
+	assert.Equal(t, far.ID, response[1].PVZ.ID)
+	assert.True(t, response[0].DistanceKm < response[1].DistanceKm)
+
+	// Закрытые ПВЗ не попадают в выдачу
+	mockStorage.UpdatePVZStatus(near.ID, "decommissioned", "аренда не продлена")
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(t, response, 1)
+	assert.Equal(t, far.ID, response[0].PVZ.ID)
+
+	// Некорректные параметры
+	for _, query := range []string{"lon=37.6", "lat=91&lon=37.6", "lat=55.7&lon=37.6&radius=500",
+		"lat=NaN&lon=NaN&radius=NaN", "lat=55.7&lon=Inf", "lat=55.7&lon=37.6&radius=NaN"} {
+		req := httptest.NewRequest(http.MethodGet, "/pvz/nearby?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	}
+}
+
+// TestValidatePVZLocation_NotFinite проверяет, что NaN и бесконечность не принимаются как координаты
+func TestValidatePVZLocation_NotFinite(t *testing.T) {
+	lat, lon := 55.75, 37.61
+	nan, inf := math.NaN(), math.Inf(1)
+
+	for _, pvz := range []models.PVZ{
+		{City: "Москва", Latitude: &nan, Longitude: &lon},
+		{City: "Москва", Latitude: &lat, Longitude: &nan},
+		{City: "Москва", Latitude: &inf, Longitude: &lon},
+	} {
+		assert.Error(t, validatePVZLocation(&pvz))
+	}
+}
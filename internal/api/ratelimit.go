@@ -0,0 +1,73 @@
+package api
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/ratelimit"
+	"github.com/gorilla/mux"
+)
+
+// SetRateLimiter включает ограничение частоты запросов; без него запросы не ограничиваются
+func (a *API) SetRateLimiter(limiter *ratelimit.Limiter) {
+	a.limiter = limiter
+}
+
+// allowRequest проверяет ограничение частоты запросов и отвечает 429, если оно превышено.
+// Запросы с действительным токеном ограничиваются по пользователю, остальные - по IP клиента
+func (a *API) allowRequest(w http.ResponseWriter, r *http.Request) bool {
+	if a.limiter == nil {
+		return true
+	}
+
+	// Правила задаются для шаблонов маршрутов, а не для конкретных путей
+	route := ""
+	var match mux.RouteMatch
+	if a.router.Match(r, &match) && match.Route != nil {
+		route, _ = match.Route.GetPathTemplate()
+	}
+
+	subject, role := "ip:"+clientIP(r), ratelimit.AnonymousRole
+	if claims, err := a.auth.ValidateToken(a.getTokenFromHeader(r)); err == nil {
+		subject, role = "user:"+claims.UserID, claims.Role
+	}
+
+	status, limited, err := a.limiter.Allow(subject, role, r.Method, route, time.Now())
+	if err != nil {
+		// Недоступное хранилище ограничений не должно останавливать сервис
+		log.Printf("Ошибка при проверке ограничения запросов: %v", err)
+		return true
+	}
+	if !limited {
+		return true
+	}
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(status.ResetAfter)))
+	if status.Allowed {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(status.RetryAfter)))
+	a.respondWithError(w, http.StatusTooManyRequests, "Слишком много запросов")
+	return false
+}
+
+// clientIP возвращает IP клиента из адреса соединения
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
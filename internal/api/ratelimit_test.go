@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/ratelimit"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRateLimit_PerUserAndRoute проверяет ответ 429 с заголовками и отдельные корзины маршрутов
+func TestRateLimit_PerUserAndRoute(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	rules, err := ratelimit.ParseRules("*=1/1m:3; moderator POST /pvz=1/1m:1")
+	assert.NoError(t, err)
+	api.SetRateLimiter(ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rules))
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	body, _ := json.Marshal(models.PVZ{City: "Москва"})
+	createPVZ := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/pvz", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+moderatorToken)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := createPVZ()
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "60", rr.Header().Get("X-RateLimit-Reset"))
+
+	rr = createPVZ()
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	assert.NotEmpty(t, rr.Header().Get(requestIDHeader))
+
+	// Ограничение создания ПВЗ не расходует общую корзину пользователя
+	req := httptest.NewRequest(http.MethodGet, "/pvz/some-id", nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.NotEqual(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "3", rr.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "2", rr.Header().Get("X-RateLimit-Remaining"))
+}
+
+// TestRateLimit_ByClientIP проверяет ограничение запросов без токена по IP клиента
+func TestRateLimit_ByClientIP(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+	api.SetRateLimiter(ratelimit.NewLimiter(ratelimit.NewMemoryStore(), []ratelimit.Rule{
+		{Role: ratelimit.AnonymousRole, Limit: ratelimit.Limit{Rate: 1, Burst: 1}},
+	}))
+
+	dummyLogin := func(remoteAddr string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.DummyLoginRequest{Role: "employee"})
+		req := httptest.NewRequest(http.MethodPost, "/dummyLogin", bytes.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, dummyLogin("10.0.0.1:5000").Code)
+	assert.Equal(t, http.StatusTooManyRequests, dummyLogin("10.0.0.1:5001").Code)
+	assert.Equal(t, http.StatusOK, dummyLogin("10.0.0.2:5000").Code)
+
+	// Запросы с токеном под правило для анонимных не попадают
+	token, _ := authService.GenerateDummyToken("moderator")
+	req := httptest.NewRequest(http.MethodGet, "/pvz", nil)
+	req.RemoteAddr = "10.0.0.1:5002"
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("X-RateLimit-Limit"))
+}
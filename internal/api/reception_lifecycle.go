@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// handleReopenReception обрабатывает запрос модератора на повторное открытие закрытой приемки
+func (a *API) handleReopenReception(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	reception, err := a.storage.GetReceptionByID(mux.Vars(r)["receptionId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "Приемка не найдена")
+		return
+	}
+
+	if reception.Status != "close" {
+		a.respondWithError(w, http.StatusBadRequest, "Открыть можно только закрытую приемку")
+		return
+	}
+
+	// Открыть можно только последнюю приемку, иначе в ПВЗ окажется открытая приемка раньше закрытой
+	last, err := a.storage.GetLastReceptionByPVZID(reception.PVZID)
+	if err != nil || last.ID != reception.ID {
+		a.respondWithError(w, http.StatusBadRequest, "Открыть можно только последнюю приемку ПВЗ")
+		return
+	}
+
+	// Неработающий ПВЗ не принимает товары
+	if _, ok := a.requireActivePVZ(w, reception.PVZID); !ok {
+		return
+	}
+
+	// Приемка перемещения повторяет состав отправки, поэтому менять ее товары нельзя
+	if _, err := a.storage.GetTransferByReceptionID(reception.ID); err == nil {
+		a.respondWithError(w, http.StatusBadRequest, "Приемку перемещения нельзя открыть повторно")
+		return
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении перемещения")
+		return
+	}
+
+	// Условия повторно проверяются при обновлении: ПВЗ могли приостановить после проверки
+	if err := a.storageFor(r).ReopenReception(reception.ID); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Открыть можно только последнюю закрытую приемку работающего ПВЗ")
+		return
+	}
+	a.publishLive("ReceptionReopened", reception.ID)
+
+	reception.Status = "in_progress"
+	a.respondWithJSON(w, http.StatusOK, reception)
+}
+
+// handleCancelReception обрабатывает запрос модератора на отмену незакрытой приемки
+func (a *API) handleCancelReception(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	var req models.ReceptionCancelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		a.respondWithError(w, http.StatusBadRequest, "Укажите причину отмены")
+		return
+	}
+
+	reception, err := a.storage.GetReceptionByID(mux.Vars(r)["receptionId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "Приемка не найдена")
+		return
+	}
+
+	if reception.Status != "in_progress" {
+		a.respondWithError(w, http.StatusBadRequest, "Отменить можно только незакрытую приемку")
+		return
+	}
+
+	if err := a.storageFor(r).CancelReception(reception.ID, req.Reason); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a.publishLive("ReceptionCancelled", reception.ID)
+
+	reception.Status = "cancelled"
+	reception.StatusReason = req.Reason
+	a.respondWithJSON(w, http.StatusOK, reception)
+}
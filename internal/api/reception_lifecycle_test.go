@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// postReceptionAction отправляет запрос на открытие или отмену приемки
+func postReceptionAction(api *API, token, receptionID, action string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/receptions/"+receptionID+"/"+action, bytes.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestReopenReception проверяет повторное открытие только последней закрытой приемки
+func TestReopenReception(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	first := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(first)
+	mockStorage.CloseReception(first.ID)
+	time.Sleep(time.Millisecond)
+	second := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(second)
+
+	// Открытую приемку открыть нельзя
+	rr := postReceptionAction(api, moderatorToken, second.ID, "reopen", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	mockStorage.CloseReception(second.ID)
+
+	// Только модератор
+	rr = postReceptionAction(api, employeeToken, second.ID, "reopen", nil)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Не последнюю приемку открыть нельзя
+	rr = postReceptionAction(api, moderatorToken, first.ID, "reopen", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = postReceptionAction(api, moderatorToken, second.ID, "reopen", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	reception, _ := mockStorage.GetReceptionByID(second.ID)
+	assert.Equal(t, "in_progress", reception.Status)
+
+	rr = postReceptionAction(api, moderatorToken, "unknown", "reopen", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+// TestReopenReception_InactivePVZ проверяет запрет открытия приемки в неработающем ПВЗ
+func TestReopenReception_InactivePVZ(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+	mockStorage.CloseReception(reception.ID)
+	mockStorage.UpdatePVZStatus(pvz.ID, "suspended", "ремонт")
+
+	rr := postReceptionAction(api, moderatorToken, reception.ID, "reopen", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	stored, _ := mockStorage.GetReceptionByID(reception.ID)
+	assert.Equal(t, "close", stored.Status)
+}
+
+// TestReopenReception_Transfer проверяет запрет открытия приемки, созданной приемом перемещения
+func TestReopenReception_Transfer(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	source := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(source)
+	destination := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(destination)
+	reception := &models.Reception{PVZID: source.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "одежда", ReceptionID: reception.ID}
+	mockStorage.CreateProduct(product)
+	mockStorage.CloseReception(reception.ID)
+
+	transfer := &models.Transfer{SourcePVZID: source.ID, DestinationPVZID: destination.ID, ProductIDs: []string{product.ID}}
+	mockStorage.CreateTransfer(transfer)
+	mockStorage.ShipTransfer(transfer.ID)
+	received, err := mockStorage.ReceiveTransfer(transfer.ID)
+	assert.NoError(t, err)
+
+	rr := postReceptionAction(api, moderatorToken, received.ID, "reopen", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	stored, _ := mockStorage.GetReceptionByID(received.ID)
+	assert.Equal(t, "close", stored.Status)
+}
+
+// TestCancelReception проверяет отмену приемки и ее исключение из статистики
+func TestCancelReception(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	pvz := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(pvz)
+	cell := &models.StorageCell{PVZID: pvz.ID, Code: "A-1", Capacity: 1}
+	mockStorage.CreateCell(cell)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "обувь", ReceptionID: reception.ID, CellID: cell.ID}
+	mockStorage.CreateProduct(product)
+
+	// Причина обязательна
+	rr := postReceptionAction(api, moderatorToken, reception.ID, "cancel", models.ReceptionCancelRequest{})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = postReceptionAction(api, moderatorToken, reception.ID, "cancel", models.ReceptionCancelRequest{Reason: "открыта по ошибке"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var cancelled models.Reception
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &cancelled))
+	assert.Equal(t, "cancelled", cancelled.Status)
+	assert.Equal(t, "открыта по ошибке", cancelled.StatusReason)
+
+	// Товар отмененной приемки освобождает ячейку
+	productCell, err := mockStorage.GetProductCell(product.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, productCell)
+
+	// Повторно отменить или открыть отмененную приемку нельзя
+	rr = postReceptionAction(api, moderatorToken, reception.ID, "cancel", models.ReceptionCancelRequest{Reason: "еще раз"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = postReceptionAction(api, moderatorToken, reception.ID, "reopen", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Отмененная приемка не мешает открыть новую
+	assert.NoError(t, mockStorage.CreateReception(&models.Reception{PVZID: pvz.ID}))
+
+	// В статистике учитывается только новая приемка, в истории видны обе
+	stats, _ := mockStorage.GetReceptionStats(models.StatsFilter{})
+	assert.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].ReceptionsOpened)
+
+	list, _ := mockStorage.GetPVZList(models.PVZListFilter{Page: 1, Limit: 10, ReceptionStatus: "cancelled"})
+	assert.Len(t, list, 1)
+	assert.Len(t, list[0].Receptions, 1)
+}
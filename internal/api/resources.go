@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// respondWithLookupError отправляет 404 для несуществующего объекта и 500 для прочих ошибок хранилища
+func (a *API) respondWithLookupError(w http.ResponseWriter, err error, notFoundMessage string) {
+	if errors.Is(err, storage.ErrNotFound) {
+		a.respondWithError(w, http.StatusNotFound, notFoundMessage)
+		return
+	}
+	a.respondWithError(w, http.StatusInternalServerError, "Ошибка при обращении к хранилищу")
+}
+
+// handleGetPVZ обрабатывает запрос на получение ПВЗ по ID
+func (a *API) handleGetPVZ(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRoleAny(token, "employee", "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	pvz, err := a.storage.GetPVZByID(mux.Vars(r)["pvzId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "ПВЗ не найден")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, pvz)
+}
+
+// handleUpdatePVZ обрабатывает запрос на изменение города, адреса, координат и часов работы ПВЗ
+func (a *API) handleUpdatePVZ(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	var req models.PVZUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	current, err := a.storage.GetPVZByID(mux.Vars(r)["pvzId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "ПВЗ не найден")
+		return
+	}
+
+	// Изменения применяем к копии, чтобы не испортить ПВЗ при ошибке валидации
+	pvz := *current
+	if req.City != nil {
+		pvz.City = strings.TrimSpace(*req.City)
+	}
+	if req.Address != nil {
+		pvz.Address = *req.Address
+	}
+	if req.Latitude != nil {
+		pvz.Latitude = req.Latitude
+	}
+	if req.Longitude != nil {
+		pvz.Longitude = req.Longitude
+	}
+	if req.OpeningHours != nil {
+		pvz.OpeningHours = *req.OpeningHours
+	}
+
+	// Проверяем город
+	if !a.isAllowedCity(pvz.City) {
+		a.respondWithError(w, http.StatusBadRequest, "ПВЗ может находиться только в городах: "+a.allowedCitiesList())
+		return
+	}
+
+	// Проверяем адрес, координаты и часы работы
+	if err := validatePVZLocation(&pvz); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := a.storageFor(r).UpdatePVZ(&pvz); err != nil {
+		a.respondWithLookupError(w, err, "ПВЗ не найден")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, pvz)
+}
+
+// handleGetReception обрабатывает запрос на получение приемки с товарами по ID
+func (a *API) handleGetReception(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRoleAny(token, "employee", "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	reception, err := a.storage.GetReceptionByID(mux.Vars(r)["receptionId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "Приемка не найдена")
+		return
+	}
+
+	products, err := a.storage.GetProductsByReceptionID(reception.ID)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении товаров приемки")
+		return
+	}
+	if products == nil {
+		products = []models.Product{}
+	}
+
+	a.respondWithJSON(w, http.StatusOK, models.ReceptionWithProducts{
+		Reception: *reception,
+		Products:  products,
+	})
+}
+
+// handleGetProduct обрабатывает запрос на получение товара по ID
+func (a *API) handleGetProduct(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRoleAny(token, "employee", "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	product, err := a.storage.GetProductByID(mux.Vars(r)["productId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "Товар не найден")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, product)
+}
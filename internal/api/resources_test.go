@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetByID проверяет получение ПВЗ, приемки и товара по ID
+func TestGetByID(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "одежда", ReceptionID: reception.ID}
+	mockStorage.CreateProduct(product)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/pvz/" + pvz.ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var pvzResponse models.PVZ
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &pvzResponse))
+	assert.Equal(t, pvz.ID, pvzResponse.ID)
+
+	rr = get("/receptions/" + reception.ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var receptionResponse models.ReceptionWithProducts
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &receptionResponse))
+	assert.Equal(t, reception.ID, receptionResponse.Reception.ID)
+	assert.Len(t, receptionResponse.Products, 1)
+
+	rr = get("/products/" + product.ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var productResponse models.Product
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &productResponse))
+	assert.Equal(t, "одежда", productResponse.Type)
+
+	// Несуществующие объекты
+	for _, path := range []string{"/pvz/unknown", "/receptions/unknown", "/products/unknown"} {
+		assert.Equal(t, http.StatusNotFound, get(path).Code, path)
+	}
+
+	// Без токена доступ запрещен
+	req := httptest.NewRequest(http.MethodGet, "/pvz/"+pvz.ID, nil)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+// TestUpdatePVZ проверяет частичное изменение ПВЗ модератором
+func TestUpdatePVZ(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Москва", Address: "Тверская, 1"}
+	mockStorage.CreatePVZ(pvz)
+
+	patch := func(token, pvzID string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/pvz/"+pvzID, bytes.NewReader([]byte(body)))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := patch(employeeToken, pvz.ID, `{"openingHours": "10:00-22:00"}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = patch(moderatorToken, pvz.ID, `{"openingHours": "10:00-22:00", "latitude": 55.75, "longitude": 37.61}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response models.PVZ
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "10:00-22:00", response.OpeningHours)
+	assert.Equal(t, "Тверская, 1", response.Address)
+	assert.Equal(t, "Москва", response.City)
+	assert.Equal(t, 55.75, *response.Latitude)
+
+	// Недопустимый город не сохраняется
+	rr = patch(moderatorToken, pvz.ID, `{"city": "Самара"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	stored, _ := mockStorage.GetPVZByID(pvz.ID)
+	assert.Equal(t, "Москва", stored.City)
+
+	rr = patch(moderatorToken, "unknown", `{"city": "Казань"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
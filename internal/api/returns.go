@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// handleCreateReturnBatch обрабатывает запрос на открытие партии возвратов
+func (a *API) handleCreateReturnBatch(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "employee"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	var req models.ReturnBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	// Проверяем, что ПВЗ существует и работает
+	pvz, ok := a.requireActivePVZ(w, req.PVZID)
+	if !ok {
+		return
+	}
+
+	batch := &models.ReturnBatch{
+		PVZID: pvz.ID,
+	}
+
+	if err := a.storageFor(r).CreateReturnBatch(batch); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusCreated, batch)
+}
+
+// handleCloseLastReturnBatch обрабатывает запрос на закрытие последней партии возвратов
+func (a *API) handleCloseLastReturnBatch(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "employee"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	pvzID := mux.Vars(r)["pvzId"]
+
+	batch, err := a.storage.GetLastReturnBatchByPVZID(pvzID)
+	if err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Партия возвратов не найдена")
+		return
+	}
+
+	if err := a.storageFor(r).CloseReturnBatch(batch.ID); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	batch.Status = "close"
+	a.respondWithJSON(w, http.StatusOK, batch)
+}
+
+// handleCreateReturn обрабатывает запрос на регистрацию возврата
+func (a *API) handleCreateReturn(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "employee"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	var req models.ReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	// Проверяем, что ПВЗ работает
+	if _, ok := a.requireActivePVZ(w, req.PVZID); !ok {
+		return
+	}
+
+	ret := &models.Return{
+		ProductID: req.ProductID,
+		Type:      req.Type,
+		Reason:    req.Reason,
+	}
+
+	// Для возврата с привязкой тип берется из выданного товара; товар должен быть принят в этом же ПВЗ
+	if req.ProductID != "" {
+		product, err := a.storage.GetProductByID(req.ProductID)
+		if err != nil {
+			a.respondWithError(w, http.StatusBadRequest, "Товар не найден")
+			return
+		}
+
+		reception, err := a.storage.GetReceptionByID(product.ReceptionID)
+		if err != nil {
+			a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении приемки товара")
+			return
+		}
+		if reception.PVZID != req.PVZID {
+			a.respondWithError(w, http.StatusBadRequest, "Товар принят в другом ПВЗ")
+			return
+		}
+		if reception.Status != "close" {
+			a.respondWithError(w, http.StatusBadRequest, "Приемка товара еще не закрыта")
+			return
+		}
+
+		ret.Type = product.Type
+	}
+
+	if ret.Type != "электроника" && ret.Type != "одежда" && ret.Type != "обувь" {
+		a.respondWithError(w, http.StatusBadRequest, "Недопустимый тип товара")
+		return
+	}
+
+	// Получаем последнюю партию возвратов для ПВЗ
+	batch, err := a.storage.GetLastReturnBatchByPVZID(req.PVZID)
+	if err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Открытая партия возвратов не найдена")
+		return
+	}
+
+	if batch.Status != "in_progress" {
+		a.respondWithError(w, http.StatusBadRequest, "Партия возвратов уже закрыта")
+		return
+	}
+
+	ret.BatchID = batch.ID
+	if err := a.storageFor(r).CreateReturn(ret); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrProductReturned):
+			a.respondWithError(w, http.StatusBadRequest, "Товар уже возвращен")
+		case errors.Is(err, storage.ErrProductInTransfer):
+			a.respondWithError(w, http.StatusBadRequest, "Товар участвует в перемещении")
+		default:
+			a.respondWithError(w, http.StatusInternalServerError, "Ошибка при регистрации возврата")
+		}
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusCreated, ret)
+}
+
+// handleGetReturnBatches обрабатывает запрос на получение партий возвратов, ожидающих курьера
+func (a *API) handleGetReturnBatches(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	// По умолчанию показываем закрытые партии, которые ожидают курьера
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "close"
+	}
+	if status != "close" && status != "in_progress" {
+		a.respondWithError(w, http.StatusBadRequest, "Недопустимый статус партии возвратов")
+		return
+	}
+
+	batches, err := a.storage.GetReturnBatches(status)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении партий возвратов")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, batches)
+}
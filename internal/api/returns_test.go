@@ -0,0 +1,258 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReturnsFlow проверяет полный цикл возвратов: открытие партии, регистрация, закрытие и просмотр
+func TestReturnsFlow(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	// Создаем ПВЗ с принятым товаром в ячейке
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	cell := &models.StorageCell{PVZID: pvz.ID, Code: "A-1", Capacity: 1}
+	mockStorage.CreateCell(cell)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "электроника", ReceptionID: reception.ID, CellID: cell.ID}
+	mockStorage.CreateProduct(product)
+	mockStorage.CloseReception(reception.ID)
+
+	// Открываем партию возвратов
+	body, _ := json.Marshal(models.ReturnBatchRequest{PVZID: pvz.ID})
+	req := httptest.NewRequest(http.MethodPost, "/return_batches", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	// Регистрируем возврат с привязкой к товару - тип берется из товара
+	body, _ = json.Marshal(models.ReturnRequest{PVZID: pvz.ID, ProductID: product.ID, Reason: "брак"})
+	req = httptest.NewRequest(http.MethodPost, "/returns", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var ret models.Return
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &ret))
+	assert.Equal(t, product.ID, ret.ProductID)
+	assert.Equal(t, "электроника", ret.Type)
+
+	// Возвращенный товар освобождает ячейку
+	productCell, err := mockStorage.GetProductCell(product.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, productCell)
+
+	// Повторный возврат того же товара запрещен
+	req = httptest.NewRequest(http.MethodPost, "/returns", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Регистрируем возврат без привязки
+	body, _ = json.Marshal(models.ReturnRequest{PVZID: pvz.ID, Type: "обувь"})
+	req = httptest.NewRequest(http.MethodPost, "/returns", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	// Открытая партия еще не ожидает курьера
+	req = httptest.NewRequest(http.MethodGet, "/return_batches", nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var batches []models.ReturnBatchWithReturns
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &batches))
+	assert.Len(t, batches, 0)
+
+	// Закрываем партию
+	req = httptest.NewRequest(http.MethodPost, "/pvz/"+pvz.ID+"/close_last_return_batch", nil)
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Теперь партия видна модератору
+	req = httptest.NewRequest(http.MethodGet, "/return_batches", nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &batches))
+	assert.Len(t, batches, 1)
+	assert.Len(t, batches[0].Returns, 2)
+}
+
+// TestCreateReturn_NoOpenBatch проверяет ошибку при регистрации возврата без открытой партии
+func TestCreateReturn_NoOpenBatch(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(pvz)
+
+	body, _ := json.Marshal(models.ReturnRequest{PVZID: pvz.ID, Type: "одежда"})
+	req := httptest.NewRequest(http.MethodPost, "/returns", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+// TestGetReturnBatches_Forbidden проверяет, что список партий доступен только модератору
+func TestGetReturnBatches_Forbidden(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	req := httptest.NewRequest(http.MethodGet, "/return_batches", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+// TestCreateReturn_ProductFromOtherPVZ проверяет, что нельзя вернуть товар, принятый в другом ПВЗ
+func TestCreateReturn_ProductFromOtherPVZ(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	mockStorage.CreateReturnBatch(&models.ReturnBatch{PVZID: pvz.ID})
+
+	// Товар принят в другом ПВЗ
+	otherPVZ := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(otherPVZ)
+	reception := &models.Reception{PVZID: otherPVZ.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "обувь", ReceptionID: reception.ID}
+	mockStorage.CreateProduct(product)
+
+	body, _ := json.Marshal(models.ReturnRequest{PVZID: pvz.ID, ProductID: product.ID})
+	req := httptest.NewRequest(http.MethodPost, "/returns", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Товар принят в другом ПВЗ")
+}
+
+// TestCreateReturn_ReceptionInProgress проверяет, что нельзя вернуть товар из незакрытой приемки
+func TestCreateReturn_ReceptionInProgress(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	mockStorage.CreateReturnBatch(&models.ReturnBatch{PVZID: pvz.ID})
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "обувь", ReceptionID: reception.ID}
+	mockStorage.CreateProduct(product)
+
+	body, _ := json.Marshal(models.ReturnRequest{PVZID: pvz.ID, ProductID: product.ID})
+	req := httptest.NewRequest(http.MethodPost, "/returns", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Приемка товара еще не закрыта")
+}
+
+// TestCreateReturn_TransferredProduct проверяет, что нельзя вернуть товар, участвующий в перемещении
+func TestCreateReturn_TransferredProduct(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("employee")
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	otherPVZ := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(otherPVZ)
+	mockStorage.CreateReturnBatch(&models.ReturnBatch{PVZID: pvz.ID})
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "обувь", ReceptionID: reception.ID}
+	mockStorage.CreateProduct(product)
+	mockStorage.CloseReception(reception.ID)
+	mockStorage.CreateTransfer(&models.Transfer{
+		SourcePVZID:      pvz.ID,
+		DestinationPVZID: otherPVZ.ID,
+		ProductIDs:       []string{product.ID},
+	})
+
+	body, _ := json.Marshal(models.ReturnRequest{PVZID: pvz.ID, ProductID: product.ID})
+	req := httptest.NewRequest(http.MethodPost, "/returns", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Товар участвует в перемещении")
+}
+
+// TestDeleteProduct_Returned проверяет, что возвращенный товар нельзя удалить после повторного открытия приемки
+func TestDeleteProduct_Returned(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	pvz := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(pvz)
+	token, _ := authService.GenerateDummyTokenForPVZ("employee", pvz.ID)
+	batch := &models.ReturnBatch{PVZID: pvz.ID}
+	mockStorage.CreateReturnBatch(batch)
+	reception := &models.Reception{PVZID: pvz.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "обувь", ReceptionID: reception.ID}
+	mockStorage.CreateProduct(product)
+	mockStorage.CloseReception(reception.ID)
+	mockStorage.CreateReturn(&models.Return{ProductID: product.ID, Type: product.Type, BatchID: batch.ID})
+	mockStorage.ReopenReception(reception.ID)
+
+	req := httptest.NewRequest(http.MethodDelete, "/products/"+product.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Товар возвращен")
+}
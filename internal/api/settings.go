@@ -0,0 +1,52 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Settings настройки API: допустимые города и включенные возможности
+type Settings struct {
+	AllowedCities     []string
+	IdempotencyKeyTTL time.Duration
+	DummyLogin        bool // POST /dummyLogin
+	LiveStream        bool // GET /receptions/live
+	Webhooks          bool // управление подписками на вебхуки
+	Idempotency       bool // повтор ответов по заголовку Idempotency-Key
+}
+
+// DefaultSettings возвращает настройки по умолчанию: три города и все возможности включены
+func DefaultSettings() Settings {
+	return Settings{
+		AllowedCities:     []string{"Москва", "Санкт-Петербург", "Казань"},
+		IdempotencyKeyTTL: 24 * time.Hour,
+		DummyLogin:        true,
+		LiveStream:        true,
+		Webhooks:          true,
+		Idempotency:       true,
+	}
+}
+
+// Configure применяет настройки; маршруты выключенных возможностей не регистрируются
+func (a *API) Configure(settings Settings) {
+	a.settings = settings
+	a.router = mux.NewRouter()
+	a.setupRoutes()
+}
+
+// isAllowedCity проверяет, что в городе можно размещать ПВЗ
+func (a *API) isAllowedCity(city string) bool {
+	for _, allowed := range a.settings.AllowedCities {
+		if city == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedCitiesList перечисляет допустимые города для сообщений об ошибках
+func (a *API) allowedCitiesList() string {
+	return strings.Join(a.settings.AllowedCities, ", ")
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConfigure_AllowedCities проверяет создание ПВЗ и фильтр списка по настроенным городам
+func TestConfigure_AllowedCities(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	settings := DefaultSettings()
+	settings.AllowedCities = []string{"Новосибирск"}
+	api.Configure(settings)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	createPVZ := func(city string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.PVZ{City: city})
+		req := httptest.NewRequest(http.MethodPost, "/pvz", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+moderatorToken)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusCreated, createPVZ("Новосибирск").Code)
+	rr := createPVZ("Москва")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Новосибирск")
+
+	req := httptest.NewRequest(http.MethodGet, "/pvz?city=Казань", nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+// TestConfigure_Features проверяет, что маршруты выключенных возможностей недоступны
+func TestConfigure_Features(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	settings := DefaultSettings()
+	settings.DummyLogin = false
+	settings.Webhooks = false
+	settings.Idempotency = false
+	api.Configure(settings)
+
+	body, _ := json.Marshal(models.DummyLoginRequest{Role: "moderator"})
+	req := httptest.NewRequest(http.MethodPost, "/dummyLogin", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+	req = httptest.NewRequest(http.MethodGet, "/webhooks", nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Без идемпотентности повтор с тем же ключом выполняется заново
+	first := postWithIdempotencyKey(api, moderatorToken, "/pvz", "key-1", models.PVZ{City: "Москва"})
+	second := postWithIdempotencyKey(api, moderatorToken, "/pvz", "key-1", models.PVZ{City: "Москва"})
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.NotEqual(t, first.Body.String(), second.Body.String())
+	assert.Empty(t, second.Header().Get(idempotentReplayedHeader))
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// handleGetStats обрабатывает запрос на получение показателей приемок
+func (a *API) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	query := r.URL.Query()
+	var filter models.StatsFilter
+	var fieldErrors []models.FieldError
+
+	if value := query.Get("startDate"); value != "" {
+		t, err := parseFilterDate(value, false)
+		if err != nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "startDate", Message: "ожидается дата в формате RFC3339 или ГГГГ-ММ-ДД"})
+		}
+		filter.StartDate = &t
+	}
+
+	if value := query.Get("endDate"); value != "" {
+		t, err := parseFilterDate(value, true)
+		if err != nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "endDate", Message: "ожидается дата в формате RFC3339 или ГГГГ-ММ-ДД"})
+		}
+		filter.EndDate = &t
+	}
+
+	if len(fieldErrors) == 0 && filter.StartDate != nil && filter.EndDate != nil && filter.StartDate.After(*filter.EndDate) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "startDate", Message: "не может быть позже endDate"})
+	}
+
+	filter.GroupBy = query.Get("groupBy")
+	if filter.GroupBy != "" && filter.GroupBy != "pvz" && filter.GroupBy != "city" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "groupBy", Message: "допустимые значения: pvz, city"})
+	}
+
+	filter.Period = query.Get("period")
+	if filter.Period != "" && filter.Period != "day" && filter.Period != "week" && filter.Period != "month" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "period", Message: "допустимые значения: day, week, month"})
+	}
+
+	if len(fieldErrors) > 0 {
+		a.respondWithValidationError(w, fieldErrors)
+		return
+	}
+
+	stats, err := a.storage.GetReceptionStats(filter)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при расчете статистики")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetStats проверяет показатели приемок с группировкой по ПВЗ
+func TestGetStats(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+
+	moscow := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(moscow)
+	kazan := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(kazan)
+
+	// В Москве две приемки: закрытая с тремя товарами и открытая с одним
+	closed := &models.Reception{PVZID: moscow.ID}
+	mockStorage.CreateReception(closed)
+	mockStorage.CreateProduct(&models.Product{Type: "электроника", ReceptionID: closed.ID})
+	mockStorage.CreateProduct(&models.Product{Type: "электроника", ReceptionID: closed.ID})
+	mockStorage.CreateProduct(&models.Product{Type: "обувь", ReceptionID: closed.ID})
+	mockStorage.CloseReception(closed.ID)
+
+	open := &models.Reception{PVZID: moscow.ID}
+	mockStorage.CreateReception(open)
+	mockStorage.CreateProduct(&models.Product{Type: "одежда", ReceptionID: open.ID})
+
+	mockStorage.CreateReception(&models.Reception{PVZID: kazan.ID})
+
+	get := func(token, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/stats?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get(employeeToken, "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = get(moderatorToken, "groupBy=city")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var stats []models.ReceptionStats
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &stats))
+	assert.Len(t, stats, 2)
+
+	var moscowStats models.ReceptionStats
+	for _, row := range stats {
+		if row.City == "Москва" {
+			moscowStats = row
+		}
+	}
+	assert.Equal(t, 2, moscowStats.ReceptionsOpened)
+	assert.Equal(t, 1, moscowStats.ReceptionsClosed)
+	assert.Equal(t, 2, moscowStats.ProductsByType["электроника"])
+	assert.Equal(t, 1, moscowStats.ProductsByType["одежда"])
+	assert.Equal(t, 2.0, moscowStats.AvgProductsPerReception)
+
+	// Итоги по дням без группировки по ПВЗ
+	rr = get(moderatorToken, "period=day")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &stats))
+	assert.Len(t, stats, 1)
+	assert.Equal(t, 3, stats[0].ReceptionsOpened)
+	assert.NotNil(t, stats[0].Period)
+
+	rr = get(moderatorToken, "groupBy=region&period=year")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var response models.Error
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(t, response.Details, 2)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// handleCreateTransfer обрабатывает запрос на создание перемещения между ПВЗ
+func (a *API) handleCreateTransfer(w http.ResponseWriter, r *http.Request) {
+	var req models.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	// Перемещение создает сотрудник ПВЗ-отправителя
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckEmployeeOfPVZ(token, req.SourcePVZID); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	if req.SourcePVZID == req.DestinationPVZID {
+		a.respondWithError(w, http.StatusBadRequest, "ПВЗ-отправитель и ПВЗ назначения должны различаться")
+		return
+	}
+
+	if len(req.ProductIDs) == 0 {
+		a.respondWithError(w, http.StatusBadRequest, "Не указаны товары для перемещения")
+		return
+	}
+
+	// Отправлять товары можно и из неработающего ПВЗ, но принимать их должен работающий
+	if _, ok := a.requireActivePVZ(w, req.DestinationPVZID); !ok {
+		return
+	}
+
+	transfer := &models.Transfer{
+		SourcePVZID:      req.SourcePVZID,
+		DestinationPVZID: req.DestinationPVZID,
+		ProductIDs:       req.ProductIDs,
+	}
+
+	if err := a.storageFor(r).CreateTransfer(transfer); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusCreated, transfer)
+}
+
+// handleGetTransfer обрабатывает запрос на получение перемещения
+func (a *API) handleGetTransfer(w http.ResponseWriter, r *http.Request) {
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRoleAny(token, "employee", "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	transfer, err := a.storage.GetTransferByID(mux.Vars(r)["transferId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "Перемещение не найдено")
+		return
+	}
+
+	// Сотрудники видят только перемещения своего ПВЗ
+	if a.auth.CheckRole(token, "moderator") != nil &&
+		a.auth.CheckEmployeeOfPVZ(token, transfer.SourcePVZID) != nil &&
+		a.auth.CheckEmployeeOfPVZ(token, transfer.DestinationPVZID) != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, transfer)
+}
+
+// handleShipTransfer обрабатывает запрос на отправку перемещения
+func (a *API) handleShipTransfer(w http.ResponseWriter, r *http.Request) {
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "employee"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	transfer, err := a.storage.GetTransferByID(mux.Vars(r)["transferId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "Перемещение не найдено")
+		return
+	}
+
+	// Отправляет сотрудник ПВЗ-отправителя
+	if err := a.auth.CheckEmployeeOfPVZ(token, transfer.SourcePVZID); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	if err := a.storageFor(r).ShipTransfer(transfer.ID); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	transfer.Status = "in_transit"
+	a.respondWithJSON(w, http.StatusOK, transfer)
+}
+
+// handleReceiveTransfer обрабатывает запрос на прием перемещения в ПВЗ назначения
+func (a *API) handleReceiveTransfer(w http.ResponseWriter, r *http.Request) {
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "employee"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	transfer, err := a.storage.GetTransferByID(mux.Vars(r)["transferId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "Перемещение не найдено")
+		return
+	}
+
+	// Принимает сотрудник ПВЗ назначения
+	if err := a.auth.CheckEmployeeOfPVZ(token, transfer.DestinationPVZID); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	if _, ok := a.requireActivePVZ(w, transfer.DestinationPVZID); !ok {
+		return
+	}
+
+	reception, err := a.storageFor(r).ReceiveTransfer(transfer.ID)
+	if err != nil {
+		a.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// На табло принятые товары появляются так же, как при обычной приемке
+	if products, err := a.storage.GetProductsByReceptionID(reception.ID); err == nil && len(products) > 0 {
+		a.publishLive("ProductAdded", reception.ID, products...)
+	}
+	a.publishLive("ReceptionClosed", reception.ID)
+
+	transfer.Status = "received"
+	transfer.ReceptionID = reception.ID
+	a.respondWithJSON(w, http.StatusOK, transfer)
+}
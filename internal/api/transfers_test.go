@@ -0,0 +1,213 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTransferFlow проверяет перемещение товаров между ПВЗ и появление их в истории ПВЗ назначения
+func TestTransferFlow(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	source := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(source)
+	destination := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(destination)
+
+	reception := &models.Reception{PVZID: source.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "одежда", ReceptionID: reception.ID}
+	mockStorage.CreateProduct(product)
+	mockStorage.CloseReception(reception.ID)
+
+	sourceToken, _ := authService.GenerateDummyTokenForPVZ("employee", source.ID)
+	destinationToken, _ := authService.GenerateDummyTokenForPVZ("employee", destination.ID)
+
+	// Сотрудник ПВЗ назначения не может создать перемещение из чужого ПВЗ
+	body, _ := json.Marshal(models.TransferRequest{
+		SourcePVZID:      source.ID,
+		DestinationPVZID: destination.ID,
+		ProductIDs:       []string{product.ID},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+destinationToken)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Создаем перемещение
+	req = httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+sourceToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var transfer models.Transfer
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &transfer))
+	assert.Equal(t, "created", transfer.Status)
+
+	// Нельзя принять неотправленное перемещение
+	req = httptest.NewRequest(http.MethodPost, "/transfers/"+transfer.ID+"/receive", nil)
+	req.Header.Set("Authorization", "Bearer "+destinationToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Отправить может только ПВЗ-отправитель
+	req = httptest.NewRequest(http.MethodPost, "/transfers/"+transfer.ID+"/ship", nil)
+	req.Header.Set("Authorization", "Bearer "+destinationToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/transfers/"+transfer.ID+"/ship", nil)
+	req.Header.Set("Authorization", "Bearer "+sourceToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Принимает ПВЗ назначения
+	// Ранее записанные события захватываются, чтобы после приема выбрались только новые
+	mockStorage.ClaimPendingEvents(time.Now(), time.Minute, 100)
+	req = httptest.NewRequest(http.MethodPost, "/transfers/"+transfer.ID+"/receive", nil)
+	req.Header.Set("Authorization", "Bearer "+destinationToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &transfer))
+	assert.Equal(t, "received", transfer.Status)
+	assert.NotEmpty(t, transfer.ReceptionID)
+
+	// Прием публикует события о товарах и приемке; создание, отправка и прием записываются в журнал от имени сотрудника
+	events, _ := mockStorage.ClaimPendingEvents(time.Now(), time.Minute, 100)
+	var receivedEvents []string
+	for _, event := range events {
+		receivedEvents = append(receivedEvents, event.Type)
+	}
+	assert.Equal(t, []string{"ProductAdded", "ReceptionClosed"}, receivedEvents)
+
+	entries, _ := mockStorage.GetAuditLog(models.AuditFilter{EntityType: "transfer", EntityID: transfer.ID})
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+		assert.Equal(t, "employee", entry.Role)
+	}
+	assert.Equal(t, []string{"transfer.receive", "transfer.ship", "transfer.create"}, actions)
+
+	// Перемещенный товар виден в истории ПВЗ назначения
+	req = httptest.NewRequest(http.MethodGet, "/pvz", nil)
+	req.Header.Set("Authorization", "Bearer "+destinationToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var pvzList []models.PVZListItem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &pvzList))
+
+	found := false
+	for _, item := range pvzList {
+		if item.PVZ.ID != destination.ID {
+			continue
+		}
+		for _, rec := range item.Receptions {
+			if rec.Reception.ID == transfer.ReceptionID {
+				found = true
+				assert.Equal(t, "close", rec.Reception.Status)
+				assert.Len(t, rec.Products, 1)
+				assert.Equal(t, "одежда", rec.Products[0].Type)
+			}
+		}
+	}
+	assert.True(t, found, "приемка перемещения не найдена в истории ПВЗ назначения")
+}
+
+// TestCreateTransfer_ProductFromOtherPVZ проверяет запрет перемещения чужого товара
+func TestCreateTransfer_ProductFromOtherPVZ(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	source := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(source)
+	other := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(other)
+
+	reception := &models.Reception{PVZID: other.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "обувь", ReceptionID: reception.ID}
+	mockStorage.CreateProduct(product)
+
+	token, _ := authService.GenerateDummyTokenForPVZ("employee", source.ID)
+
+	body, _ := json.Marshal(models.TransferRequest{
+		SourcePVZID:      source.ID,
+		DestinationPVZID: other.ID,
+		ProductIDs:       []string{product.ID},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+// TestGetTransfer_NotFound проверяет ответ на несуществующее перемещение
+func TestGetTransfer_NotFound(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	token, _ := authService.GenerateDummyToken("moderator")
+
+	req := httptest.NewRequest(http.MethodGet, "/transfers/missing-id", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+// TestDeleteProduct_InTransfer проверяет, что товар из перемещения нельзя удалить из приемки
+func TestDeleteProduct_InTransfer(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	source := &models.PVZ{City: "Москва"}
+	mockStorage.CreatePVZ(source)
+	destination := &models.PVZ{City: "Казань"}
+	mockStorage.CreatePVZ(destination)
+
+	reception := &models.Reception{PVZID: source.ID}
+	mockStorage.CreateReception(reception)
+	product := &models.Product{Type: "одежда", ReceptionID: reception.ID}
+	mockStorage.CreateProduct(product)
+	mockStorage.CreateTransfer(&models.Transfer{
+		SourcePVZID:      source.ID,
+		DestinationPVZID: destination.ID,
+		ProductIDs:       []string{product.ID},
+	})
+
+	token, _ := authService.GenerateDummyTokenForPVZ("employee", source.ID)
+
+	req := httptest.NewRequest(http.MethodDelete, "/products/"+product.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	_, err := mockStorage.GetProductByID(product.ID)
+	assert.NoError(t, err)
+}
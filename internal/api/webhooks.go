@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/aventhis/avito_pvz_service/internal/webhooks"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// minWebhookSecretLength минимальная длина ключа подписи, заданного партнером
+	minWebhookSecretLength = 16
+
+	// defaultWebhookDeliveryLimit и maxWebhookDeliveryLimit число доставок в ответе по умолчанию и максимум
+	defaultWebhookDeliveryLimit = 100
+	maxWebhookDeliveryLimit     = 1000
+)
+
+// webhookEventTypes типы событий, на которые можно подписаться
+var webhookEventTypes = map[string]bool{
+	"PVZCreated":      true,
+	"ReceptionOpened": true,
+	"ReceptionClosed": true,
+	"ProductAdded":    true,
+	"ProductDeleted":  true,
+}
+
+// handleCreateWebhook обрабатывает запрос модератора на создание подписки на события
+func (a *API) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	var req models.WebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondWithError(w, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	var fieldErrors []models.FieldError
+	if target, err := url.Parse(req.URL); err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "url", Message: "ожидается абсолютный адрес http или https"})
+	} else if err := webhooks.CheckURL(r.Context(), req.URL); err != nil {
+		// Адрес повторно проверяется при каждой отправке, так как DNS-запись может измениться
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "url", Message: "адрес не должен указывать на внутреннюю сеть"})
+	}
+	if req.Secret != "" && len(req.Secret) < minWebhookSecretLength {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "secret", Message: fmt.Sprintf("должен быть не короче %d символов", minWebhookSecretLength)})
+	}
+	for i, eventType := range req.EventTypes {
+		if !webhookEventTypes[eventType] {
+			fieldErrors = append(fieldErrors, models.FieldError{
+				Field:   fmt.Sprintf("eventTypes[%d]", i),
+				Message: "допустимые значения: PVZCreated, ReceptionOpened, ReceptionClosed, ProductAdded, ProductDeleted",
+			})
+		}
+	}
+	if req.City != "" && !a.isAllowedCity(req.City) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "city", Message: "допустимые значения: " + a.allowedCitiesList()})
+	}
+	if req.PVZID != "" {
+		if _, err := a.storage.GetPVZByID(req.PVZID); err != nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "pvzId", Message: "ПВЗ не найден"})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		a.respondWithValidationError(w, fieldErrors)
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = webhooks.GenerateSecret(); err != nil {
+			a.respondWithError(w, http.StatusInternalServerError, "Ошибка при создании подписки")
+			return
+		}
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		PVZID:      req.PVZID,
+		City:       req.City,
+	}
+	if subscription.EventTypes == nil {
+		subscription.EventTypes = []string{}
+	}
+	if err := a.storageFor(r).CreateWebhookSubscription(subscription); err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при создании подписки")
+		return
+	}
+
+	// Ключ подписи возвращается только в ответе на создание
+	a.respondWithJSON(w, http.StatusCreated, subscription)
+}
+
+// handleGetWebhooks обрабатывает запрос модератора на получение списка подписок
+func (a *API) handleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	subscriptions, err := a.storage.GetWebhookSubscriptions()
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении подписок")
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []models.WebhookSubscription{}
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	a.respondWithJSON(w, http.StatusOK, subscriptions)
+}
+
+// handleDeleteWebhook обрабатывает запрос модератора на удаление подписки
+func (a *API) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	if err := a.storageFor(r).DeleteWebhookSubscription(mux.Vars(r)["webhookId"]); err != nil {
+		a.respondWithLookupError(w, err, "Подписка не найдена")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetWebhookDeliveries обрабатывает запрос модератора на историю доставок подписки
+func (a *API) handleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	subscription, err := a.storage.GetWebhookSubscriptionByID(mux.Vars(r)["webhookId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "Подписка не найдена")
+		return
+	}
+
+	filter, ok := a.parseWebhookDeliveryFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.SubscriptionID = subscription.ID
+
+	a.respondWithWebhookDeliveries(w, filter)
+}
+
+// handleGetWebhookDeadLetters обрабатывает запрос модератора на список недоставленных событий всех подписок
+func (a *API) handleGetWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	filter, ok := a.parseWebhookDeliveryFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.Status = "dead"
+
+	a.respondWithWebhookDeliveries(w, filter)
+}
+
+// handleRedeliverWebhook обрабатывает запрос модератора на повторную отправку доставки.
+// Доставка создается в статусе pending; планировщик переводит ее в delivered после успешной отправки или в dead,
+// когда исчерпаны попытки. Повторная отправка возвращает delivered или dead доставку в pending с новым циклом
+// попыток. При удалении подписки ее доставки удаляются, поэтому доставка без подписки встречается только при
+// гонке с удалением и не возобновляется
+func (a *API) handleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	// Проверяем роль
+	token := a.getTokenFromHeader(r)
+	if err := a.auth.CheckRole(token, "moderator"); err != nil {
+		a.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+		return
+	}
+
+	delivery, err := a.storage.GetWebhookDeliveryByID(mux.Vars(r)["deliveryId"])
+	if err != nil {
+		a.respondWithLookupError(w, err, "Доставка не найдена")
+		return
+	}
+
+	if delivery.Status == "pending" {
+		a.respondWithError(w, http.StatusBadRequest, "Доставка уже ожидает отправки")
+		return
+	}
+
+	if _, err := a.storage.GetWebhookSubscriptionByID(delivery.SubscriptionID); err != nil {
+		a.respondWithLookupError(w, err, "Подписка доставки удалена")
+		return
+	}
+
+	// Повторная отправка начинает новый цикл попыток; доставку могли вернуть в pending после проверки
+	delivery, err = a.storageFor(r).RedeliverWebhookDelivery(delivery.ID)
+	if errors.Is(err, storage.ErrWebhookDeliveryPending) {
+		a.respondWithError(w, http.StatusConflict, "Доставка уже ожидает отправки")
+		return
+	}
+	if err != nil {
+		a.respondWithLookupError(w, err, "Доставка не найдена")
+		return
+	}
+
+	a.respondWithJSON(w, http.StatusOK, delivery)
+}
+
+// parseWebhookDeliveryFilter разбирает параметры status и limit; при ошибке отправляет 400
+func (a *API) parseWebhookDeliveryFilter(w http.ResponseWriter, r *http.Request) (models.WebhookDeliveryFilter, bool) {
+	query := r.URL.Query()
+	filter := models.WebhookDeliveryFilter{Status: query.Get("status"), Limit: defaultWebhookDeliveryLimit}
+
+	var fieldErrors []models.FieldError
+	if filter.Status != "" && filter.Status != "pending" && filter.Status != "delivered" && filter.Status != "dead" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "status", Message: "допустимые значения: pending, delivered, dead"})
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxWebhookDeliveryLimit {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "limit", Message: "должен быть целым числом от 1 до " + strconv.Itoa(maxWebhookDeliveryLimit)})
+		} else {
+			filter.Limit = limit
+		}
+	}
+	if len(fieldErrors) > 0 {
+		a.respondWithValidationError(w, fieldErrors)
+		return filter, false
+	}
+
+	return filter, true
+}
+
+// respondWithWebhookDeliveries отправляет доставки под фильтром, новые первыми
+func (a *API) respondWithWebhookDeliveries(w http.ResponseWriter, filter models.WebhookDeliveryFilter) {
+	deliveries, err := a.storage.GetWebhookDeliveries(filter)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, "Ошибка при получении доставок")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	a.respondWithJSON(w, http.StatusOK, deliveries)
+}
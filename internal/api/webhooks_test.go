@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/auth"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/aventhis/avito_pvz_service/internal/webhooks"
+	"github.com/stretchr/testify/assert"
+)
+
+// sendWebhookRequest отправляет запрос к API вебхуков
+func sendWebhookRequest(api *API, token, method, path string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestWebhookSubscriptions проверяет создание, просмотр и удаление подписок модератором
+func TestWebhookSubscriptions(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+	employeeToken, _ := authService.GenerateDummyToken("employee")
+
+	req := models.WebhookSubscriptionRequest{URL: "https://partner.example/hooks", EventTypes: []string{"ReceptionClosed"}, City: "Казань"}
+
+	rr := sendWebhookRequest(api, employeeToken, http.MethodPost, "/webhooks", req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodPost, "/webhooks", req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var created models.WebhookSubscription
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	assert.NotEmpty(t, created.ID)
+	assert.Len(t, created.Secret, 64)
+
+	// В списке ключ подписи не возвращается
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodGet, "/webhooks", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list []models.WebhookSubscription
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Len(t, list, 1)
+	assert.Empty(t, list[0].Secret)
+	assert.Equal(t, []string{"ReceptionClosed"}, list[0].EventTypes)
+
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodDelete, "/webhooks/"+created.ID, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodDelete, "/webhooks/"+created.ID, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+// TestCreateWebhook_Validation проверяет ошибки по полям подписки
+func TestCreateWebhook_Validation(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	req := models.WebhookSubscriptionRequest{
+		URL:        "ftp://partner.example",
+		Secret:     "short",
+		EventTypes: []string{"ReceptionClosed", "ReceptionDeleted"},
+		City:       "Омск",
+		PVZID:      "unknown",
+	}
+	rr := sendWebhookRequest(api, moderatorToken, http.MethodPost, "/webhooks", req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var response models.Error
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	var fields []string
+	for _, detail := range response.Details {
+		fields = append(fields, detail.Field)
+	}
+	assert.Equal(t, []string{"url", "secret", "eventTypes[1]", "city", "pvzId"}, fields)
+}
+
+// TestCreateWebhook_InternalAddress проверяет запрет подписок на адреса внутренней сети
+func TestCreateWebhook_InternalAddress(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	for _, url := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest/meta-data", "http://10.0.0.5/hook", "http://localhost/hook"} {
+		rr := sendWebhookRequest(api, moderatorToken, http.MethodPost, "/webhooks", models.WebhookSubscriptionRequest{URL: url})
+		assert.Equal(t, http.StatusBadRequest, rr.Code, url)
+	}
+
+	subscriptions, _ := mockStorage.GetWebhookSubscriptions()
+	assert.Empty(t, subscriptions)
+}
+
+// TestWebhookDeliveries проверяет историю доставок, список недоставленных и повторную отправку
+func TestWebhookDeliveries(t *testing.T) {
+	mockStorage := mock.New()
+	authService := auth.New("test-secret")
+	api := New(mockStorage, authService)
+
+	moderatorToken, _ := authService.GenerateDummyToken("moderator")
+
+	rr := sendWebhookRequest(api, moderatorToken, http.MethodPost, "/webhooks",
+		models.WebhookSubscriptionRequest{URL: "https://partner.example/hooks"})
+	var subscription models.WebhookSubscription
+	json.Unmarshal(rr.Body.Bytes(), &subscription)
+
+	// Событие создания ПВЗ распределяется по подписке
+	mockStorage.CreatePVZ(&models.PVZ{City: "Москва"})
+	pending, _ := mockStorage.ClaimPendingEvents(time.Now(), time.Minute, 10)
+	assert.NoError(t, webhooks.NewDispatcher(mockStorage).Publish(context.Background(), pending[0]))
+
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodGet, "/webhooks/"+subscription.ID+"/deliveries", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var deliveries []models.WebhookDelivery
+	json.Unmarshal(rr.Body.Bytes(), &deliveries)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, "PVZCreated", deliveries[0].EventType)
+
+	// Ожидающую доставку повторно отправить нельзя
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodPost, "/webhooks/deliveries/"+deliveries[0].ID+"/redeliver", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	dead := deliveries[0]
+	dead.Status = "dead"
+	dead.Attempts = 8
+	mockStorage.UpdateWebhookDelivery(&dead, deliveries[0].NextAttemptAt)
+
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodGet, "/webhooks/dead_letters", nil)
+	var deadLetters []models.WebhookDelivery
+	json.Unmarshal(rr.Body.Bytes(), &deadLetters)
+	assert.Len(t, deadLetters, 1)
+
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodPost, "/webhooks/deliveries/"+dead.ID+"/redeliver", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	stored, _ := mockStorage.GetWebhookDeliveryByID(dead.ID)
+	assert.Equal(t, "pending", stored.Status)
+	assert.Equal(t, 0, stored.Attempts)
+
+	// Доставку, уже возвращенную в pending, повторно вернуть нельзя
+	_, err := mockStorage.RedeliverWebhookDelivery(dead.ID)
+	assert.ErrorIs(t, err, storage.ErrWebhookDeliveryPending)
+
+	// Повторная отправка записывается в журнал аудита от имени модератора
+	entries, _ := mockStorage.GetAuditLog(models.AuditFilter{EntityType: "webhook_delivery", EntityID: dead.ID})
+	assert.Len(t, entries, 1)
+
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodGet, "/webhooks/dead_letters", nil)
+	assert.JSONEq(t, "[]", rr.Body.String())
+
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodGet, "/webhooks/"+subscription.ID+"/deliveries?status=lost", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodGet, "/webhooks/unknown/deliveries", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = sendWebhookRequest(api, moderatorToken, http.MethodPost, "/webhooks/deliveries/unknown/redeliver", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
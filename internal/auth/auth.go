@@ -20,7 +20,9 @@ var (
 
 // Auth представляет сервис аутентификации
 type Auth struct {
-	secret string
+	secret        string
+	tokenTTL      time.Duration
+	dummyTokenTTL time.Duration
 }
 
 // TokenClaims представляет JWT-токен
@@ -28,11 +30,22 @@ type TokenClaims struct {
 	jwt.StandardClaims
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
+	PVZID  string `json:"pvz_id,omitempty"` // ПВЗ, к которому привязан сотрудник
 }
 
 // New создает новый экземпляр Auth
 func New(secret string) *Auth {
-	return &Auth{secret: secret}
+	return &Auth{
+		secret:        secret,
+		tokenTTL:      24 * time.Hour,
+		dummyTokenTTL: 24 * time.Hour,
+	}
+}
+
+// SetTokenLifetimes задает время жизни токенов пользователей и тестовых токенов
+func (a *Auth) SetTokenLifetimes(tokenTTL, dummyTokenTTL time.Duration) {
+	a.tokenTTL = tokenTTL
+	a.dummyTokenTTL = dummyTokenTTL
 }
 
 // HashPassword хеширует пароль
@@ -45,11 +58,12 @@ func (a *Auth) HashPassword(password string) string {
 func (a *Auth) GenerateToken(user *models.User) (string, error) {
 	claims := TokenClaims{
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
+			ExpiresAt: time.Now().Add(a.tokenTTL).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 		UserID: user.ID,
 		Role:   user.Role,
+		PVZID:  user.PVZID,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -58,17 +72,23 @@ func (a *Auth) GenerateToken(user *models.User) (string, error) {
 
 // GenerateDummyToken генерирует тестовый JWT-токен с указанной ролью
 func (a *Auth) GenerateDummyToken(role string) (string, error) {
+	return a.GenerateDummyTokenForPVZ(role, "")
+}
+
+// GenerateDummyTokenForPVZ генерирует тестовый JWT-токен сотрудника, привязанного к ПВЗ
+func (a *Auth) GenerateDummyTokenForPVZ(role, pvzID string) (string, error) {
 	if role != "employee" && role != "moderator" {
 		return "", fmt.Errorf("недопустимая роль: %s", role)
 	}
 
 	claims := TokenClaims{
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
+			ExpiresAt: time.Now().Add(a.dummyTokenTTL).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 		UserID: "dummy-user",
 		Role:   role,
+		PVZID:  pvzID,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -128,4 +148,18 @@ func (a *Auth) CheckRoleAny(tokenString string, roles ...string) error {
 	}
 
 	return ErrForbidden
-} 
\ No newline at end of file
+}
+
+// CheckEmployeeOfPVZ проверяет, что пользователь является сотрудником указанного ПВЗ
+func (a *Auth) CheckEmployeeOfPVZ(tokenString, pvzID string) error {
+	claims, err := a.ValidateToken(tokenString)
+	if err != nil {
+		return ErrUnauthorized
+	}
+
+	if claims.Role != "employee" || claims.PVZID == "" || claims.PVZID != pvzID {
+		return ErrForbidden
+	}
+
+	return nil
+}
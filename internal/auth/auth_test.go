@@ -2,6 +2,7 @@ package auth
 
 import (
 	"testing"
+	"time"
 
 	"github.com/aventhis/avito_pvz_service/internal/models"
 )
@@ -123,4 +124,52 @@ func TestCheckRoleAny(t *testing.T) {
 	if err == nil {
 		t.Errorf("Должна быть ошибка при неправильных ролях")
 	}
-} 
\ No newline at end of file
+}
+
+func TestCheckEmployeeOfPVZ(t *testing.T) {
+	auth := New("test-secret")
+
+	token, _ := auth.GenerateDummyTokenForPVZ("employee", "pvz-1")
+
+	if err := auth.CheckEmployeeOfPVZ(token, "pvz-1"); err != nil {
+		t.Errorf("Сотрудник должен иметь доступ к своему ПВЗ: %v", err)
+	}
+
+	if err := auth.CheckEmployeeOfPVZ(token, "pvz-2"); err != ErrForbidden {
+		t.Errorf("Сотрудник не должен иметь доступ к чужому ПВЗ")
+	}
+
+	// Сотрудник без привязки к ПВЗ не имеет доступа
+	unbound, _ := auth.GenerateDummyToken("employee")
+	if err := auth.CheckEmployeeOfPVZ(unbound, "pvz-1"); err != ErrForbidden {
+		t.Errorf("Сотрудник без привязки не должен иметь доступ к ПВЗ")
+	}
+
+	if err := auth.CheckEmployeeOfPVZ("invalid-token", "pvz-1"); err != ErrUnauthorized {
+		t.Errorf("Должна быть ошибка авторизации для неверного токена")
+	}
+}
+
+// TestSetTokenLifetimes проверяет время жизни токенов пользователей и тестовых токенов
+func TestSetTokenLifetimes(t *testing.T) {
+	auth := New("test-secret")
+	auth.SetTokenLifetimes(2*time.Hour, 10*time.Minute)
+
+	token, _ := auth.GenerateToken(&models.User{ID: "test-id", Role: "employee"})
+	claims, err := auth.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Ошибка при проверке токена: %v", err)
+	}
+	if ttl := time.Until(time.Unix(claims.ExpiresAt, 0)); ttl < 119*time.Minute || ttl > 2*time.Hour {
+		t.Errorf("Неверное время жизни токена: %v", ttl)
+	}
+
+	token, _ = auth.GenerateDummyToken("moderator")
+	claims, err = auth.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Ошибка при проверке токена: %v", err)
+	}
+	if ttl := time.Until(time.Unix(claims.ExpiresAt, 0)); ttl < 9*time.Minute || ttl > 10*time.Minute {
+		t.Errorf("Неверное время жизни тестового токена: %v", ttl)
+	}
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted заменяет секреты при выводе конфигурации, как пароль в url.URL.Redacted
+const redacted = "xxxxx"
+
+// Config настройки сервиса. Значения берутся по умолчанию, из YAML-файла и из переменных окружения,
+// переменные окружения важнее файла
+type Config struct {
+	Server        ServerConfig      `yaml:"server"`
+	Database      DatabaseConfig    `yaml:"database"`
+	Auth          AuthConfig        `yaml:"auth"`
+	Features      FeaturesConfig    `yaml:"features"`
+	AllowedCities []string          `yaml:"allowed_cities" env:"ALLOWED_CITIES"`
+	Receptions    ReceptionsConfig  `yaml:"receptions"`
+	Events        EventsConfig      `yaml:"events"`
+	Webhooks      WebhooksConfig    `yaml:"webhooks"`
+	Idempotency   IdempotencyConfig `yaml:"idempotency"`
+	RateLimit     RateLimitConfig   `yaml:"rate_limit"`
+	Metrics       MetricsConfig     `yaml:"metrics"`
+}
+
+// ServerConfig настройки HTTP-сервера
+type ServerConfig struct {
+	Port              int           `yaml:"port" env:"PORT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"` // 0 - без ограничения, иначе поток /receptions/live обрывается
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+}
+
+// DatabaseConfig настройки подключения к PostgreSQL
+type DatabaseConfig struct {
+	URL          string `yaml:"url" env:"DB_URL"`
+	MaxOpenConns int    `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"` // 0 - без ограничения
+	MaxIdleConns int    `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+
+	ConnMaxLifetime  time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`   // 0 - без ограничения
+	ConnMaxIdleTime  time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"` // 0 - без ограничения
+	StatementTimeout time.Duration `yaml:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"`   // 0 - без ограничения
+
+	ConnectAttempts   int           `yaml:"connect_attempts" env:"DB_CONNECT_ATTEMPTS"`
+	ConnectBackoff    time.Duration `yaml:"connect_backoff" env:"DB_CONNECT_BACKOFF"`
+	ConnectMaxBackoff time.Duration `yaml:"connect_max_backoff" env:"DB_CONNECT_MAX_BACKOFF"`
+}
+
+// AuthConfig настройки токенов
+type AuthConfig struct {
+	JWTSecret     string        `yaml:"jwt_secret" env:"JWT_SECRET"`
+	TokenTTL      time.Duration `yaml:"token_ttl" env:"TOKEN_TTL"`
+	DummyTokenTTL time.Duration `yaml:"dummy_token_ttl" env:"DUMMY_TOKEN_TTL"`
+}
+
+// FeaturesConfig включение отдельных возможностей
+type FeaturesConfig struct {
+	DummyLogin  bool `yaml:"dummy_login" env:"FEATURE_DUMMY_LOGIN"`
+	LiveStream  bool `yaml:"live_stream" env:"FEATURE_LIVE_STREAM"`
+	Webhooks    bool `yaml:"webhooks" env:"FEATURE_WEBHOOKS"`
+	Idempotency bool `yaml:"idempotency" env:"FEATURE_IDEMPOTENCY"`
+}
+
+// ReceptionsConfig настройки автоматического закрытия зависших приемок
+type ReceptionsConfig struct {
+	StaleAge           time.Duration `yaml:"stale_age" env:"STALE_RECEPTION_AGE"` // 0 отключает закрытие
+	StaleCheckInterval time.Duration `yaml:"stale_check_interval" env:"STALE_RECEPTION_CHECK_INTERVAL"`
+}
+
+// EventsConfig настройки публикации доменных событий
+type EventsConfig struct {
+	Publisher           string        `yaml:"publisher" env:"EVENT_PUBLISHER"`
+	File                string        `yaml:"file" env:"EVENT_FILE"`
+	WebhookURL          string        `yaml:"webhook_url" env:"EVENT_WEBHOOK_URL"`
+	WebhookTimeout      time.Duration `yaml:"webhook_timeout" env:"EVENT_WEBHOOK_TIMEOUT"`
+	OutboxRelayInterval time.Duration `yaml:"outbox_relay_interval" env:"OUTBOX_RELAY_INTERVAL"`
+}
+
+// WebhooksConfig настройки отправки вебхуков по подпискам
+type WebhooksConfig struct {
+	DeliveryInterval time.Duration `yaml:"delivery_interval" env:"WEBHOOK_DELIVERY_INTERVAL"`
+	Timeout          time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT"`
+}
+
+// IdempotencyConfig настройки ключей идемпотентности
+type IdempotencyConfig struct {
+	KeyTTL          time.Duration `yaml:"key_ttl" env:"IDEMPOTENCY_KEY_TTL"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env:"IDEMPOTENCY_CLEANUP_INTERVAL"`
+}
+
+// RateLimitConfig настройки ограничения частоты запросов
+type RateLimitConfig struct {
+	Enabled bool   `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	Rules   string `yaml:"rules" env:"RATE_LIMITS"`
+	Store   string `yaml:"store" env:"RATE_LIMIT_STORE"`
+}
+
+// MetricsConfig настройки метрик в формате Prometheus
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled" env:"METRICS_ENABLED"`
+	Path    string `yaml:"path" env:"METRICS_PATH"`
+}
+
+// Default возвращает настройки по умолчанию. Секрет JWT не задан: его нужно указать явно
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			IdleTimeout:       60 * time.Second,
+		},
+		Database: DatabaseConfig{
+			URL:               "postgres://postgres:postgres@db:5432/postgres?sslmode=disable",
+			MaxOpenConns:      25,
+			MaxIdleConns:      25,
+			ConnMaxLifetime:   30 * time.Minute,
+			ConnMaxIdleTime:   5 * time.Minute,
+			StatementTimeout:  30 * time.Second,
+			ConnectAttempts:   10,
+			ConnectBackoff:    500 * time.Millisecond,
+			ConnectMaxBackoff: 10 * time.Second,
+		},
+		Auth: AuthConfig{
+			TokenTTL:      24 * time.Hour,
+			DummyTokenTTL: 24 * time.Hour,
+		},
+		Features: FeaturesConfig{
+			DummyLogin:  true,
+			LiveStream:  true,
+			Webhooks:    true,
+			Idempotency: true,
+		},
+		AllowedCities: []string{"Москва", "Санкт-Петербург", "Казань"},
+		Receptions: ReceptionsConfig{
+			StaleAge:           12 * time.Hour,
+			StaleCheckInterval: 5 * time.Minute,
+		},
+		Events: EventsConfig{
+			Publisher:           "none",
+			File:                "events.jsonl",
+			WebhookTimeout:      5 * time.Second,
+			OutboxRelayInterval: time.Second,
+		},
+		Webhooks: WebhooksConfig{
+			DeliveryInterval: 5 * time.Second,
+			Timeout:          10 * time.Second,
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL:          24 * time.Hour,
+			CleanupInterval: time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Rules:   "anonymous=10/1s:20; employee POST /products=10/1s:20; *=50/1s:100",
+			Store:   "memory",
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+	}
+}
+
+// Load загружает настройки: значения по умолчанию, затем файл path (если задан), затем переменные окружения,
+// и проверяет результат. При ошибке проверки возвращаются и настройки, и *ValidationError
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("чтение файла конфигурации: %w", err)
+		}
+		if err := cfg.decode(data); err != nil {
+			return nil, fmt.Errorf("файл конфигурации %s: %w", path, err)
+		}
+	}
+
+	if err := applyEnv(cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// decode читает YAML поверх текущих значений; неизвестные ключи считаются ошибкой, чтобы опечатки не терялись
+func (c *Config) decode(data []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Redacted возвращает копию настроек со скрытыми секретами
+func (c *Config) Redacted() *Config {
+	copied := *c
+	copied.AllowedCities = append([]string(nil), c.AllowedCities...)
+
+	if copied.Auth.JWTSecret != "" {
+		copied.Auth.JWTSecret = redacted
+	}
+	if u, err := url.Parse(copied.Database.URL); err == nil {
+		copied.Database.URL = u.Redacted()
+	}
+	return &copied
+}
+
+// Print выводит настройки в формате YAML со скрытыми секретами
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
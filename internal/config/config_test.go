@@ -0,0 +1,157 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeConfigFile записывает файл конфигурации во временный каталог
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Ошибка при записи файла конфигурации: %v", err)
+	}
+	return path
+}
+
+// TestLoad_FileAndEnv проверяет загрузку из файла поверх значений по умолчанию и приоритет переменных окружения
+func TestLoad_FileAndEnv(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  port: 9090
+  read_timeout: 30s
+database:
+  max_open_conns: 50
+  statement_timeout: 5s
+auth:
+  jwt_secret: file-secret-0123456789
+  token_ttl: 2h
+features:
+  dummy_login: false
+allowed_cities: [Москва, Казань]
+`)
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("PORT", "7070")
+	t.Setenv("DUMMY_TOKEN_TTL", "15m")
+	t.Setenv("FEATURE_WEBHOOKS", "false")
+
+	cfg, err := Load(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7070, cfg.Server.Port)
+	assert.Equal(t, 30*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 5*time.Second, cfg.Server.ReadHeaderTimeout)
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 5*time.Second, cfg.Database.StatementTimeout)
+	assert.Equal(t, 10, cfg.Database.ConnectAttempts)
+	assert.Equal(t, "file-secret-0123456789", cfg.Auth.JWTSecret)
+	assert.Equal(t, 2*time.Hour, cfg.Auth.TokenTTL)
+	assert.Equal(t, 15*time.Minute, cfg.Auth.DummyTokenTTL)
+	assert.False(t, cfg.Features.DummyLogin)
+	assert.False(t, cfg.Features.Webhooks)
+	assert.True(t, cfg.Features.LiveStream)
+	assert.Equal(t, []string{"Москва", "Казань"}, cfg.AllowedCities)
+}
+
+// TestLoad_EnvOnly проверяет загрузку без файла и разбор списков из переменных окружения
+func TestLoad_EnvOnly(t *testing.T) {
+	t.Setenv("JWT_SECRET", "env-secret-0123456789")
+	t.Setenv("ALLOWED_CITIES", "Москва, Санкт-Петербург ,")
+
+	cfg, err := Load("")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.Equal(t, []string{"Москва", "Санкт-Петербург"}, cfg.AllowedCities)
+}
+
+// TestLoad_Errors проверяет ошибки в файле и переменных окружения
+func TestLoad_Errors(t *testing.T) {
+	t.Setenv("JWT_SECRET", "env-secret-0123456789")
+
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+
+	// Настройки с ошибками проверки возвращаются вместе с ошибкой, чтобы их можно было вывести
+	t.Setenv("PORT", "0")
+	cfg, err := Load("")
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 0, cfg.Server.Port)
+	t.Setenv("PORT", "")
+
+	// Опечатка в ключе не пропускается молча
+	_, err = Load(writeConfigFile(t, "server:\n  prot: 9090\n"))
+	assert.ErrorContains(t, err, "prot")
+
+	t.Setenv("SERVER_READ_TIMEOUT", "10")
+	_, err = Load("")
+	assert.ErrorContains(t, err, "SERVER_READ_TIMEOUT")
+}
+
+// TestValidate проверяет, что все ошибки собираются в одну
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	assert.Error(t, cfg.Validate(), "секрет JWT обязателен")
+
+	cfg.Auth.JWTSecret = "short"
+	cfg.Server.Port = 0
+	cfg.Database.MaxOpenConns = 5
+	cfg.Database.MaxIdleConns = 10
+	cfg.AllowedCities = []string{"Москва", "Москва"}
+	cfg.Events.Publisher = "webhook"
+	cfg.Database.ConnectAttempts = 0
+	cfg.RateLimit.Rules = "employee=5"
+	cfg.Metrics.Path = "metrics"
+
+	err := cfg.Validate()
+
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []string{
+		"server.port: ожидается порт от 1 до 65535",
+		"database.max_idle_conns: не может быть больше max_open_conns",
+		"database.connect_attempts: нужна хотя бы одна попытка",
+		"auth.jwt_secret: не короче 16 символов",
+		"allowed_cities: город Москва указан дважды",
+		"events.webhook_url: обязателен для publisher: webhook",
+		`rate_limit.rules: правило "employee=5": ожидается <число>/<период>, например 10/1s`,
+		"metrics.path: должен начинаться с /",
+	}, validationErr.Problems)
+
+	// Выключенные возможности не проверяются
+	cfg = Default()
+	cfg.Auth.JWTSecret = "valid-secret-0123456789"
+	cfg.RateLimit.Enabled = false
+	cfg.RateLimit.Rules = "invalid"
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestPrint проверяет вывод настроек со скрытыми секретами
+func TestPrint(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.JWTSecret = "valid-secret-0123456789"
+
+	var buf bytes.Buffer
+	assert.NoError(t, cfg.Print(&buf))
+
+	output := buf.String()
+	assert.NotContains(t, output, "valid-secret-0123456789")
+	assert.NotContains(t, output, "postgres:postgres@")
+	assert.Contains(t, output, "jwt_secret: xxxxx")
+	assert.Contains(t, output, "postgres:xxxxx@db:5432")
+	assert.Contains(t, output, "read_timeout: 15s")
+	assert.Equal(t, "valid-secret-0123456789", cfg.Auth.JWTSecret, "исходные настройки не меняются")
+
+	// Напечатанные настройки читаются обратно
+	printed := Default()
+	assert.NoError(t, printed.decode(buf.Bytes()))
+	assert.Equal(t, cfg.Server, printed.Server)
+	assert.Equal(t, cfg.AllowedCities, printed.AllowedCities)
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationType тип длительности, который разбирается через time.ParseDuration
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv переопределяет поля с тегом env значениями заданных переменных окружения
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	return applyEnvValue(reflect.ValueOf(cfg).Elem(), lookup)
+}
+
+// applyEnvValue обходит поля структуры, включая вложенные структуры
+func applyEnvValue(value reflect.Value, lookup func(string) (string, bool)) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		fieldType := value.Type().Field(i)
+
+		if field.Kind() == reflect.Struct && fieldType.Type != durationType {
+			if err := applyEnvValue(field, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := fieldType.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw, found := lookup(name)
+		if !found || raw == "" {
+			continue
+		}
+		if err := setField(field, raw); err != nil {
+			return fmt.Errorf("переменная окружения %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setField разбирает строковое значение переменной окружения в поле
+func setField(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается длительность, например 10s или 5m")
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается целое число")
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается true или false")
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		// Список задается через запятую
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("неподдерживаемый тип поля %s", field.Type())
+	}
+	return nil
+}
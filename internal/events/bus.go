@@ -0,0 +1,91 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// subscriberBuffer число событий, которые подписчик может не успеть прочитать до отключения
+const subscriberBuffer = 64
+
+// LiveMessage событие шины с номером для возобновления потока по Last-Event-ID
+type LiveMessage struct {
+	ID    uint64
+	Event models.LiveEvent
+}
+
+// Bus шина событий внутри процесса: хранит последние события для возобновления и рассылает новые подписчикам.
+// Подписчик, не успевающий читать, отключается и переподключается с Last-Event-ID
+type Bus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []LiveMessage
+	capacity    int
+	subscribers map[chan LiveMessage]struct{}
+}
+
+// NewBus создает шину, хранящую capacity последних событий
+func NewBus(capacity int) *Bus {
+	return &Bus{
+		// Номера начинаются со времени запуска, чтобы после перезапуска они не повторяли выданные ранее
+		lastID:      uint64(time.Now().UnixMicro()),
+		capacity:    capacity,
+		subscribers: make(map[chan LiveMessage]struct{}),
+	}
+}
+
+// Publish присваивает событию номер, сохраняет его в истории и рассылает подписчикам
+func (b *Bus) Publish(event models.LiveEvent) LiveMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	message := LiveMessage{ID: b.lastID, Event: event}
+
+	if len(b.history) == b.capacity {
+		copy(b.history, b.history[1:])
+		b.history[len(b.history)-1] = message
+	} else {
+		b.history = append(b.history, message)
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- message:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return message
+}
+
+// Subscribe возвращает сохраненные события с номером больше afterID и канал новых событий.
+// Канал закрывается при отключении медленного подписчика или вызове unsubscribe
+func (b *Bus) Subscribe(afterID uint64) (replay []LiveMessage, messages <-chan LiveMessage, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, message := range b.history {
+		if message.ID > afterID {
+			replay = append(replay, message)
+		}
+	}
+
+	ch := make(chan LiveMessage, subscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+
+	unsubscribe = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return replay, ch, unsubscribe
+}
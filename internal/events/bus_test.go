@@ -0,0 +1,54 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBus_Replay проверяет возобновление с номера и ограничение истории
+func TestBus_Replay(t *testing.T) {
+	bus := NewBus(3)
+
+	var published []LiveMessage
+	for _, eventType := range []string{"ReceptionOpened", "ProductAdded", "ProductAdded", "ReceptionClosed"} {
+		published = append(published, bus.Publish(models.LiveEvent{Type: eventType}))
+	}
+	assert.Equal(t, published[0].ID+1, published[1].ID)
+
+	// Первое событие вытеснено из истории
+	replay, _, unsubscribe := bus.Subscribe(0)
+	unsubscribe()
+	assert.Len(t, replay, 3)
+	assert.Equal(t, published[1].ID, replay[0].ID)
+
+	replay, _, unsubscribe = bus.Subscribe(published[2].ID)
+	unsubscribe()
+	assert.Len(t, replay, 1)
+	assert.Equal(t, "ReceptionClosed", replay[0].Event.Type)
+}
+
+// TestBus_Subscribers проверяет рассылку новых событий и отключение медленного подписчика
+func TestBus_Subscribers(t *testing.T) {
+	bus := NewBus(10)
+
+	_, fast, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+	_, slow, _ := bus.Subscribe(0)
+
+	message := bus.Publish(models.LiveEvent{Type: "ReceptionOpened"})
+	assert.Equal(t, message, <-fast)
+
+	for i := 0; i < subscriberBuffer; i++ {
+		bus.Publish(models.LiveEvent{Type: "ProductAdded"})
+		<-fast
+	}
+
+	// Медленный подписчик не читал канал и отключен после заполнения буфера
+	received := 0
+	for range slow {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+}
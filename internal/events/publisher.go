@@ -0,0 +1,32 @@
+package events
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// Publisher доставляет доменные события другим системам
+type Publisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// multiPublisher передает событие нескольким издателям по очереди
+type multiPublisher []Publisher
+
+// Multi объединяет издателей; при ошибке одного из них событие повторно получат и те, что уже приняли его
+func Multi(publishers ...Publisher) Publisher {
+	return multiPublisher(publishers)
+}
+
+// Publish передает событие всем издателям; ошибка одного из них не мешает остальным и возвращается вместе с другими
+func (m multiPublisher) Publish(ctx context.Context, event models.Event) error {
+	var errs []error
+	for _, publisher := range m {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,35 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingPublisher всегда отказывает в публикации
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(ctx context.Context, event models.Event) error {
+	return errors.New("недоступен")
+}
+
+// TestMulti проверяет передачу события всем издателям, в том числе после ошибки одного из них
+func TestMulti(t *testing.T) {
+	var first, second bytes.Buffer
+	event := models.Event{ID: "event-id", Type: "PVZCreated"}
+
+	err := Multi(NewWriterPublisher(&first), NewWriterPublisher(&second)).Publish(context.Background(), event)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(first.String(), "\n"))
+	assert.Equal(t, 1, strings.Count(second.String(), "\n"))
+
+	var third bytes.Buffer
+	err = Multi(failingPublisher{}, NewWriterPublisher(&third)).Publish(context.Background(), event)
+	assert.Error(t, err)
+	assert.Equal(t, 1, strings.Count(third.String(), "\n"))
+}
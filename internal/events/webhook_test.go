@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWebhookPublisher проверяет отправку события на вебхук
+func TestWebhookPublisher(t *testing.T) {
+	var received models.Event
+	var eventType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		eventType = r.Header.Get("X-Event-Type")
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	publisher := NewWebhookPublisher(server.URL, time.Second)
+	event := models.Event{ID: "event-id", Type: "ProductAdded", EntityID: "product-id", DateTime: time.Now(),
+		Payload: json.RawMessage(`{"id":"product-id","type":"обувь"}`)}
+
+	err := publisher.Publish(context.Background(), event)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ProductAdded", eventType)
+	assert.Equal(t, "event-id", received.ID)
+	assert.JSONEq(t, `{"id":"product-id","type":"обувь"}`, string(received.Payload))
+}
+
+// TestWebhookPublisher_ErrorStatus проверяет, что ответ вне 2xx считается ошибкой доставки
+func TestWebhookPublisher_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	publisher := NewWebhookPublisher(server.URL, time.Second)
+	err := publisher.Publish(context.Background(), models.Event{ID: "event-id", Type: "PVZCreated"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
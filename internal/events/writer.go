@@ -0,0 +1,34 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// WriterPublisher записывает события в поток (файл или stdout) по одному JSON-объекту в строке
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterPublisher создает издателя, пишущего события в w
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+// Publish записывает событие строкой JSON
+func (p *WriterPublisher) Publish(ctx context.Context, event models.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
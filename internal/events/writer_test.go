@@ -0,0 +1,39 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWriterPublisher проверяет запись событий по одному JSON-объекту в строке
+func TestWriterPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewWriterPublisher(&buf)
+
+	first := models.Event{ID: "event-1", Type: "ReceptionOpened", EntityID: "reception-id", DateTime: time.Now(),
+		Payload: json.RawMessage(`{"id":"reception-id"}`)}
+	second := models.Event{ID: "event-2", Type: "ReceptionClosed", EntityID: "reception-id", DateTime: time.Now(),
+		Payload: json.RawMessage(`{"id":"reception-id","status":"close"}`)}
+
+	assert.NoError(t, publisher.Publish(context.Background(), first))
+	assert.NoError(t, publisher.Publish(context.Background(), second))
+
+	var lines []models.Event
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event models.Event
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		lines = append(lines, event)
+	}
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "event-1", lines[0].ID)
+	assert.Equal(t, "ReceptionClosed", lines[1].Type)
+	assert.JSONEq(t, `{"id":"reception-id","status":"close"}`, string(lines[1].Payload))
+}
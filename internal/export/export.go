@@ -0,0 +1,69 @@
+// Package export записывает табличные отчеты в форматах CSV и XLSX построчно,
+// не накапливая весь отчет в памяти
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// Форматы отчетов
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Типы содержимого форматов отчетов
+const (
+	ContentTypeCSV  = "text/csv; charset=utf-8"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// RowWriter построчно записывает отчет
+type RowWriter interface {
+	// WriteRow записывает строку отчета
+	WriteRow(values []string) error
+	// Flush передает записанные строки в нижележащий writer
+	Flush() error
+	// Close завершает отчет; после Close писать строки нельзя
+	Close() error
+}
+
+// NewWriter создает writer отчета в указанном формате
+func NewWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatXLSX:
+		return NewXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат отчета: %s", format)
+	}
+}
+
+// CSVWriter записывает отчет в формате CSV
+type CSVWriter struct {
+	w *csv.Writer
+}
+
+// NewCSVWriter создает writer отчета в формате CSV
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+// WriteRow записывает строку отчета
+func (c *CSVWriter) WriteRow(values []string) error {
+	return c.w.Write(values)
+}
+
+// Flush передает записанные строки в нижележащий writer
+func (c *CSVWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Close завершает отчет
+func (c *CSVWriter) Close() error {
+	return c.Flush()
+}
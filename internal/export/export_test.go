@@ -0,0 +1,71 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCSVWriter проверяет запись отчета в CSV
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(FormatCSV, &buf)
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.WriteRow([]string{"id", "city"}))
+	assert.NoError(t, writer.WriteRow([]string{"pvz-1", "Москва, центр"}))
+	assert.NoError(t, writer.Close())
+
+	assert.Equal(t, "id,city\npvz-1,\"Москва, центр\"\n", buf.String())
+}
+
+// TestXLSXWriter проверяет структуру книги XLSX и экранирование значений
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(FormatXLSX, &buf)
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.WriteRow([]string{"id", "type"}))
+	assert.NoError(t, writer.WriteRow([]string{"pvz-1", "<обувь> & одежда"}))
+	assert.NoError(t, writer.Close())
+
+	// Книга - корректный zip-архив со всеми обязательными частями
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	files := map[string]string{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		assert.Contains(t, files, name)
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.True(t, strings.HasSuffix(sheet, "</sheetData></worksheet>"))
+	assert.Contains(t, sheet, `<c r="B2" t="inlineStr"><is><t xml:space="preserve">&lt;обувь&gt; &amp; одежда</t></is></c>`)
+}
+
+// TestColumnName проверяет имена колонок XLSX
+func TestColumnName(t *testing.T) {
+	assert.Equal(t, "A", columnName(0))
+	assert.Equal(t, "Z", columnName(25))
+	assert.Equal(t, "AA", columnName(26))
+	assert.Equal(t, "AZ", columnName(51))
+	assert.Equal(t, "BA", columnName(52))
+}
+
+// TestNewWriter_UnknownFormat проверяет ошибку для неизвестного формата
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	_, err := NewWriter("pdf", &bytes.Buffer{})
+	assert.Error(t, err)
+}
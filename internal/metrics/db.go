@@ -0,0 +1,34 @@
+package metrics
+
+import "database/sql"
+
+// RegisterDBStats регистрирует метрики пула соединений с базой данных
+func RegisterDBStats(r *Registry, stats func() sql.DBStats) {
+	r.GaugeFunc("db_pool_max_open_connections", "Максимальное число открытых соединений.", func() float64 {
+		return float64(stats().MaxOpenConnections)
+	})
+	r.GaugeFunc("db_pool_open_connections", "Число открытых соединений.", func() float64 {
+		return float64(stats().OpenConnections)
+	})
+	r.GaugeFunc("db_pool_in_use_connections", "Число занятых соединений.", func() float64 {
+		return float64(stats().InUse)
+	})
+	r.GaugeFunc("db_pool_idle_connections", "Число свободных соединений.", func() float64 {
+		return float64(stats().Idle)
+	})
+	r.CounterFunc("db_pool_wait_count_total", "Сколько раз запрос ждал свободного соединения.", func() float64 {
+		return float64(stats().WaitCount)
+	})
+	r.CounterFunc("db_pool_wait_duration_seconds_total", "Суммарное время ожидания свободного соединения.", func() float64 {
+		return stats().WaitDuration.Seconds()
+	})
+	r.CounterFunc("db_pool_max_idle_closed_total", "Соединения, закрытые из-за ограничения числа свободных.", func() float64 {
+		return float64(stats().MaxIdleClosed)
+	})
+	r.CounterFunc("db_pool_max_idle_time_closed_total", "Соединения, закрытые после простоя дольше conn_max_idle_time.", func() float64 {
+		return float64(stats().MaxIdleTimeClosed)
+	})
+	r.CounterFunc("db_pool_max_lifetime_closed_total", "Соединения, закрытые по истечении conn_max_lifetime.", func() float64 {
+		return float64(stats().MaxLifetimeClosed)
+	})
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// metric метрика, значение которой вычисляется при каждом чтении
+type metric struct {
+	name  string
+	help  string
+	kind  string // gauge или counter
+	value func() float64
+}
+
+// Registry набор метрик, отдаваемых по HTTP в текстовом формате Prometheus
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// NewRegistry создает пустой набор метрик
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+// GaugeFunc регистрирует метрику, которая может расти и убывать, например число открытых соединений
+func (r *Registry) GaugeFunc(name, help string, value func() float64) {
+	r.register(metric{name: name, help: help, kind: "gauge", value: value})
+}
+
+// CounterFunc регистрирует монотонно растущую метрику, например число ожиданий соединения
+func (r *Registry) CounterFunc(name, help string, value func() float64) {
+	r.register(metric{name: name, help: help, kind: "counter", value: value})
+}
+
+// register добавляет метрику; повторная регистрация имени - ошибка программы
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.metrics[m.name]; exists {
+		panic(fmt.Sprintf("метрика %s уже зарегистрирована", m.name))
+	}
+	r.metrics[m.name] = m
+}
+
+// Write выводит все метрики в текстовом формате Prometheus, по алфавиту
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	metrics := make([]metric, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		metrics = append(metrics, r.metrics[name])
+	}
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		value := strconv.FormatFloat(m.value(), 'g', -1, 64)
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", m.name, m.help, m.name, m.kind, m.name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP отдает метрики для сборщика
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(w)
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRegistry_ServeHTTP проверяет вывод метрик в текстовом формате Prometheus
+func TestRegistry_ServeHTTP(t *testing.T) {
+	registry := NewRegistry()
+	value := 1.0
+	registry.GaugeFunc("b_gauge", "Показатель.", func() float64 { return value })
+	registry.CounterFunc("a_total", "Счетчик.", func() float64 { return 42 })
+
+	value = 2.5
+	rr := httptest.NewRecorder()
+	registry.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/plain")
+	assert.Equal(t, "# HELP a_total Счетчик.\n# TYPE a_total counter\na_total 42\n"+
+		"# HELP b_gauge Показатель.\n# TYPE b_gauge gauge\nb_gauge 2.5\n", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	registry.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	assert.Panics(t, func() { registry.GaugeFunc("a_total", "Повтор.", func() float64 { return 0 }) })
+}
+
+// TestRegisterDBStats проверяет метрики пула соединений
+func TestRegisterDBStats(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDBStats(registry, func() sql.DBStats {
+		return sql.DBStats{MaxOpenConnections: 25, OpenConnections: 4, InUse: 3, Idle: 1, WaitCount: 7, WaitDuration: 1500 * time.Millisecond}
+	})
+
+	rr := httptest.NewRecorder()
+	registry.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rr.Body.String()
+	assert.Contains(t, body, "db_pool_max_open_connections 25\n")
+	assert.Contains(t, body, "db_pool_in_use_connections 3\n")
+	assert.Contains(t, body, "# TYPE db_pool_wait_count_total counter\ndb_pool_wait_count_total 7\n")
+	assert.Contains(t, body, "db_pool_wait_duration_seconds_total 1.5\n")
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -10,13 +11,26 @@ type User struct {
 	Email    string `json:"email"`
 	Password string `json:"-"`
 	Role     string `json:"role"` // employee или moderator
+	PVZID    string `json:"pvzId,omitempty"` // ПВЗ, к которому привязан сотрудник
 }
 
 // PVZ представляет пункт выдачи заказов
 type PVZ struct {
 	ID               string    `json:"id"`
 	RegistrationDate time.Time `json:"registrationDate"`
-	City             string    `json:"city"` // Москва, Санкт-Петербург или Казань
+	City             string    `json:"city"`                   // Москва, Санкт-Петербург или Казань
+	Status           string    `json:"status"`                 // active, suspended или decommissioned
+	StatusReason     string    `json:"statusReason,omitempty"` // причина приостановки или закрытия
+	Address          string    `json:"address,omitempty"`
+	Latitude         *float64  `json:"latitude,omitempty"`
+	Longitude        *float64  `json:"longitude,omitempty"`
+	OpeningHours     string    `json:"openingHours,omitempty"` // например 09:00-21:00
+}
+
+// PVZWithDistance представляет ПВЗ с расстоянием до точки поиска
+type PVZWithDistance struct {
+	PVZ        PVZ     `json:"pvz"`
+	DistanceKm float64 `json:"distanceKm"`
 }
 
 // Reception представляет приемку товаров
@@ -24,7 +38,8 @@ type Reception struct {
 	ID       string    `json:"id"`
 	DateTime time.Time `json:"dateTime"`
 	PVZID    string    `json:"pvzId"`
-	Status   string    `json:"status"` // in_progress или close
+	Status       string    `json:"status"`                 // in_progress, close или cancelled
+	StatusReason string    `json:"statusReason,omitempty"` // причина отмены или автоматического закрытия
 }
 
 // Product представляет товар
@@ -33,6 +48,8 @@ type Product struct {
 	DateTime    time.Time `json:"dateTime"`
 	Type        string    `json:"type"` // электроника, одежда или обувь
 	ReceptionID string    `json:"receptionId"`
+	Sequence    int       `json:"sequence"` // порядковый номер товара в приемке, задает порядок LIFO
+	CellID      string    `json:"cellId,omitempty"` // ячейка, в которую товар размещен при добавлении
 }
 
 // LoginRequest модель для запроса авторизации
@@ -43,7 +60,8 @@ type LoginRequest struct {
 
 // DummyLoginRequest модель для тестовой авторизации
 type DummyLoginRequest struct {
-	Role string `json:"role"`
+	Role  string `json:"role"`
+	PVZID string `json:"pvzId,omitempty"`
 }
 
 // RegisterRequest модель для запроса регистрации
@@ -51,6 +69,7 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Role     string `json:"role"`
+	PVZID    string `json:"pvzId,omitempty"`
 }
 
 // ReceptionRequest модель для создания приемки
@@ -60,12 +79,20 @@ type ReceptionRequest struct {
 
 // ProductRequest модель для добавления товара
 type ProductRequest struct {
-	Type  string `json:"type"`
-	PVZID string `json:"pvzId"`
+	Type   string `json:"type"`
+	PVZID  string `json:"pvzId"`
+	CellID string `json:"cellId,omitempty"` // необязательная ячейка хранения
 }
 
 // Error модель для ошибки
 type Error struct {
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"` // ошибки по отдельным полям запроса
+}
+
+// FieldError описывает ошибку в конкретном поле запроса
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
 }
 
@@ -75,8 +102,294 @@ type PVZListItem struct {
 	Receptions []ReceptionWithProducts `json:"receptions"`
 }
 
+// PVZListFilter параметры фильтрации и пагинации списка ПВЗ
+type PVZListFilter struct {
+	StartDate       *time.Time
+	EndDate         *time.Time
+	Statuses        []string // пустой список означает все статусы
+	City            string
+	ReceptionStatus string     // фильтры по приемкам и товарам: в список попадают ПВЗ с подходящими
+	ProductType     string     // приемками, и внутри ПВЗ возвращаются только они
+	OmitProducts    bool       // возвращать приемки без товаров
+	After           *PVZCursor // при заданном курсоре Page не используется
+	Page            int
+	Limit           int
+}
+
+// PVZCursor позиция в списке ПВЗ, отсортированном по дате регистрации и ID по убыванию
+type PVZCursor struct {
+	RegistrationDate time.Time `json:"registrationDate"`
+	ID               string    `json:"id"`
+}
+
+// PVZListPage страница списка ПВЗ с метаданными пагинации
+type PVZListPage struct {
+	Items      []PVZListItem `json:"items"`
+	Page       int           `json:"page,omitempty"` // не заполняется при обходе по курсору
+	Limit      int           `json:"limit"`
+	Total      int           `json:"total"` // количество ПВЗ под фильтром без учета пагинации
+	HasMore    bool          `json:"hasMore"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+// PVZStatusRequest модель для смены статуса ПВЗ
+type PVZStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
 // ReceptionWithProducts представляет приемку с товарами
 type ReceptionWithProducts struct {
 	Reception Reception `json:"reception"`
-	Products  []Product `json:"products"`
-} 
\ No newline at end of file
+	Products  []Product `json:"products,omitempty"`
+}
+
+// ReturnBatch представляет исходящую партию возвратов ПВЗ
+type ReturnBatch struct {
+	ID       string    `json:"id"`
+	DateTime time.Time `json:"dateTime"`
+	PVZID    string    `json:"pvzId"`
+	Status   string    `json:"status"` // in_progress или close (ожидает курьера)
+}
+
+// Return представляет возвращенный покупателем товар
+type Return struct {
+	ID        string    `json:"id"`
+	DateTime  time.Time `json:"dateTime"`
+	ProductID string    `json:"productId,omitempty"` // пусто для возврата без привязки к товару
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason,omitempty"`
+	BatchID   string    `json:"batchId"`
+}
+
+// ReturnBatchRequest модель для открытия партии возвратов
+type ReturnBatchRequest struct {
+	PVZID string `json:"pvzId"`
+}
+
+// ReturnRequest модель для регистрации возврата
+type ReturnRequest struct {
+	PVZID     string `json:"pvzId"`
+	ProductID string `json:"productId"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+}
+
+// ReturnBatchWithReturns представляет партию возвратов с товарами
+type ReturnBatchWithReturns struct {
+	Batch   ReturnBatch `json:"batch"`
+	Returns []Return    `json:"returns"`
+}
+
+// StorageCell представляет ячейку хранения ПВЗ
+type StorageCell struct {
+	ID       string `json:"id"`
+	PVZID    string `json:"pvzId"`
+	Code     string `json:"code"` // код полки и ячейки, например A-01-03
+	Capacity int    `json:"capacity"`
+	Occupied int    `json:"occupied"`
+}
+
+// StorageCellRequest модель для создания ячейки хранения
+type StorageCellRequest struct {
+	Code     string `json:"code"`
+	Capacity int    `json:"capacity"`
+}
+
+// CellAssignmentRequest модель для размещения товара в ячейке
+type CellAssignmentRequest struct {
+	CellID string `json:"cellId"`
+}
+
+// ProductLocation представляет местонахождение товара в ПВЗ
+type ProductLocation struct {
+	Product Product      `json:"product"`
+	Cell    *StorageCell `json:"cell"` // nil, если товар еще не размещен
+}
+
+// Transfer представляет перемещение товаров между ПВЗ
+type Transfer struct {
+	ID               string    `json:"id"`
+	DateTime         time.Time `json:"dateTime"`
+	SourcePVZID      string    `json:"sourcePvzId"`
+	DestinationPVZID string    `json:"destinationPvzId"`
+	Status           string    `json:"status"` // created, in_transit или received
+	ProductIDs       []string  `json:"productIds"`
+	ReceptionID      string    `json:"receptionId,omitempty"` // приемка в ПВЗ назначения
+}
+
+// TransferRequest модель для создания перемещения
+type TransferRequest struct {
+	SourcePVZID      string   `json:"sourcePvzId"`
+	DestinationPVZID string   `json:"destinationPvzId"`
+	ProductIDs       []string `json:"productIds"`
+}
+
+// PVZUpdateRequest модель для изменения ПВЗ; незаданные поля не меняются
+type PVZUpdateRequest struct {
+	City         *string  `json:"city"`
+	Address      *string  `json:"address"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+	OpeningHours *string  `json:"openingHours"`
+}
+
+// StatsFilter параметры отчета по приемкам
+type StatsFilter struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+	GroupBy   string // pvz, city или пусто для итогов по всем ПВЗ
+	Period    string // day, week, month или пусто для итогов за весь диапазон
+}
+
+// ReceptionStats агрегированные показатели приемок за период
+type ReceptionStats struct {
+	PVZID                       string         `json:"pvzId,omitempty"`
+	City                        string         `json:"city,omitempty"`
+	Period                      *time.Time     `json:"period,omitempty"` // начало дня, недели или месяца
+	ReceptionsOpened            int            `json:"receptionsOpened"`
+	ReceptionsClosed            int            `json:"receptionsClosed"`
+	ProductsByType              map[string]int `json:"productsByType"`
+	AvgProductsPerReception     float64        `json:"avgProductsPerReception"`
+	AvgReceptionDurationSeconds float64        `json:"avgReceptionDurationSeconds"` // по закрытым приемкам
+}
+
+// ProductBatchItem товар в пакетном добавлении
+type ProductBatchItem struct {
+	Type string `json:"type"`
+}
+
+// ProductBatchRequest модель для пакетного добавления товаров в открытую приемку
+type ProductBatchRequest struct {
+	PVZID    string             `json:"pvzId"`
+	Products []ProductBatchItem `json:"products"`
+}
+
+// ProductUpdateRequest модель для исправления типа товара
+type ProductUpdateRequest struct {
+	Type string `json:"type"`
+}
+
+// ProductChange запись журнала исправлений товаров в приемке
+type ProductChange struct {
+	ID          string    `json:"id"`
+	DateTime    time.Time `json:"dateTime"`
+	ProductID   string    `json:"productId"`
+	ReceptionID string    `json:"receptionId"`
+	Action      string    `json:"action"` // delete или change_type
+	OldType     string    `json:"oldType"`
+	NewType     string    `json:"newType,omitempty"` // только для change_type
+	UserID      string    `json:"userId"`
+}
+
+// ReceptionCancelRequest модель для отмены приемки модератором
+type ReceptionCancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Actor пользователь, от имени которого выполняется изменение
+type Actor struct {
+	UserID    string
+	Role      string // employee, moderator или system для фоновых задач
+	RequestID string
+}
+
+// AuditEntry запись журнала аудита
+type AuditEntry struct {
+	ID         string          `json:"id"`
+	DateTime   time.Time       `json:"dateTime"`
+	UserID     string          `json:"userId"`
+	Role       string          `json:"role"`
+	RequestID  string          `json:"requestId,omitempty"`
+	Action     string          `json:"action"` // например reception.close
+	EntityType string          `json:"entityType"`
+	EntityID   string          `json:"entityId"`
+	Before     json.RawMessage `json:"before,omitempty"` // состояние до изменения, пусто при создании
+	After      json.RawMessage `json:"after,omitempty"`  // состояние после изменения, пусто при удалении
+}
+
+// AuditFilter параметры выборки журнала аудита
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	UserID     string
+	StartDate  *time.Time
+	EndDate    *time.Time
+	Limit      int
+}
+
+// Event доменное событие, передаваемое другим системам через outbox
+type Event struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"` // PVZCreated, ReceptionOpened, ReceptionClosed, ProductAdded или ProductDeleted
+	EntityID string          `json:"entityId"`
+	DateTime time.Time       `json:"dateTime"`
+	Payload  json.RawMessage `json:"payload"` // состояние сущности после события, для ProductDeleted - удаленный товар
+}
+
+// WebhookSubscription подписка партнера на доменные события
+type WebhookSubscription struct {
+	ID         string    `json:"id"`
+	DateTime   time.Time `json:"dateTime"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"` // ключ HMAC-подписи, в ответах возвращается только при создании
+	EventTypes []string  `json:"eventTypes"`       // пустой список означает все события
+	PVZID      string    `json:"pvzId,omitempty"`  // только события этого ПВЗ
+	City       string    `json:"city,omitempty"`   // только события ПВЗ этого города
+}
+
+// WebhookSubscriptionRequest модель для создания подписки; без secret ключ создается сервером
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"eventTypes"`
+	PVZID      string   `json:"pvzId"`
+	City       string   `json:"city"`
+}
+
+// WebhookDelivery доставка события по подписке
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	DateTime       time.Time       `json:"dateTime"`
+	SubscriptionID string          `json:"subscriptionId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"` // тело запроса: событие целиком
+	Status         string          `json:"status"`  // pending, delivered или dead (исчерпаны попытки)
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	LastError      string          `json:"lastError,omitempty"`
+	ResponseStatus int             `json:"responseStatus,omitempty"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+// WebhookDeliveryFilter параметры выборки доставок
+type WebhookDeliveryFilter struct {
+	SubscriptionID string
+	Status         string
+	Limit          int
+}
+
+// LiveEvent событие потока активности приемок для табло в реальном времени
+type LiveEvent struct {
+	Type         string     `json:"type"` // ReceptionOpened, ReceptionClosed, ReceptionReopened, ReceptionCancelled, ProductAdded, ProductDeleted или ProductUpdated
+	DateTime     time.Time  `json:"dateTime"`
+	PVZID        string     `json:"pvzId"`
+	City         string     `json:"city"`
+	Reception    *Reception `json:"reception"`
+	Products     []Product  `json:"products,omitempty"` // затронутые товары для событий товаров
+	ProductCount int        `json:"productCount"`       // товаров в приемке после изменения
+}
+
+// IdempotencyRecord ответ на запрос с ключом идемпотентности, повторяемый для повторов запроса
+type IdempotencyRecord struct {
+	UserID      string
+	Key         string
+	RequestHash string // хэш метода, пути и тела первого запроса
+	StatusCode  int    // 0, пока первый запрос выполняется
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	LockedUntil time.Time // пока первый запрос выполняется, ключ закреплен за ним до этого времени
+}
@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// AnyRole в правиле означает любую роль
+	AnyRole = "*"
+
+	// AnonymousRole роль запросов без действительного токена; они ограничиваются по IP клиента
+	AnonymousRole = "anonymous"
+
+	// sweepInterval как часто из хранилища удаляются неиспользуемые корзины
+	sweepInterval = time.Minute
+)
+
+// Limit ограничение по алгоритму корзины токенов: до Burst запросов подряд,
+// корзина пополняется на Rate запросов в секунду
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Rule ограничение для роли; с заданным Route - только для этого маршрута
+type Rule struct {
+	Role   string // роль из токена, AnonymousRole или AnyRole
+	Method string // пустой метод - любой
+	Route  string // шаблон маршрута, например /pvz/{pvzId}; пустой - все маршруты без своего правила
+	Limit  Limit
+}
+
+// Status состояние корзины после проверки запроса
+type Status struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // через сколько будет доступен запрос, если он отклонен
+	ResetAfter time.Duration // через сколько корзина наполнится полностью
+}
+
+// Store хранилище корзин токенов
+type Store interface {
+	// TakeRateLimitToken пополняет корзину key на прошедшее время и забирает из нее токен, если он есть.
+	// Возвращает оставшееся число токенов и был ли токен выдан
+	TakeRateLimitToken(key string, rate float64, burst int, now time.Time) (float64, bool, error)
+	// DeleteIdleRateLimitBuckets удаляет корзины, не использованные с idleSince
+	DeleteIdleRateLimitBuckets(idleSince time.Time) (int, error)
+}
+
+// Limiter ограничивает частоту запросов по правилам для ролей и маршрутов
+type Limiter struct {
+	store     Store
+	rules     []Rule
+	idleAfter time.Duration // за это время любая корзина наполняется полностью и ее можно удалить
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewLimiter создает ограничитель с правилами
+func NewLimiter(store Store, rules []Rule) *Limiter {
+	var idleAfter time.Duration
+	for _, rule := range rules {
+		refill := time.Duration(float64(rule.Limit.Burst) / rule.Limit.Rate * float64(time.Second))
+		if refill > idleAfter {
+			idleAfter = refill
+		}
+	}
+
+	return &Limiter{
+		store:     store,
+		rules:     rules,
+		idleAfter: idleAfter,
+	}
+}
+
+// match выбирает самое точное правило для запроса: правило маршрута важнее общего,
+// правило роли важнее правила для любой роли
+func (l *Limiter) match(role, method, route string) (Rule, bool) {
+	var best Rule
+	bestScore := -1
+	for _, rule := range l.rules {
+		if rule.Role != AnyRole && rule.Role != role {
+			continue
+		}
+		if rule.Route != "" && (rule.Route != route || (rule.Method != "" && rule.Method != method)) {
+			continue
+		}
+
+		score := 0
+		if rule.Route != "" {
+			score += 2
+		}
+		if rule.Role != AnyRole {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rule, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// Allow расходует токен из корзины subject (пользователь или IP клиента) для запроса.
+// Второе значение false, если к запросу не применяется ни одно правило
+func (l *Limiter) Allow(subject, role, method, route string, now time.Time) (Status, bool, error) {
+	rule, ok := l.match(role, method, route)
+	if !ok {
+		return Status{}, false, nil
+	}
+
+	// У правила маршрута своя корзина, общие правила делят одну корзину на все маршруты.
+	// Роль входит в ключ, чтобы у одного пользователя с разными ролями были разные корзины
+	key := fmt.Sprintf("%s|%s|%s %s", subject, role, rule.Method, rule.Route)
+	tokens, allowed, err := l.store.TakeRateLimitToken(key, rule.Limit.Rate, rule.Limit.Burst, now)
+	if err != nil {
+		return Status{}, true, err
+	}
+	l.sweep(now)
+
+	status := Status{
+		Allowed:    allowed,
+		Limit:      rule.Limit.Burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: secondsToDuration((float64(rule.Limit.Burst) - tokens) / rule.Limit.Rate),
+	}
+	if !allowed {
+		status.RetryAfter = secondsToDuration((1 - tokens) / rule.Limit.Rate)
+	}
+	return status, true, nil
+}
+
+// sweep раз в sweepInterval удаляет в фоне корзины, которые успели наполниться полностью
+func (l *Limiter) sweep(now time.Time) {
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) < sweepInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.lastSweep = now
+	l.mu.Unlock()
+
+	go func() {
+		if _, err := l.store.DeleteIdleRateLimitBuckets(now.Add(-l.idleAfter)); err != nil {
+			log.Printf("Ошибка при удалении неиспользуемых корзин ограничения запросов: %v", err)
+		}
+	}()
+}
+
+// secondsToDuration переводит секунды в длительность, отрицательные значения - в ноль
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
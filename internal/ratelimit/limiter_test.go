@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLimiter_TokenBucket проверяет расход корзины, отказ при пустой корзине и пополнение со временем
+func TestLimiter_TokenBucket(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), []Rule{{Role: AnyRole, Limit: Limit{Rate: 1, Burst: 3}}})
+	now := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+
+	for i := 2; i >= 0; i-- {
+		status, ok, err := limiter.Allow("user:1", "employee", "GET", "/pvz", now)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, status.Allowed)
+		assert.Equal(t, 3, status.Limit)
+		assert.Equal(t, i, status.Remaining)
+	}
+
+	status, _, _ := limiter.Allow("user:1", "employee", "GET", "/pvz", now)
+	assert.False(t, status.Allowed)
+	assert.Equal(t, 0, status.Remaining)
+	assert.Equal(t, time.Second, status.RetryAfter)
+	assert.Equal(t, 3*time.Second, status.ResetAfter)
+
+	// Другой пользователь ограничивается отдельно
+	status, _, _ = limiter.Allow("user:2", "employee", "GET", "/pvz", now)
+	assert.True(t, status.Allowed)
+
+	// Через полсекунды токена еще нет, через секунду есть
+	status, _, _ = limiter.Allow("user:1", "employee", "GET", "/pvz", now.Add(500*time.Millisecond))
+	assert.False(t, status.Allowed)
+	assert.Equal(t, 500*time.Millisecond, status.RetryAfter)
+	status, _, _ = limiter.Allow("user:1", "employee", "GET", "/pvz", now.Add(time.Second))
+	assert.True(t, status.Allowed)
+
+	// Корзина не наполняется сверх размера
+	status, _, _ = limiter.Allow("user:1", "employee", "GET", "/pvz", now.Add(time.Hour))
+	assert.True(t, status.Allowed)
+	assert.Equal(t, 2, status.Remaining)
+}
+
+// TestLimiter_Rules проверяет выбор самого точного правила и отдельные корзины маршрутов
+func TestLimiter_Rules(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), []Rule{
+		{Role: AnyRole, Limit: Limit{Rate: 1, Burst: 10}},
+		{Role: AnonymousRole, Limit: Limit{Rate: 1, Burst: 2}},
+		{Role: "employee", Method: "POST", Route: "/products", Limit: Limit{Rate: 1, Burst: 1}},
+	})
+	now := time.Now()
+
+	status, _, _ := limiter.Allow("ip:10.0.0.1", AnonymousRole, "POST", "/login", now)
+	assert.Equal(t, 2, status.Limit)
+	status, _, _ = limiter.Allow("user:1", "moderator", "POST", "/products", now)
+	assert.Equal(t, 10, status.Limit)
+
+	// Правило маршрута расходует свою корзину, а не общую корзину роли
+	status, _, _ = limiter.Allow("user:1", "employee", "POST", "/products", now)
+	assert.True(t, status.Allowed)
+	assert.Equal(t, 1, status.Limit)
+	status, _, _ = limiter.Allow("user:1", "employee", "POST", "/products", now)
+	assert.False(t, status.Allowed)
+	status, _, _ = limiter.Allow("user:1", "employee", "GET", "/products/{productId}", now)
+	assert.True(t, status.Allowed)
+	assert.Equal(t, 9, status.Remaining)
+
+	// Без подходящего правила запрос не ограничивается
+	limiter = NewLimiter(NewMemoryStore(), []Rule{{Role: "employee", Limit: Limit{Rate: 1, Burst: 1}}})
+	_, ok, err := limiter.Allow("user:1", "moderator", "GET", "/pvz", now)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// TestMemoryStore_DeleteIdle проверяет удаление неиспользуемых корзин
+func TestMemoryStore_DeleteIdle(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+
+	store.TakeRateLimitToken("idle", 1, 5, now.Add(-time.Hour))
+	store.TakeRateLimitToken("active", 1, 5, now)
+
+	deleted, err := store.DeleteIdleRateLimitBuckets(now.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	// Оставшаяся корзина сохранила расход
+	tokens, allowed, _ := store.TakeRateLimitToken("active", 1, 5, now)
+	assert.True(t, allowed)
+	assert.Equal(t, 3.0, tokens)
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// bucket корзина токенов
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryStore хранит корзины в памяти процесса; при нескольких репликах у каждой свои ограничения
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewMemoryStore создает хранилище корзин в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// TakeRateLimitToken пополняет корзину на прошедшее время и забирает из нее токен, если он есть
+func (s *MemoryStore) TakeRateLimitToken(key string, rate float64, burst int, now time.Time) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.updatedAt).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+		b.updatedAt = now
+	}
+
+	if b.tokens < 1 {
+		return b.tokens, false, nil
+	}
+	b.tokens--
+	return b.tokens, true, nil
+}
+
+// DeleteIdleRateLimitBuckets удаляет корзины, не использованные с idleSince
+func (s *MemoryStore) DeleteIdleRateLimitBuckets(idleSince time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for key, b := range s.buckets {
+		if b.updatedAt.Before(idleSince) {
+			delete(s.buckets, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
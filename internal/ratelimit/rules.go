@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseRules разбирает правила из строки вида
+//
+//	anonymous=10/1s:20; employee POST /products=5/s; *=100/1m
+//
+// Правила разделяются ";". Слева от "=" роль и, если нужно, метод и шаблон маршрута,
+// справа - число запросов за период и необязательный размер корзины после ":" (по умолчанию равен числу запросов)
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		rule, err := parseRule(entry)
+		if err != nil {
+			return nil, fmt.Errorf("правило %q: %w", entry, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseRule разбирает одно правило
+func parseRule(entry string) (Rule, error) {
+	selector, limit, found := strings.Cut(entry, "=")
+	if !found {
+		return Rule{}, fmt.Errorf("ожидается <роль> [<метод> <маршрут>]=<число>/<период>[:<корзина>]")
+	}
+
+	var rule Rule
+	fields := strings.Fields(selector)
+	switch len(fields) {
+	case 1:
+		rule.Role = fields[0]
+	case 3:
+		rule.Role, rule.Method, rule.Route = fields[0], strings.ToUpper(fields[1]), fields[2]
+		if rule.Method == AnyRole {
+			rule.Method = ""
+		}
+		if !strings.HasPrefix(rule.Route, "/") {
+			return Rule{}, fmt.Errorf("маршрут должен начинаться с /")
+		}
+	default:
+		return Rule{}, fmt.Errorf("ожидается роль или роль, метод и маршрут")
+	}
+
+	parsed, err := parseLimit(strings.TrimSpace(limit))
+	if err != nil {
+		return Rule{}, err
+	}
+	rule.Limit = parsed
+	return rule, nil
+}
+
+// parseLimit разбирает ограничение вида 10/1s:20
+func parseLimit(value string) (Limit, error) {
+	rate, burstValue, hasBurst := strings.Cut(value, ":")
+	countValue, periodValue, found := strings.Cut(rate, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("ожидается <число>/<период>, например 10/1s")
+	}
+
+	count, err := strconv.Atoi(countValue)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("число запросов должно быть положительным целым")
+	}
+
+	// Период без числа, например "s", означает одну единицу
+	if periodValue != "" && (periodValue[0] < '0' || periodValue[0] > '9') {
+		periodValue = "1" + periodValue
+	}
+	period, err := time.ParseDuration(periodValue)
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("период должен быть положительной длительностью, например 1s или 1m")
+	}
+
+	burst := count
+	if hasBurst {
+		burst, err = strconv.Atoi(burstValue)
+		if err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("размер корзины должен быть положительным целым")
+		}
+	}
+
+	return Limit{Rate: float64(count) / period.Seconds(), Burst: burst}, nil
+}
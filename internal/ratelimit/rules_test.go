@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseRules проверяет разбор правил для ролей и маршрутов
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("anonymous=10/1s:20; employee post /products=5/s;*=120/1m ;")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Role: AnonymousRole, Limit: Limit{Rate: 10, Burst: 20}},
+		{Role: "employee", Method: "POST", Route: "/products", Limit: Limit{Rate: 5, Burst: 5}},
+		{Role: AnyRole, Limit: Limit{Rate: 2, Burst: 120}},
+	}, rules)
+
+	rules, err = ParseRules("")
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+// TestParseRules_Invalid проверяет ошибки в правилах
+func TestParseRules_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"employee",
+		"employee POST=5/s",
+		"employee POST products=5/s",
+		"employee=0/s",
+		"employee=5",
+		"employee=5/0s",
+		"employee=5/week",
+		"employee=5/s:0",
+	} {
+		_, err := ParseRules(spec)
+		assert.Error(t, err, spec)
+	}
+}
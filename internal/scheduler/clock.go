@@ -0,0 +1,22 @@
+package scheduler
+
+import "time"
+
+// Clock источник времени планировщика; в тестах подменяется управляемыми часами
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock системные часы
+type SystemClock struct{}
+
+// Now возвращает текущее время
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After возвращает канал, в который придет время через d
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// IdempotencyKeyStore хранилище ключей идемпотентности, из которого удаляются истекшие
+type IdempotencyKeyStore interface {
+	DeleteExpiredIdempotencyKeys(now time.Time) (int, error)
+}
+
+// IdempotencyKeyCleaner периодически удаляет истекшие ключи идемпотентности.
+// Истекший ключ занимается заново и без очистки, она лишь не дает таблице расти
+type IdempotencyKeyCleaner struct {
+	store    IdempotencyKeyStore
+	clock    Clock
+	interval time.Duration
+}
+
+// NewIdempotencyKeyCleaner создает планировщик очистки ключей идемпотентности
+func NewIdempotencyKeyCleaner(store IdempotencyKeyStore, clock Clock, interval time.Duration) *IdempotencyKeyCleaner {
+	return &IdempotencyKeyCleaner{
+		store:    store,
+		clock:    clock,
+		interval: interval,
+	}
+}
+
+// Run удаляет истекшие ключи каждые interval до отмены контекста
+func (c *IdempotencyKeyCleaner) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(c.interval):
+			if _, err := c.RunOnce(); err != nil {
+				log.Printf("Ошибка при удалении истекших ключей идемпотентности: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce удаляет истекшие ключи и возвращает их число
+func (c *IdempotencyKeyCleaner) RunOnce() (int, error) {
+	return c.store.DeleteExpiredIdempotencyKeys(c.clock.Now())
+}
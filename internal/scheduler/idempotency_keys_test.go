@@ -0,0 +1,31 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestIdempotencyKeyCleaner_RunOnce проверяет удаление только истекших ключей
+func TestIdempotencyKeyCleaner_RunOnce(t *testing.T) {
+	storage := mock.New()
+	now := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	clock := newFakeClock(now)
+
+	storage.ReserveIdempotencyKey(&models.IdempotencyRecord{UserID: "user-1", Key: "expired", RequestHash: "a", CreatedAt: now.Add(-25 * time.Hour), ExpiresAt: now.Add(-time.Hour)})
+	storage.ReserveIdempotencyKey(&models.IdempotencyRecord{UserID: "user-1", Key: "active", RequestHash: "b", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(23 * time.Hour), LockedUntil: now.Add(time.Hour)})
+
+	cleaner := NewIdempotencyKeyCleaner(storage, clock, time.Hour)
+	deleted, err := cleaner.RunOnce()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	// Действующий ключ остался занятым
+	existing, reserved, _ := storage.ReserveIdempotencyKey(&models.IdempotencyRecord{UserID: "user-1", Key: "active", RequestHash: "b", CreatedAt: now, ExpiresAt: now.Add(24 * time.Hour)})
+	assert.False(t, reserved)
+	assert.Equal(t, "b", existing.RequestHash)
+}
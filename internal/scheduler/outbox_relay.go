@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/events"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+const (
+	// outboxBatchSize число событий, передаваемых за один проход
+	outboxBatchSize = 100
+
+	// outboxLease время, на которое выбранное событие скрывается от других реплик; неудачная публикация
+	// повторяется после его истечения
+	outboxLease = time.Minute
+
+	// outboxMaxAttempts число попыток, после которого событие переносится в недоставленные
+	outboxMaxAttempts = 10
+)
+
+// EventSource хранилище исходящих событий (outbox)
+type EventSource interface {
+	ClaimPendingEvents(now time.Time, lease time.Duration, limit int) ([]models.Event, error)
+	MarkEventPublished(id string, publishedAt time.Time) error
+	MarkEventFailed(id, reason string, now time.Time, maxAttempts int) (bool, error)
+}
+
+// OutboxRelay периодически передает неопубликованные события из outbox издателю в порядке записи.
+// Доставка выполняется не менее одного раза: получатели отбрасывают повторы по ID события. Событие, которое
+// не удалось опубликовать, не задерживает остальные и повторяется позже, поэтому при сбоях порядок не гарантируется
+type OutboxRelay struct {
+	store     EventSource
+	publisher events.Publisher
+	clock     Clock
+	interval  time.Duration
+}
+
+// NewOutboxRelay создает ретранслятор событий из outbox
+func NewOutboxRelay(store EventSource, publisher events.Publisher, clock Clock, interval time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		store:     store,
+		publisher: publisher,
+		clock:     clock,
+		interval:  interval,
+	}
+}
+
+// Run передает события каждые interval до отмены контекста
+func (r *OutboxRelay) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.clock.After(r.interval):
+			if _, err := r.RunOnce(ctx); err != nil {
+				log.Printf("Ошибка при публикации событий: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce передает очередную порцию событий и возвращает число опубликованных. Ошибки публикации
+// не прерывают проход и возвращаются вместе; после outboxMaxAttempts попыток событие переносится в недоставленные
+func (r *OutboxRelay) RunOnce(ctx context.Context) (int, error) {
+	now := r.clock.Now()
+	claimed, err := r.store.ClaimPendingEvents(now, outboxLease, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	var errs []error
+	for _, event := range claimed {
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("событие %s (%s): %w", event.ID, event.Type, err))
+			dead, markErr := r.store.MarkEventFailed(event.ID, err.Error(), now, outboxMaxAttempts)
+			if markErr != nil {
+				log.Printf("Ошибка при сохранении неудачной публикации события %s: %v", event.ID, markErr)
+			}
+			if dead {
+				log.Printf("Событие %s (%s) не опубликовано после %d попыток и перенесено в недоставленные: %v",
+					event.ID, event.Type, outboxMaxAttempts, err)
+			}
+			continue
+		}
+		if err := r.store.MarkEventPublished(event.ID, r.clock.Now()); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, errors.Join(errs...)
+}
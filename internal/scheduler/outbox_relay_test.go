@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingPublisher запоминает опубликованные события; при заданной ошибке отказывает во всех событиях
+// или только в событиях типа failType
+type recordingPublisher struct {
+	events   []models.Event
+	err      error
+	failType string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event models.Event) error {
+	if p.err != nil && (p.failType == "" || p.failType == event.Type) {
+		return p.err
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+// eventTypes возвращает типы событий по порядку
+func eventTypes(events []models.Event) []string {
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+// TestOutboxRelay_RunOnce проверяет публикацию событий в порядке изменений и однократную отметку
+func TestOutboxRelay_RunOnce(t *testing.T) {
+	storage := mock.New()
+	clock := newFakeClock(time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC))
+
+	pvz := &models.PVZ{City: "Москва"}
+	storage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	storage.CreateReception(reception)
+	storage.CreateProduct(&models.Product{Type: "обувь", ReceptionID: reception.ID})
+	storage.DeleteLastProductInReception(reception.ID)
+	storage.CloseReception(reception.ID)
+
+	publisher := &recordingPublisher{}
+	relay := NewOutboxRelay(storage, publisher, clock, time.Second)
+
+	published, err := relay.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, published)
+	assert.Equal(t, []string{"PVZCreated", "ReceptionOpened", "ProductAdded", "ProductDeleted", "ReceptionClosed"},
+		eventTypes(publisher.events))
+	assert.Equal(t, reception.ID, publisher.events[4].EntityID)
+
+	// Опубликованные события повторно не отправляются
+	published, err = relay.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+	assert.Len(t, publisher.events, 5)
+}
+
+// TestOutboxRelay_PublishError проверяет, что неотправленное событие остается в outbox до следующей попытки
+func TestOutboxRelay_PublishError(t *testing.T) {
+	storage := mock.New()
+	clock := newFakeClock(time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC))
+
+	storage.CreatePVZ(&models.PVZ{City: "Казань"})
+	storage.CreatePVZ(&models.PVZ{City: "Москва"})
+
+	publisher := &recordingPublisher{err: errors.New("недоступен")}
+	relay := NewOutboxRelay(storage, publisher, clock, time.Second)
+
+	published, err := relay.RunOnce(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, published)
+
+	// События остаются в outbox: после истечения аренды их снова можно захватить
+	pending, _ := storage.ClaimPendingEvents(clock.Now().Add(outboxLease), 0, 10)
+	assert.Len(t, pending, 2)
+
+	// До истечения аренды события не выбираются повторно
+	publisher.err = nil
+	published, err = relay.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	clock.Advance(outboxLease)
+	published, err = relay.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+}
+
+// TestOutboxRelay_ContinuesAfterError проверяет, что событие с ошибкой не задерживает остальные
+func TestOutboxRelay_ContinuesAfterError(t *testing.T) {
+	storage := mock.New()
+	clock := newFakeClock(time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC))
+
+	pvz := &models.PVZ{City: "Москва"}
+	storage.CreatePVZ(pvz)
+	storage.CreateReception(&models.Reception{PVZID: pvz.ID})
+
+	publisher := &recordingPublisher{err: errors.New("недоступен"), failType: "PVZCreated"}
+	relay := NewOutboxRelay(storage, publisher, clock, time.Second)
+
+	published, err := relay.RunOnce(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{"ReceptionOpened"}, eventTypes(publisher.events))
+
+	pending, _ := storage.ClaimPendingEvents(clock.Now().Add(outboxLease), 0, 10)
+	assert.Equal(t, []string{"PVZCreated"}, eventTypes(pending))
+}
+
+// TestOutboxRelay_DeadLetter проверяет перенос события в недоставленные после исчерпания попыток
+func TestOutboxRelay_DeadLetter(t *testing.T) {
+	storage := mock.New()
+	clock := newFakeClock(time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC))
+
+	storage.CreatePVZ(&models.PVZ{City: "Казань"})
+
+	publisher := &recordingPublisher{err: errors.New("недоступен")}
+	relay := NewOutboxRelay(storage, publisher, clock, time.Second)
+
+	for i := 0; i < outboxMaxAttempts; i++ {
+		_, err := relay.RunOnce(context.Background())
+		assert.Error(t, err)
+		clock.Advance(outboxLease)
+	}
+
+	pending, _ := storage.ClaimPendingEvents(clock.Now(), 0, 10)
+	assert.Empty(t, pending)
+
+	// Недоставленное событие больше не выбирается
+	publisher.err = nil
+	published, err := relay.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+}
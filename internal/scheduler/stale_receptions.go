@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// ReceptionCloser хранилище, умеющее закрывать зависшие приемки
+type ReceptionCloser interface {
+	CloseStaleReceptions(openedBefore, closedAt time.Time, reason string) ([]models.Reception, error)
+}
+
+// LivePublisher публикует события приемок в поток активности табло
+type LivePublisher interface {
+	PublishLive(eventType, receptionID string)
+}
+
+// StaleReceptionCloser периодически закрывает приемки, открытые дольше maxAge.
+// На нескольких репликах работу выполняет та, что получила блокировку в хранилище
+type StaleReceptionCloser struct {
+	store    ReceptionCloser
+	live     LivePublisher
+	clock    Clock
+	maxAge   time.Duration
+	interval time.Duration
+}
+
+// NewStaleReceptionCloser создает планировщик закрытия зависших приемок
+func NewStaleReceptionCloser(store ReceptionCloser, clock Clock, maxAge, interval time.Duration) *StaleReceptionCloser {
+	return &StaleReceptionCloser{
+		store:    store,
+		clock:    clock,
+		maxAge:   maxAge,
+		interval: interval,
+	}
+}
+
+// SetLivePublisher включает публикацию закрытых приемок в поток активности; без него они не публикуются
+func (c *StaleReceptionCloser) SetLivePublisher(live LivePublisher) {
+	c.live = live
+}
+
+// Run проверяет приемки каждые interval до отмены контекста
+func (c *StaleReceptionCloser) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(c.interval):
+			if _, err := c.RunOnce(); err != nil {
+				log.Printf("Ошибка при закрытии зависших приемок: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce закрывает приемки, открытые дольше maxAge, и возвращает закрытые
+func (c *StaleReceptionCloser) RunOnce() ([]models.Reception, error) {
+	now := c.clock.Now()
+	reason := fmt.Sprintf("закрыта автоматически: открыта дольше %s", c.maxAge)
+
+	closed, err := c.store.CloseStaleReceptions(now.Add(-c.maxAge), now, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reception := range closed {
+		log.Printf("Приемка %s ПВЗ %s закрыта автоматически, открыта %s", reception.ID, reception.PVZID, reception.DateTime.Format(time.RFC3339))
+		if c.live != nil {
+			c.live.PublishLive("ReceptionClosed", reception.ID)
+		}
+	}
+
+	return closed, nil
+}
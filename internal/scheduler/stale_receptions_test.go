@@ -0,0 +1,167 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock управляемые часы: время идет только при вызове Advance
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance переводит часы вперед и срабатывает наступившие таймеры
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+
+	var pending []fakeWaiter
+	for _, waiter := range c.waiters {
+		if waiter.deadline.After(c.now) {
+			pending = append(pending, waiter)
+			continue
+		}
+		waiter.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// waitForTimer ждет, пока планировщик заведет таймер
+func (c *fakeClock) waitForTimer(t *testing.T) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		n := len(c.waiters)
+		c.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("планировщик не завел таймер")
+}
+
+// recordingLivePublisher запоминает события, опубликованные в поток активности
+type recordingLivePublisher struct {
+	events []string
+}
+
+func (p *recordingLivePublisher) PublishLive(eventType, receptionID string) {
+	p.events = append(p.events, eventType+" "+receptionID)
+}
+
+// TestStaleReceptionCloser_RunOnce проверяет закрытие только приемок старше заданного возраста
+func TestStaleReceptionCloser_RunOnce(t *testing.T) {
+	storage := mock.New()
+	now := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	clock := newFakeClock(now)
+
+	stalePVZ := &models.PVZ{City: "Москва"}
+	storage.CreatePVZ(stalePVZ)
+	stale := &models.Reception{PVZID: stalePVZ.ID}
+	storage.CreateReception(stale)
+	stale.DateTime = now.Add(-13 * time.Hour)
+
+	freshPVZ := &models.PVZ{City: "Казань"}
+	storage.CreatePVZ(freshPVZ)
+	fresh := &models.Reception{PVZID: freshPVZ.ID}
+	storage.CreateReception(fresh)
+	fresh.DateTime = now.Add(-time.Hour)
+
+	live := &recordingLivePublisher{}
+	closer := NewStaleReceptionCloser(storage, clock, 12*time.Hour, 5*time.Minute)
+	closer.SetLivePublisher(live)
+	closed, err := closer.RunOnce()
+
+	assert.NoError(t, err)
+	assert.Len(t, closed, 1)
+	assert.Equal(t, stale.ID, closed[0].ID)
+	assert.Equal(t, []string{"ReceptionClosed " + stale.ID}, live.events)
+	assert.Equal(t, "close", stale.Status)
+	assert.Contains(t, stale.StatusReason, "закрыта автоматически")
+	assert.Equal(t, "in_progress", fresh.Status)
+
+	// После закрытия в ПВЗ можно открыть новую приемку
+	assert.NoError(t, storage.CreateReception(&models.Reception{PVZID: stalePVZ.ID}))
+}
+
+// TestStaleReceptionCloser_Run проверяет периодический запуск по часам планировщика
+func TestStaleReceptionCloser_Run(t *testing.T) {
+	storage := mock.New()
+	now := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	clock := newFakeClock(now)
+
+	pvz := &models.PVZ{City: "Москва"}
+	storage.CreatePVZ(pvz)
+	reception := &models.Reception{PVZID: pvz.ID}
+	storage.CreateReception(reception)
+	reception.DateTime = now.Add(-11 * time.Hour)
+
+	closer := NewStaleReceptionCloser(storage, clock, 12*time.Hour, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		closer.Run(ctx)
+		close(done)
+	}()
+
+	// Через час приемка открыта 12 часов: еще не старше предела
+	clock.waitForTimer(t)
+	clock.Advance(time.Hour)
+	clock.waitForTimer(t)
+
+	// Еще через час она закрывается
+	clock.Advance(time.Hour)
+	clock.waitForTimer(t)
+
+	cancel()
+	<-done
+
+	assert.Equal(t, "close", reception.Status)
+	assert.Equal(t, 1, countReceptions(storage, pvz.ID, "close"))
+}
+
+// countReceptions считает приемки ПВЗ с указанным статусом
+func countReceptions(storage *mock.MockStorage, pvzID, status string) int {
+	list, _ := storage.GetPVZList(models.PVZListFilter{Page: 1, Limit: 10, ReceptionStatus: status})
+	count := 0
+	for _, item := range list {
+		if item.PVZ.ID == pvzID {
+			count += len(item.Receptions)
+		}
+	}
+	return count
+}
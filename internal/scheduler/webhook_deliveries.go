@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+)
+
+const (
+	// webhookBatchSize число доставок, отправляемых за один проход
+	webhookBatchSize = 50
+
+	// webhookLease время, на которое выбранная доставка скрывается от других реплик; больше таймаута запроса
+	webhookLease = time.Minute
+
+	// webhookMaxAttempts число попыток, после которого доставка попадает в список недоставленных (dead)
+	webhookMaxAttempts = 8
+
+	// webhookRetryBase и webhookRetryMax задержка перед второй попыткой и предел ее удвоения
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = time.Hour
+)
+
+// WebhookDeliveryStore хранилище доставок вебхуков
+type WebhookDeliveryStore interface {
+	ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	GetWebhookSubscriptionByID(id string) (*models.WebhookSubscription, error)
+	UpdateWebhookDelivery(delivery *models.WebhookDelivery, leasedUntil time.Time) error
+}
+
+// WebhookSender отправляет доставку на адрес подписки и возвращает код ответа
+type WebhookSender interface {
+	Send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, error)
+}
+
+// WebhookDeliveryWorker периодически отправляет наступившие доставки вебхуков. Неудачная попытка
+// повторяется с экспоненциально растущей задержкой, после webhookMaxAttempts доставка получает статус dead
+type WebhookDeliveryWorker struct {
+	store    WebhookDeliveryStore
+	sender   WebhookSender
+	clock    Clock
+	interval time.Duration
+}
+
+// NewWebhookDeliveryWorker создает планировщик доставки вебхуков
+func NewWebhookDeliveryWorker(store WebhookDeliveryStore, sender WebhookSender, clock Clock, interval time.Duration) *WebhookDeliveryWorker {
+	return &WebhookDeliveryWorker{
+		store:    store,
+		sender:   sender,
+		clock:    clock,
+		interval: interval,
+	}
+}
+
+// Run отправляет доставки каждые interval до отмены контекста
+func (w *WebhookDeliveryWorker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.clock.After(w.interval):
+			if _, err := w.RunOnce(ctx); err != nil {
+				log.Printf("Ошибка при доставке вебхуков: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce отправляет наступившие доставки и возвращает их с результатами попыток
+func (w *WebhookDeliveryWorker) RunOnce(ctx context.Context) ([]models.WebhookDelivery, error) {
+	now := w.clock.Now()
+	claimed, err := w.store.ClaimWebhookDeliveries(now, webhookLease, webhookBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Результат попытки не записывается, если аренда истекла: доставку могли удалить, выбрать снова
+	// или отправить повторно
+	for i := range claimed {
+		delivery := &claimed[i]
+		leasedUntil := delivery.NextAttemptAt
+		w.attempt(ctx, delivery, now)
+		if err := w.store.UpdateWebhookDelivery(delivery, leasedUntil); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return claimed, err
+		}
+	}
+
+	return claimed, nil
+}
+
+// attempt выполняет одну попытку доставки и записывает ее результат в delivery
+func (w *WebhookDeliveryWorker) attempt(ctx context.Context, delivery *models.WebhookDelivery, now time.Time) {
+	delivery.Attempts++
+
+	subscription, err := w.store.GetWebhookSubscriptionByID(delivery.SubscriptionID)
+	if err != nil {
+		w.fail(delivery, now, 0, err, errors.Is(err, storage.ErrNotFound))
+		return
+	}
+
+	status, err := w.sender.Send(ctx, subscription, delivery, now)
+	if err != nil {
+		w.fail(delivery, now, status, err, false)
+		return
+	}
+
+	delivery.Status = "delivered"
+	delivery.ResponseStatus = status
+	delivery.LastError = ""
+	delivery.DeliveredAt = &now
+}
+
+// fail записывает неудачную попытку: назначает повтор или переводит доставку в dead
+func (w *WebhookDeliveryWorker) fail(delivery *models.WebhookDelivery, now time.Time, status int, err error, final bool) {
+	delivery.ResponseStatus = status
+	delivery.LastError = err.Error()
+	if final || delivery.Attempts >= webhookMaxAttempts {
+		delivery.Status = "dead"
+		log.Printf("Доставка %s события %s не выполнена после %d попыток: %v", delivery.ID, delivery.EventID, delivery.Attempts, err)
+		return
+	}
+	delivery.NextAttemptAt = now.Add(webhookRetryDelay(delivery.Attempts))
+}
+
+// webhookRetryDelay задержка перед следующей попыткой после attempts неудачных
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookRetryMax {
+			return webhookRetryMax
+		}
+	}
+	return delay
+}
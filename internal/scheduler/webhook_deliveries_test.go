@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// scriptedSender отвечает заданным кодом и запоминает время попыток
+type scriptedSender struct {
+	status   int
+	attempts []time.Time
+}
+
+func (s *scriptedSender) Send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	s.attempts = append(s.attempts, now)
+	if s.status >= 300 {
+		return s.status, errors.New("вебхук ответил ошибкой")
+	}
+	return s.status, nil
+}
+
+// newPendingDelivery создает подписку и ожидающую доставку
+func newPendingDelivery(storage *mock.MockStorage) *models.WebhookDelivery {
+	subscription := &models.WebhookSubscription{URL: "http://partner.example", Secret: "secret-key-0123456789"}
+	storage.CreateWebhookSubscription(subscription)
+	delivery := &models.WebhookDelivery{SubscriptionID: subscription.ID, EventID: "event-id", EventType: "ReceptionClosed",
+		Payload: json.RawMessage(`{"id":"event-id"}`)}
+	storage.CreateWebhookDeliveries([]*models.WebhookDelivery{delivery})
+	return delivery
+}
+
+// TestWebhookDeliveryWorker_Delivered проверяет успешную доставку с первой попытки
+func TestWebhookDeliveryWorker_Delivered(t *testing.T) {
+	storage := mock.New()
+	clock := newFakeClock(time.Now().Add(time.Second))
+	delivery := newPendingDelivery(storage)
+
+	sender := &scriptedSender{status: http.StatusOK}
+	worker := NewWebhookDeliveryWorker(storage, sender, clock, time.Second)
+
+	processed, err := worker.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, processed, 1)
+	stored, _ := storage.GetWebhookDeliveryByID(delivery.ID)
+	assert.Equal(t, "delivered", stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, http.StatusOK, stored.ResponseStatus)
+	assert.NotNil(t, stored.DeliveredAt)
+}
+
+// TestWebhookDeliveryWorker_Backoff проверяет повторы с растущей задержкой и перевод в dead
+func TestWebhookDeliveryWorker_Backoff(t *testing.T) {
+	storage := mock.New()
+	clock := newFakeClock(time.Now().Add(time.Second))
+	delivery := newPendingDelivery(storage)
+
+	sender := &scriptedSender{status: http.StatusServiceUnavailable}
+	worker := NewWebhookDeliveryWorker(storage, sender, clock, time.Second)
+
+	worker.RunOnce(context.Background())
+	stored, _ := storage.GetWebhookDeliveryByID(delivery.ID)
+	assert.Equal(t, "pending", stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, stored.ResponseStatus)
+	assert.Equal(t, clock.Now().Add(webhookRetryBase), stored.NextAttemptAt)
+
+	// До наступления времени повтора доставка не отправляется
+	clock.Advance(webhookRetryBase - time.Second)
+	processed, _ := worker.RunOnce(context.Background())
+	assert.Empty(t, processed)
+
+	for len(sender.attempts) < webhookMaxAttempts {
+		stored, _ = storage.GetWebhookDeliveryByID(delivery.ID)
+		clock.Advance(stored.NextAttemptAt.Sub(clock.Now()))
+		worker.RunOnce(context.Background())
+	}
+
+	// Задержка удваивается, но не превышает предела
+	assert.Equal(t, webhookRetryBase, sender.attempts[1].Sub(sender.attempts[0]))
+	assert.Equal(t, 2*webhookRetryBase, sender.attempts[2].Sub(sender.attempts[1]))
+	assert.Equal(t, 64*webhookRetryBase, sender.attempts[7].Sub(sender.attempts[6]))
+	assert.Equal(t, webhookRetryMax, webhookRetryDelay(20))
+
+	stored, _ = storage.GetWebhookDeliveryByID(delivery.ID)
+	assert.Equal(t, "dead", stored.Status)
+	assert.Equal(t, webhookMaxAttempts, stored.Attempts)
+	assert.NotEmpty(t, stored.LastError)
+
+	dead, _ := storage.GetWebhookDeliveries(models.WebhookDeliveryFilter{Status: "dead"})
+	assert.Len(t, dead, 1)
+}
+
+// TestWebhookDeliveryWorker_DeletedSubscription проверяет, что доставка удаленной подписки сразу становится dead
+func TestWebhookDeliveryWorker_DeletedSubscription(t *testing.T) {
+	storage := mock.New()
+	clock := newFakeClock(time.Now().Add(time.Second))
+	delivery := newPendingDelivery(storage)
+
+	// Подписку подменяем на отсутствующую, чтобы доставка осталась в хранилище
+	delivery.SubscriptionID = "unknown"
+	delivery.Status = "pending"
+	storage.UpdateWebhookDelivery(delivery, delivery.NextAttemptAt)
+
+	worker := NewWebhookDeliveryWorker(storage, &scriptedSender{status: http.StatusOK}, clock, time.Second)
+	worker.RunOnce(context.Background())
+
+	stored, _ := storage.GetWebhookDeliveryByID(delivery.ID)
+	assert.Equal(t, "dead", stored.Status)
+}
+
+// leaseStealingSender имитирует долгую отправку: пока она идет, аренда истекает и доставку выбирает другая реплика
+type leaseStealingSender struct {
+	storage *mock.MockStorage
+}
+
+func (s *leaseStealingSender) Send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	s.storage.ClaimWebhookDeliveries(now.Add(2*webhookLease), webhookLease, webhookBatchSize)
+	return http.StatusServiceUnavailable, errors.New("вебхук ответил ошибкой")
+}
+
+// TestWebhookDeliveryWorker_LeaseExpired проверяет, что результат попытки после истечения аренды не записывается
+func TestWebhookDeliveryWorker_LeaseExpired(t *testing.T) {
+	storage := mock.New()
+	clock := newFakeClock(time.Now().Add(time.Second))
+	delivery := newPendingDelivery(storage)
+
+	worker := NewWebhookDeliveryWorker(storage, &leaseStealingSender{storage: storage}, clock, time.Second)
+	_, err := worker.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	stored, _ := storage.GetWebhookDeliveryByID(delivery.ID)
+	assert.Equal(t, 0, stored.Attempts)
+	assert.Equal(t, clock.Now().Add(3*webhookLease), stored.NextAttemptAt)
+}
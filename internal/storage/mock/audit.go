@@ -0,0 +1,72 @@
+package mock
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// WithActor возвращает хранилище, которое записывает изменения от имени actor в журнал аудита
+func (s *MockStorage) WithActor(actor models.Actor) storage.Storage {
+	return &MockStorage{state: s.state, actor: &actor}
+}
+
+// audit добавляет запись в журнал аудита; изменения без пользователя не записываются
+func (s *MockStorage) audit(action, entityType, entityID string, before, after interface{}) {
+	if s.actor == nil {
+		return
+	}
+
+	entry := models.AuditEntry{
+		ID:         uuid.New().String(),
+		DateTime:   time.Now(),
+		UserID:     s.actor.UserID,
+		Role:       s.actor.Role,
+		RequestID:  s.actor.RequestID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	if before != nil {
+		entry.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		entry.After, _ = json.Marshal(after)
+	}
+	s.auditLog = append(s.auditLog, entry)
+}
+
+// GetAuditLog получает записи журнала аудита, новые первыми
+func (s *MockStorage) GetAuditLog(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	var result []models.AuditEntry
+
+	// Записи добавляются по порядку, поэтому новые первыми - это обход с конца
+	for i := len(s.auditLog) - 1; i >= 0; i-- {
+		entry := s.auditLog[i]
+		if filter.EntityType != "" && entry.EntityType != filter.EntityType {
+			continue
+		}
+		if filter.EntityID != "" && entry.EntityID != filter.EntityID {
+			continue
+		}
+		if filter.UserID != "" && entry.UserID != filter.UserID {
+			continue
+		}
+		if filter.StartDate != nil && entry.DateTime.Before(*filter.StartDate) {
+			continue
+		}
+		if filter.EndDate != nil && entry.DateTime.After(*filter.EndDate) {
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,143 @@
+package mock
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// CreateCell создает ячейку хранения в ПВЗ
+func (s *MockStorage) CreateCell(cell *models.StorageCell) error {
+	if _, exists := s.pvzs[cell.PVZID]; !exists {
+		return errors.New("ПВЗ не найден")
+	}
+
+	for _, c := range s.cells {
+		if c.PVZID == cell.PVZID && c.Code == cell.Code {
+			return errors.New("ячейка с таким кодом уже существует")
+		}
+	}
+
+	cell.ID = uuid.New().String()
+	cell.Occupied = 0
+	stored := *cell
+	s.cells[cell.ID] = &stored
+	s.audit("cell.create", "cell", cell.ID, nil, &stored)
+	return nil
+}
+
+// GetCellByID получает ячейку хранения по ID вместе с текущей заполненностью
+func (s *MockStorage) GetCellByID(id string) (*models.StorageCell, error) {
+	cell, exists := s.cells[id]
+	if !exists {
+		return nil, errors.New("ячейка не найдена")
+	}
+
+	result := *cell
+	result.Occupied = s.cellOccupancy(id)
+	return &result, nil
+}
+
+// GetCellsByPVZID получает ячейки хранения ПВЗ вместе с текущей заполненностью
+func (s *MockStorage) GetCellsByPVZID(pvzID string) ([]models.StorageCell, error) {
+	cells := []models.StorageCell{}
+	for _, cell := range s.cells {
+		if cell.PVZID == pvzID {
+			c := *cell
+			c.Occupied = s.cellOccupancy(cell.ID)
+			cells = append(cells, c)
+		}
+	}
+
+	sort.Slice(cells, func(i, j int) bool {
+		return cells[i].Code < cells[j].Code
+	})
+
+	return cells, nil
+}
+
+// SuggestCell подбирает ячейку ПВЗ с наибольшим свободным местом
+func (s *MockStorage) SuggestCell(pvzID string) (*models.StorageCell, error) {
+	cells, _ := s.GetCellsByPVZID(pvzID)
+
+	var best *models.StorageCell
+	for i := range cells {
+		free := cells[i].Capacity - cells[i].Occupied
+		if free <= 0 {
+			continue
+		}
+		if best == nil || free > best.Capacity-best.Occupied {
+			best = &cells[i]
+		}
+	}
+
+	if best == nil {
+		return nil, errors.New("нет свободных ячеек")
+	}
+
+	return best, nil
+}
+
+// AssignProductToCell размещает товар в ячейке, при необходимости перемещая его из другой ячейки
+func (s *MockStorage) AssignProductToCell(productID, cellID string) error {
+	product, exists := s.products[productID]
+	if !exists {
+		return storage.ErrNotFound
+	}
+
+	reception, exists := s.receptions[product.ReceptionID]
+	if !exists {
+		return storage.ErrNotFound
+	}
+
+	if err := s.checkCellPlacement(productID, cellID, reception.PVZID); err != nil {
+		return err
+	}
+
+	s.productCells[productID] = cellID
+	s.audit("product.place", "product", productID, nil, map[string]string{"cellId": cellID})
+	return nil
+}
+
+// checkCellPlacement проверяет, что товар из ПВЗ pvzID помещается в ячейку
+func (s *MockStorage) checkCellPlacement(productID, cellID, pvzID string) error {
+	cell, exists := s.cells[cellID]
+	if !exists {
+		return storage.ErrCellNotFound
+	}
+	if cell.PVZID != pvzID {
+		return storage.ErrCellOtherPVZ
+	}
+
+	occupied := s.cellOccupancy(cellID)
+	if s.productCells[productID] == cellID {
+		occupied--
+	}
+	if occupied >= cell.Capacity {
+		return storage.ErrCellFull
+	}
+	return nil
+}
+
+// GetProductCell получает ячейку, в которой размещен товар, или nil, если товар не размещен
+func (s *MockStorage) GetProductCell(productID string) (*models.StorageCell, error) {
+	cellID, exists := s.productCells[productID]
+	if !exists {
+		return nil, nil
+	}
+	return s.GetCellByID(cellID)
+}
+
+// cellOccupancy считает количество товаров в ячейке
+func (s *MockStorage) cellOccupancy(cellID string) int {
+	occupied := 0
+	for _, id := range s.productCells {
+		if id == cellID {
+			occupied++
+		}
+	}
+	return occupied
+}
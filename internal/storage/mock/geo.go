@@ -0,0 +1,53 @@
+package mock
+
+import (
+	"math"
+	"sort"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// earthRadiusKm средний радиус Земли в километрах
+const earthRadiusKm = 6371.0
+
+// GetNearbyPVZ ищет работающие ПВЗ в радиусе radiusKm от точки, ближайшие первыми
+func (s *MockStorage) GetNearbyPVZ(lat, lon, radiusKm float64, limit int) ([]models.PVZWithDistance, error) {
+	result := []models.PVZWithDistance{}
+
+	for _, pvz := range s.pvzs {
+		if pvz.Latitude == nil || pvz.Longitude == nil || pvz.Status == "decommissioned" {
+			continue
+		}
+
+		distance := greatCircleDistance(lat, lon, *pvz.Latitude, *pvz.Longitude)
+		if distance <= radiusKm {
+			result = append(result, models.PVZWithDistance{PVZ: *pvz, DistanceKm: distance})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].DistanceKm != result[j].DistanceKm {
+			return result[i].DistanceKm < result[j].DistanceKm
+		}
+		return result[i].PVZ.ID < result[j].PVZ.ID
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// greatCircleDistance считает расстояние по большому кругу по формуле гаверсинусов
+func greatCircleDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+
+	a := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Pow(math.Sin(dLon/2), 2)
+
+	return earthRadiusKm * 2 * math.Asin(math.Min(1, math.Sqrt(a)))
+}
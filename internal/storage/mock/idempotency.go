@@ -0,0 +1,61 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// idempotencyMapKey ключ записи идемпотентности в карте
+func idempotencyMapKey(userID, key string) string {
+	return userID + "\x00" + key
+}
+
+// ReserveIdempotencyKey резервирует ключ; действующий ключ не перезаписывается, а истекший ключ и ключ, резерв
+// которого истек до сохранения ответа, занимаются заново
+func (s *MockStorage) ReserveIdempotencyKey(record *models.IdempotencyRecord) (*models.IdempotencyRecord, bool, error) {
+	mapKey := idempotencyMapKey(record.UserID, record.Key)
+	if existing, exists := s.idempotencyKeys[mapKey]; exists && existing.ExpiresAt.After(record.CreatedAt) &&
+		(existing.StatusCode != 0 || existing.LockedUntil.After(record.CreatedAt)) {
+		found := *existing
+		return &found, false, nil
+	}
+
+	reserved := *record
+	reserved.StatusCode = 0
+	reserved.ContentType = ""
+	reserved.Body = nil
+	s.idempotencyKeys[mapKey] = &reserved
+	return nil, true, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом, если ключ еще зарезервирован за этим запросом
+func (s *MockStorage) CompleteIdempotencyKey(record *models.IdempotencyRecord) error {
+	if existing, exists := s.idempotencyKeys[idempotencyMapKey(record.UserID, record.Key)]; exists && existing.CreatedAt.Equal(record.CreatedAt) {
+		existing.StatusCode = record.StatusCode
+		existing.ContentType = record.ContentType
+		existing.Body = record.Body
+	}
+	return nil
+}
+
+// DeleteIdempotencyKey освобождает ключ, если он еще зарезервирован за запросом record
+func (s *MockStorage) DeleteIdempotencyKey(record *models.IdempotencyRecord) error {
+	mapKey := idempotencyMapKey(record.UserID, record.Key)
+	if existing, exists := s.idempotencyKeys[mapKey]; exists && existing.CreatedAt.Equal(record.CreatedAt) && existing.StatusCode == 0 {
+		delete(s.idempotencyKeys, mapKey)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys удаляет истекшие ключи и возвращает их число
+func (s *MockStorage) DeleteExpiredIdempotencyKeys(now time.Time) (int, error) {
+	deleted := 0
+	for mapKey, record := range s.idempotencyKeys {
+		if !record.ExpiresAt.After(now) {
+			delete(s.idempotencyKeys, mapKey)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -2,37 +2,74 @@ package mock
 
 import (
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
 )
 
 // MockStorage реализует интерфейс Storage для тестирования
 type MockStorage struct {
+	*state
+	actor *models.Actor // пользователь для журнала аудита, см. WithActor
+}
+
+// state данные хранилища, общие для всех копий из WithActor
+type state struct {
 	users      map[string]*models.User
 	usersByEmail map[string]*models.User
 	pvzs       map[string]*models.PVZ
 	receptions map[string]*models.Reception
 	products   map[string]*models.Product
+	returnBatches map[string]*models.ReturnBatch
+	returns       map[string]*models.Return
+	cells         map[string]*models.StorageCell
+	productCells  map[string]string // ID товара -> ID ячейки
+	transfers     map[string]*models.Transfer
+	transferredProducts map[string]bool // товары, отправленные или принятые по перемещению
+	closedAt      map[string]time.Time // ID приемки -> время закрытия
+	productSequences map[string]int    // ID приемки -> последний выданный номер товара
+	productChanges   []models.ProductChange
+	auditLog         []models.AuditEntry
+	outbox           []*outboxEntry
+	webhookSubscriptions []*models.WebhookSubscription
+	webhookDeliveries    []*models.WebhookDelivery
+	idempotencyKeys      map[string]*models.IdempotencyRecord // ID пользователя и ключ -> запись
 }
 
 // New создает новый экземпляр MockStorage
 func New() *MockStorage {
-	return &MockStorage{
+	return &MockStorage{state: &state{
 		users:      make(map[string]*models.User),
 		usersByEmail: make(map[string]*models.User),
 		pvzs:       make(map[string]*models.PVZ),
 		receptions: make(map[string]*models.Reception),
 		products:   make(map[string]*models.Product),
-	}
+		returnBatches: make(map[string]*models.ReturnBatch),
+		returns:       make(map[string]*models.Return),
+		cells:         make(map[string]*models.StorageCell),
+		productCells:  make(map[string]string),
+		transfers:     make(map[string]*models.Transfer),
+		transferredProducts: make(map[string]bool),
+		closedAt:      make(map[string]time.Time),
+		productSequences: make(map[string]int),
+		idempotencyKeys:  make(map[string]*models.IdempotencyRecord),
+	}}
 }
 
 // CreateUser создает нового пользователя
 func (s *MockStorage) CreateUser(user *models.User) error {
 	user.ID = uuid.New().String()
+	if user.PVZID != "" {
+		if _, exists := s.pvzs[user.PVZID]; !exists {
+			return errors.New("ПВЗ не найден")
+		}
+	}
 	s.users[user.ID] = user
 	s.usersByEmail[user.Email] = user
+	s.audit("user.create", "user", user.ID, nil, user)
 	return nil
 }
 
@@ -49,7 +86,11 @@ func (s *MockStorage) GetUserByEmail(email string) (*models.User, error) {
 func (s *MockStorage) CreatePVZ(pvz *models.PVZ) error {
 	pvz.ID = uuid.New().String()
 	pvz.RegistrationDate = time.Now()
+	pvz.Status = "active"
+	pvz.StatusReason = ""
 	s.pvzs[pvz.ID] = pvz
+	s.addEvent("PVZCreated", pvz.ID, pvz)
+	s.audit("pvz.create", "pvz", pvz.ID, nil, pvz)
 	return nil
 }
 
@@ -57,54 +98,64 @@ func (s *MockStorage) CreatePVZ(pvz *models.PVZ) error {
 func (s *MockStorage) GetPVZByID(id string) (*models.PVZ, error) {
 	pvz, exists := s.pvzs[id]
 	if !exists {
-		return nil, errors.New("ПВЗ не найден")
+		return nil, storage.ErrNotFound
 	}
 	return pvz, nil
 }
 
-// GetPVZList получает список ПВЗ с фильтрацией по дате приемки и пагинацией
-func (s *MockStorage) GetPVZList(startDate, endDate *time.Time, page, limit int) ([]models.PVZListItem, error) {
-	var result []models.PVZListItem
-
-	for _, pvz := range s.pvzs {
-		item := models.PVZListItem{
-			PVZ:        *pvz,
-			Receptions: []models.ReceptionWithProducts{},
-		}
+// UpdatePVZ обновляет изменяемые поля ПВЗ: город, адрес, координаты и часы работы
+func (s *MockStorage) UpdatePVZ(pvz *models.PVZ) error {
+	stored, exists := s.pvzs[pvz.ID]
+	if !exists {
+		return storage.ErrNotFound
+	}
 
-		for _, reception := range s.receptions {
-			if reception.PVZID == pvz.ID {
-				// Проверяем фильтр по дате
-				if startDate != nil && reception.DateTime.Before(*startDate) {
-					continue
-				}
-				if endDate != nil && reception.DateTime.After(*endDate) {
-					continue
-				}
+	before := *stored
+	stored.City = pvz.City
+	stored.Address = pvz.Address
+	stored.Latitude = pvz.Latitude
+	stored.Longitude = pvz.Longitude
+	stored.OpeningHours = pvz.OpeningHours
+	s.audit("pvz.update", "pvz", pvz.ID, before, stored)
+	return nil
+}
 
-				var products []models.Product
-				for _, product := range s.products {
-					if product.ReceptionID == reception.ID {
-						products = append(products, *product)
-					}
-				}
+// UpdatePVZStatus меняет статус ПВЗ
+func (s *MockStorage) UpdatePVZStatus(id, status, reason string) error {
+	pvz, exists := s.pvzs[id]
+	if !exists {
+		return storage.ErrNotFound
+	}
 
-				item.Receptions = append(item.Receptions, models.ReceptionWithProducts{
-					Reception: *reception,
-					Products:  products,
-				})
+	if status != "active" {
+		for _, reception := range s.receptions {
+			if reception.PVZID == id && reception.Status == "in_progress" {
+				return storage.ErrPVZHasOpenReception
 			}
 		}
-
-		result = append(result, item)
 	}
 
+	before := *pvz
+	pvz.Status = status
+	pvz.StatusReason = reason
+	s.audit("pvz.status", "pvz", pvz.ID, before, pvz)
+	return nil
+}
+
+// GetPVZList получает список ПВЗ с фильтрацией по дате приемки, статусу и пагинацией
+func (s *MockStorage) GetPVZList(filter models.PVZListFilter) ([]models.PVZListItem, error) {
+	result := s.filterPVZList(filter)
+	page, limit := filter.Page, filter.Limit
+
 	// Применяем пагинацию
 	if len(result) == 0 {
 		return []models.PVZListItem{}, nil
 	}
 
 	startIndex := (page - 1) * limit
+	if filter.After != nil {
+		startIndex = 0
+	}
 	endIndex := startIndex + limit
 
 	if startIndex >= len(result) {
@@ -118,6 +169,100 @@ func (s *MockStorage) GetPVZList(startDate, endDate *time.Time, page, limit int)
 	return result[startIndex:endIndex], nil
 }
 
+// CountPVZList считает ПВЗ, подходящие под фильтр, без учета пагинации и курсора
+func (s *MockStorage) CountPVZList(filter models.PVZListFilter) (int, error) {
+	filter.After = nil
+	return len(s.filterPVZList(filter)), nil
+}
+
+// filterPVZList отбирает ПВЗ по фильтру в порядке списка
+func (s *MockStorage) filterPVZList(filter models.PVZListFilter) []models.PVZListItem {
+	var result []models.PVZListItem
+	startDate, endDate := filter.StartDate, filter.EndDate
+	receptionFilter := startDate != nil || endDate != nil || filter.ReceptionStatus != "" || filter.ProductType != ""
+
+	for _, pvz := range s.sortedPVZs() {
+		if len(filter.Statuses) > 0 && !containsString(filter.Statuses, pvz.Status) {
+			continue
+		}
+
+		if filter.City != "" && pvz.City != filter.City {
+			continue
+		}
+
+		if filter.After != nil && !pvzBefore(pvz, filter.After) {
+			continue
+		}
+
+		item := models.PVZListItem{
+			PVZ:        *pvz,
+			Receptions: []models.ReceptionWithProducts{},
+		}
+
+		for _, reception := range s.sortedReceptions(pvz.ID) {
+			// Проверяем фильтр по дате и статусу приемки
+			if startDate != nil && reception.DateTime.Before(*startDate) {
+				continue
+			}
+			if endDate != nil && reception.DateTime.After(*endDate) {
+				continue
+			}
+			if filter.ReceptionStatus != "" && reception.Status != filter.ReceptionStatus {
+				continue
+			}
+
+			var products []models.Product
+			for _, product := range s.products {
+				if product.ReceptionID == reception.ID && (filter.ProductType == "" || product.Type == filter.ProductType) {
+					products = append(products, *product)
+				}
+			}
+
+			// Приемка подходит под фильтр по типу, только если в ней есть товары этого типа
+			if filter.ProductType != "" && len(products) == 0 {
+				continue
+			}
+
+			sort.Slice(products, func(i, j int) bool {
+				return products[i].Sequence < products[j].Sequence
+			})
+			if filter.OmitProducts {
+				products = nil
+			}
+
+			item.Receptions = append(item.Receptions, models.ReceptionWithProducts{
+				Reception: *reception,
+				Products:  products,
+			})
+		}
+
+		// Как и в PostgreSQL, при фильтрах по приемкам в список попадают только ПВЗ с подходящими приемками
+		if receptionFilter && len(item.Receptions) == 0 {
+			continue
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// sortedReceptions возвращает приемки ПВЗ, начиная с последней
+func (s *MockStorage) sortedReceptions(pvzID string) []*models.Reception {
+	var result []*models.Reception
+	for _, reception := range s.receptions {
+		if reception.PVZID == pvzID {
+			result = append(result, reception)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DateTime.After(result[j].DateTime)
+	})
+
+	return result
+}
+
 // CreateReception создает новую приемку
 func (s *MockStorage) CreateReception(reception *models.Reception) error {
 	// Проверяем существование ПВЗ
@@ -137,6 +282,8 @@ func (s *MockStorage) CreateReception(reception *models.Reception) error {
 	reception.DateTime = time.Now()
 	reception.Status = "in_progress"
 	s.receptions[reception.ID] = reception
+	s.addEvent("ReceptionOpened", reception.ID, reception)
+	s.audit("reception.create", "reception", reception.ID, nil, reception)
 	return nil
 }
 
@@ -159,6 +306,15 @@ func (s *MockStorage) GetLastReceptionByPVZID(pvzID string) (*models.Reception,
 	return lastReception, nil
 }
 
+// GetReceptionByID получает приемку по ID
+func (s *MockStorage) GetReceptionByID(id string) (*models.Reception, error) {
+	reception, exists := s.receptions[id]
+	if !exists {
+		return nil, storage.ErrNotFound
+	}
+	return reception, nil
+}
+
 // CloseReception закрывает приемку
 func (s *MockStorage) CloseReception(receptionID string) error {
 	reception, exists := s.receptions[receptionID]
@@ -166,11 +322,67 @@ func (s *MockStorage) CloseReception(receptionID string) error {
 		return errors.New("приемка не найдена")
 	}
 
-	if reception.Status == "close" {
+	if reception.Status != "in_progress" {
 		return errors.New("приемка уже закрыта")
 	}
 
+	before := *reception
 	reception.Status = "close"
+	s.closedAt[reception.ID] = time.Now()
+	s.addEvent("ReceptionClosed", reception.ID, reception)
+	s.audit("reception.close", "reception", reception.ID, before, reception)
+	return nil
+}
+
+// ReopenReception снова открывает закрытую приемку, если она последняя в ПВЗ
+func (s *MockStorage) ReopenReception(receptionID string) error {
+	reception, exists := s.receptions[receptionID]
+	if !exists {
+		return errors.New("приемка не найдена")
+	}
+
+	last, err := s.GetLastReceptionByPVZID(reception.PVZID)
+	if err != nil || last.ID != reception.ID || reception.Status != "close" {
+		return errors.New("открыть можно только последнюю закрытую приемку ПВЗ")
+	}
+
+	if pvz, exists := s.pvzs[reception.PVZID]; !exists || pvz.Status != "active" {
+		return errors.New("ПВЗ не работает")
+	}
+	if _, err := s.GetTransferByReceptionID(reception.ID); err == nil {
+		return errors.New("приемку перемещения нельзя открыть повторно")
+	}
+
+	before := *reception
+	reception.Status = "in_progress"
+	reception.StatusReason = ""
+	delete(s.closedAt, reception.ID)
+	s.audit("reception.reopen", "reception", reception.ID, before, reception)
+	return nil
+}
+
+// CancelReception отменяет незакрытую приемку с указанием причины
+func (s *MockStorage) CancelReception(receptionID, reason string) error {
+	reception, exists := s.receptions[receptionID]
+	if !exists {
+		return errors.New("приемка не найдена")
+	}
+
+	if reception.Status != "in_progress" {
+		return errors.New("приемка уже закрыта")
+	}
+
+	before := *reception
+	reception.Status = "cancelled"
+	reception.StatusReason = reason
+
+	// Товары отмененной приемки не остаются в ПВЗ и освобождают ячейки
+	for _, product := range s.products {
+		if product.ReceptionID == receptionID {
+			delete(s.productCells, product.ID)
+		}
+	}
+	s.audit("reception.cancel", "reception", reception.ID, before, reception)
 	return nil
 }
 
@@ -179,21 +391,33 @@ func (s *MockStorage) CreateProduct(product *models.Product) error {
 	// Проверяем существование приемки
 	reception, exists := s.receptions[product.ReceptionID]
 	if !exists {
-		return errors.New("приемка не найдена")
+		return storage.ErrNotFound
 	}
 
 	// Проверяем, что приемка не закрыта
-	if reception.Status == "close" {
-		return errors.New("приемка уже закрыта")
+	if reception.Status != "in_progress" {
+		return storage.ErrReceptionClosed
+	}
+
+	if product.CellID != "" {
+		if err := s.checkCellPlacement("", product.CellID, reception.PVZID); err != nil {
+			return err
+		}
 	}
 
 	product.ID = uuid.New().String()
 	product.DateTime = time.Now()
+	product.Sequence = s.nextProductSequence(product.ReceptionID)
 	s.products[product.ID] = product
+	if product.CellID != "" {
+		s.productCells[product.ID] = product.CellID
+	}
+	s.addEvent("ProductAdded", product.ID, product)
+	s.audit("product.create", "product", product.ID, nil, product)
 	return nil
 }
 
-// GetProductsByReceptionID получает товары по ID приемки
+// GetProductsByReceptionID получает товары по ID приемки в порядке добавления
 func (s *MockStorage) GetProductsByReceptionID(receptionID string) ([]models.Product, error) {
 	var result []models.Product
 
@@ -203,25 +427,79 @@ func (s *MockStorage) GetProductsByReceptionID(receptionID string) ([]models.Pro
 		}
 	}
 
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Sequence < result[j].Sequence
+	})
+
 	return result, nil
 }
 
-// DeleteLastProductInReception удаляет последний добавленный товар в приемке
-func (s *MockStorage) DeleteLastProductInReception(receptionID string) error {
+// DeleteLastProductInReception удаляет последний добавленный товар в приемке и возвращает его
+func (s *MockStorage) DeleteLastProductInReception(receptionID string) (*models.Product, error) {
 	var lastProduct *models.Product
-	var lastTime time.Time
 
 	for _, product := range s.products {
-		if product.ReceptionID == receptionID && (lastProduct == nil || product.DateTime.After(lastTime)) {
+		if product.ReceptionID == receptionID && (lastProduct == nil || product.Sequence > lastProduct.Sequence) {
 			lastProduct = product
-			lastTime = product.DateTime
 		}
 	}
 
 	if lastProduct == nil {
-		return errors.New("нет товаров для удаления")
+		return nil, errors.New("нет товаров для удаления")
+	}
+
+	if s.transferredProducts[lastProduct.ID] {
+		return nil, storage.ErrProductInTransfer
+	}
+
+	if s.productReturned(lastProduct.ID) {
+		return nil, storage.ErrProductReturned
 	}
 
 	delete(s.products, lastProduct.ID)
-	return nil
-} 
\ No newline at end of file
+	delete(s.productCells, lastProduct.ID)
+	s.addEvent("ProductDeleted", lastProduct.ID, lastProduct)
+	s.audit("product.delete", "product", lastProduct.ID, lastProduct, nil)
+	return lastProduct, nil
+}
+
+// GetProductByID получает товар по ID
+func (s *MockStorage) GetProductByID(id string) (*models.Product, error) {
+	product, exists := s.products[id]
+	if !exists {
+		return nil, storage.ErrNotFound
+	}
+	return product, nil
+}
+
+// containsString проверяет, содержится ли значение в списке
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedPVZs возвращает ПВЗ, отсортированные по дате регистрации и ID по убыванию
+func (s *MockStorage) sortedPVZs() []*models.PVZ {
+	result := make([]*models.PVZ, 0, len(s.pvzs))
+	for _, pvz := range s.pvzs {
+		result = append(result, pvz)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return pvzBefore(result[j], &models.PVZCursor{RegistrationDate: result[i].RegistrationDate, ID: result[i].ID})
+	})
+
+	return result
+}
+
+// pvzBefore проверяет, что ПВЗ идет в списке после позиции курсора
+func pvzBefore(pvz *models.PVZ, cursor *models.PVZCursor) bool {
+	if !pvz.RegistrationDate.Equal(cursor.RegistrationDate) {
+		return pvz.RegistrationDate.Before(cursor.RegistrationDate)
+	}
+	return pvz.ID < cursor.ID
+}
@@ -0,0 +1,75 @@
+package mock
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// outboxEntry событие в outbox вместе с состоянием доставки
+type outboxEntry struct {
+	event       models.Event
+	publishedAt *time.Time
+	attempts    int
+	lastError   string
+	lockedUntil time.Time
+	deadAt      *time.Time
+}
+
+// addEvent записывает доменное событие в outbox
+func (s *MockStorage) addEvent(eventType, entityID string, payload interface{}) {
+	payloadJSON, _ := json.Marshal(payload)
+	s.outbox = append(s.outbox, &outboxEntry{event: models.Event{
+		ID:       uuid.New().String(),
+		Type:     eventType,
+		EntityID: entityID,
+		DateTime: time.Now(),
+		Payload:  payloadJSON,
+	}})
+}
+
+// ClaimPendingEvents выбирает неопубликованные события и скрывает их от повторного выбора до now+lease
+func (s *MockStorage) ClaimPendingEvents(now time.Time, lease time.Duration, limit int) ([]models.Event, error) {
+	var events []models.Event
+	for _, entry := range s.outbox {
+		if len(events) == limit {
+			break
+		}
+		if entry.publishedAt != nil || entry.deadAt != nil || entry.lockedUntil.After(now) {
+			continue
+		}
+		entry.lockedUntil = now.Add(lease)
+		events = append(events, entry.event)
+	}
+	return events, nil
+}
+
+// MarkEventPublished отмечает событие опубликованным
+func (s *MockStorage) MarkEventPublished(id string, publishedAt time.Time) error {
+	for _, entry := range s.outbox {
+		if entry.event.ID == id {
+			entry.publishedAt = &publishedAt
+			return nil
+		}
+	}
+	return nil
+}
+
+// MarkEventFailed сохраняет причину неудачной публикации и увеличивает счетчик попыток; после maxAttempts
+// попыток событие переносится в недоставленные
+func (s *MockStorage) MarkEventFailed(id, reason string, now time.Time, maxAttempts int) (bool, error) {
+	for _, entry := range s.outbox {
+		if entry.event.ID == id {
+			entry.attempts++
+			entry.lastError = reason
+			if entry.attempts >= maxAttempts {
+				entry.deadAt = &now
+			}
+			return entry.deadAt != nil, nil
+		}
+	}
+	return false, storage.ErrNotFound
+}
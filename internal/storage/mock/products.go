@@ -0,0 +1,123 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// CreateProducts добавляет товары в открытую приемку: либо все, либо ни одного
+func (s *MockStorage) CreateProducts(receptionID string, products []*models.Product) error {
+	reception, exists := s.receptions[receptionID]
+	if !exists {
+		return storage.ErrNotFound
+	}
+
+	if reception.Status != "in_progress" {
+		return storage.ErrReceptionClosed
+	}
+
+	now := time.Now()
+	for _, product := range products {
+		product.ID = uuid.New().String()
+		product.DateTime = now
+		product.ReceptionID = receptionID
+		product.Sequence = s.nextProductSequence(receptionID)
+		s.products[product.ID] = product
+		s.addEvent("ProductAdded", product.ID, product)
+		s.audit("product.create", "product", product.ID, nil, product)
+	}
+
+	return nil
+}
+
+// nextProductSequence выдает следующий порядковый номер товара в приемке
+func (s *MockStorage) nextProductSequence(receptionID string) int {
+	s.productSequences[receptionID]++
+	return s.productSequences[receptionID]
+}
+
+// productInOpenReception находит товар, который можно изменить: только в открытой приемке
+func (s *MockStorage) productInOpenReception(productID string) (*models.Product, error) {
+	product, exists := s.products[productID]
+	if !exists {
+		return nil, storage.ErrNotFound
+	}
+
+	reception, exists := s.receptions[product.ReceptionID]
+	if !exists || reception.Status != "in_progress" {
+		return nil, storage.ErrReceptionClosed
+	}
+
+	return product, nil
+}
+
+// DeleteProduct удаляет товар из открытой приемки и записывает удаление в журнал
+func (s *MockStorage) DeleteProduct(productID, userID string) (*models.Product, error) {
+	product, err := s.productInOpenReception(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.transferredProducts[product.ID] {
+		return nil, storage.ErrProductInTransfer
+	}
+
+	if s.productReturned(product.ID) {
+		return nil, storage.ErrProductReturned
+	}
+
+	delete(s.products, product.ID)
+	delete(s.productCells, product.ID)
+	s.addEvent("ProductDeleted", product.ID, product)
+	s.audit("product.delete", "product", product.ID, product, nil)
+	s.productChanges = append(s.productChanges, models.ProductChange{
+		ID:          uuid.New().String(),
+		DateTime:    time.Now(),
+		ProductID:   product.ID,
+		ReceptionID: product.ReceptionID,
+		Action:      "delete",
+		OldType:     product.Type,
+		UserID:      userID,
+	})
+
+	return product, nil
+}
+
+// UpdateProductType исправляет тип товара в открытой приемке и записывает исправление в журнал
+func (s *MockStorage) UpdateProductType(productID, productType, userID string) (*models.Product, error) {
+	product, err := s.productInOpenReception(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.productChanges = append(s.productChanges, models.ProductChange{
+		ID:          uuid.New().String(),
+		DateTime:    time.Now(),
+		ProductID:   product.ID,
+		ReceptionID: product.ReceptionID,
+		Action:      "change_type",
+		OldType:     product.Type,
+		NewType:     productType,
+		UserID:      userID,
+	})
+	before := *product
+	product.Type = productType
+	s.audit("product.update", "product", product.ID, before, product)
+
+	result := *product
+	return &result, nil
+}
+
+// GetProductChanges получает журнал исправлений товаров приемки
+func (s *MockStorage) GetProductChanges(receptionID string) ([]models.ProductChange, error) {
+	var result []models.ProductChange
+	for _, change := range s.productChanges {
+		if change.ReceptionID == receptionID {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,140 @@
+package mock
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// CreateReturnBatch открывает новую партию возвратов
+func (s *MockStorage) CreateReturnBatch(batch *models.ReturnBatch) error {
+	// Проверяем существование ПВЗ
+	_, exists := s.pvzs[batch.PVZID]
+	if !exists {
+		return errors.New("ПВЗ не найден")
+	}
+
+	// Проверяем, нет ли незакрытой партии
+	for _, b := range s.returnBatches {
+		if b.PVZID == batch.PVZID && b.Status == "in_progress" {
+			return errors.New("уже есть незакрытая партия возвратов для этого ПВЗ")
+		}
+	}
+
+	batch.ID = uuid.New().String()
+	batch.DateTime = time.Now()
+	batch.Status = "in_progress"
+	s.returnBatches[batch.ID] = batch
+	s.audit("return_batch.create", "return_batch", batch.ID, nil, batch)
+	return nil
+}
+
+// GetLastReturnBatchByPVZID получает последнюю партию возвратов для ПВЗ
+func (s *MockStorage) GetLastReturnBatchByPVZID(pvzID string) (*models.ReturnBatch, error) {
+	var lastBatch *models.ReturnBatch
+
+	for _, batch := range s.returnBatches {
+		if batch.PVZID == pvzID && (lastBatch == nil || batch.DateTime.After(lastBatch.DateTime)) {
+			lastBatch = batch
+		}
+	}
+
+	if lastBatch == nil {
+		return nil, errors.New("партия возвратов не найдена")
+	}
+
+	return lastBatch, nil
+}
+
+// CloseReturnBatch закрывает партию возвратов
+func (s *MockStorage) CloseReturnBatch(batchID string) error {
+	batch, exists := s.returnBatches[batchID]
+	if !exists {
+		return errors.New("партия возвратов не найдена")
+	}
+
+	if batch.Status == "close" {
+		return errors.New("партия возвратов уже закрыта")
+	}
+
+	batch.Status = "close"
+	s.audit("return_batch.close", "return_batch", batch.ID, map[string]string{"status": "in_progress"}, map[string]string{"status": "close"})
+	return nil
+}
+
+// CreateReturn регистрирует возврат в партии
+func (s *MockStorage) CreateReturn(ret *models.Return) error {
+	batch, exists := s.returnBatches[ret.BatchID]
+	if !exists {
+		return errors.New("партия возвратов не найдена")
+	}
+
+	if batch.Status == "close" {
+		return errors.New("партия возвратов уже закрыта")
+	}
+
+	if ret.ProductID != "" {
+		if s.productReturned(ret.ProductID) {
+			return storage.ErrProductReturned
+		}
+
+		if s.transferredProducts[ret.ProductID] {
+			return storage.ErrProductInTransfer
+		}
+
+		// Возвращенный товар освобождает ячейку хранения
+		delete(s.productCells, ret.ProductID)
+	}
+
+	ret.ID = uuid.New().String()
+	ret.DateTime = time.Now()
+	s.returns[ret.ID] = ret
+	s.audit("return.create", "return", ret.ID, nil, ret)
+	return nil
+}
+
+// productReturned сообщает, зарегистрирован ли возврат товара
+func (s *MockStorage) productReturned(productID string) bool {
+	for _, ret := range s.returns {
+		if ret.ProductID == productID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetReturnBatches получает партии возвратов с указанным статусом вместе с возвратами
+func (s *MockStorage) GetReturnBatches(status string) ([]models.ReturnBatchWithReturns, error) {
+	result := []models.ReturnBatchWithReturns{}
+
+	for _, batch := range s.returnBatches {
+		if batch.Status != status {
+			continue
+		}
+
+		var returns []models.Return
+		for _, ret := range s.returns {
+			if ret.BatchID == batch.ID {
+				returns = append(returns, *ret)
+			}
+		}
+		sort.Slice(returns, func(i, j int) bool {
+			return returns[i].DateTime.Before(returns[j].DateTime)
+		})
+
+		result = append(result, models.ReturnBatchWithReturns{
+			Batch:   *batch,
+			Returns: returns,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Batch.DateTime.Before(result[j].Batch.DateTime)
+	})
+
+	return result, nil
+}
@@ -0,0 +1,32 @@
+package mock
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// CloseStaleReceptions закрывает приемки, открытые раньше openedBefore
+func (s *MockStorage) CloseStaleReceptions(openedBefore, closedAt time.Time, reason string) ([]models.Reception, error) {
+	var closed []models.Reception
+	for _, reception := range s.receptions {
+		if reception.Status != "in_progress" || !reception.DateTime.Before(openedBefore) {
+			continue
+		}
+
+		before := *reception
+		reception.Status = "close"
+		reception.StatusReason = reason
+		s.closedAt[reception.ID] = closedAt
+		s.addEvent("ReceptionClosed", reception.ID, reception)
+		s.audit("reception.auto_close", "reception", reception.ID, before, reception)
+		closed = append(closed, *reception)
+	}
+
+	sort.Slice(closed, func(i, j int) bool {
+		return closed[i].DateTime.Before(closed[j].DateTime)
+	})
+
+	return closed, nil
+}
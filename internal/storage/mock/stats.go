@@ -0,0 +1,109 @@
+package mock
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// GetReceptionStats считает показатели приемок за период с группировкой по ПВЗ, городу и периоду
+func (s *MockStorage) GetReceptionStats(filter models.StatsFilter) ([]models.ReceptionStats, error) {
+	type group struct {
+		stats         models.ReceptionStats
+		products      int
+		durationSum   float64
+		durationCount int
+	}
+
+	groups := make(map[string]*group)
+	var keys []string
+
+	for _, reception := range s.receptions {
+		if filter.StartDate != nil && reception.DateTime.Before(*filter.StartDate) {
+			continue
+		}
+		if filter.EndDate != nil && reception.DateTime.After(*filter.EndDate) {
+			continue
+		}
+
+		if reception.Status == "cancelled" {
+			continue
+		}
+
+		pvz, exists := s.pvzs[reception.PVZID]
+		if !exists {
+			continue
+		}
+
+		var key models.ReceptionStats
+		switch filter.GroupBy {
+		case "pvz":
+			key.PVZID, key.City = pvz.ID, pvz.City
+		case "city":
+			key.City = pvz.City
+		}
+		if filter.Period != "" {
+			period := truncatePeriod(reception.DateTime, filter.Period)
+			key.Period = &period
+		}
+
+		// Ключ группы упорядочен так же, как ORDER BY в PostgreSQL
+		groupKey := key.PVZID + "|" + key.City
+		if key.Period != nil {
+			groupKey += "|" + key.Period.Format(time.RFC3339)
+		}
+
+		g, exists := groups[groupKey]
+		if !exists {
+			key.ProductsByType = map[string]int{"электроника": 0, "одежда": 0, "обувь": 0}
+			g = &group{stats: key}
+			groups[groupKey] = g
+			keys = append(keys, groupKey)
+		}
+
+		g.stats.ReceptionsOpened++
+		if reception.Status == "close" {
+			g.stats.ReceptionsClosed++
+		}
+		if closedAt, closed := s.closedAt[reception.ID]; closed {
+			g.durationSum += closedAt.Sub(reception.DateTime).Seconds()
+			g.durationCount++
+		}
+
+		for _, product := range s.products {
+			if product.ReceptionID == reception.ID {
+				g.stats.ProductsByType[product.Type]++
+				g.products++
+			}
+		}
+	}
+
+	sort.Strings(keys)
+
+	result := []models.ReceptionStats{}
+	for _, key := range keys {
+		g := groups[key]
+		g.stats.AvgProductsPerReception = float64(g.products) / float64(g.stats.ReceptionsOpened)
+		if g.durationCount > 0 {
+			g.stats.AvgReceptionDurationSeconds = g.durationSum / float64(g.durationCount)
+		}
+		result = append(result, g.stats)
+	}
+
+	return result, nil
+}
+
+// truncatePeriod возвращает начало дня, недели (с понедельника) или месяца, как date_trunc в PostgreSQL
+func truncatePeriod(t time.Time, period string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch period {
+	case "week":
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return day
+	}
+}
@@ -0,0 +1,135 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// CreateTransfer создает перемещение товаров из одного ПВЗ в другой
+func (s *MockStorage) CreateTransfer(transfer *models.Transfer) error {
+	for _, productID := range transfer.ProductIDs {
+		product, exists := s.products[productID]
+		if !exists {
+			return fmt.Errorf("товар %s не найден", productID)
+		}
+
+		reception := s.receptions[product.ReceptionID]
+		if reception == nil || reception.PVZID != transfer.SourcePVZID {
+			return fmt.Errorf("товар %s не находится в ПВЗ-отправителе", productID)
+		}
+
+		for _, t := range s.transfers {
+			for _, id := range t.ProductIDs {
+				if id == productID {
+					return fmt.Errorf("товар %s уже перемещался", productID)
+				}
+			}
+		}
+	}
+
+	transfer.ID = uuid.New().String()
+	transfer.DateTime = time.Now()
+	transfer.Status = "created"
+
+	stored := *transfer
+	stored.ProductIDs = append([]string(nil), transfer.ProductIDs...)
+	s.transfers[transfer.ID] = &stored
+	for _, productID := range transfer.ProductIDs {
+		s.transferredProducts[productID] = true
+	}
+	s.audit("transfer.create", "transfer", transfer.ID, nil, &stored)
+	return nil
+}
+
+// GetTransferByID получает перемещение по ID
+func (s *MockStorage) GetTransferByID(id string) (*models.Transfer, error) {
+	transfer, exists := s.transfers[id]
+	if !exists {
+		return nil, storage.ErrNotFound
+	}
+
+	result := *transfer
+	result.ProductIDs = append([]string{}, transfer.ProductIDs...)
+	return &result, nil
+}
+
+// GetTransferByReceptionID получает перемещение, принятое приемкой
+func (s *MockStorage) GetTransferByReceptionID(receptionID string) (*models.Transfer, error) {
+	for id, transfer := range s.transfers {
+		if transfer.ReceptionID == receptionID {
+			return s.GetTransferByID(id)
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+// ShipTransfer отправляет перемещение: товары покидают ячейки ПВЗ-отправителя
+func (s *MockStorage) ShipTransfer(transferID string) error {
+	transfer, exists := s.transfers[transferID]
+	if !exists || transfer.Status != "created" {
+		return errors.New("перемещение уже отправлено или не существует")
+	}
+
+	for _, productID := range transfer.ProductIDs {
+		delete(s.productCells, productID)
+	}
+
+	transfer.Status = "in_transit"
+	s.audit("transfer.ship", "transfer", transfer.ID, map[string]string{"status": "created"}, map[string]string{"status": "in_transit"})
+	return nil
+}
+
+// ReceiveTransfer принимает перемещение в ПВЗ назначения как закрытую приемку. Для подписчиков прием выглядит
+// как добавление товаров (ProductAdded) и закрытие приемки (ReceptionClosed)
+func (s *MockStorage) ReceiveTransfer(transferID string) (*models.Reception, error) {
+	transfer, exists := s.transfers[transferID]
+	if !exists {
+		return nil, errors.New("перемещение не найдено")
+	}
+
+	if transfer.Status != "in_transit" {
+		return nil, errors.New("перемещение еще не отправлено или уже принято")
+	}
+
+	for _, r := range s.receptions {
+		if r.PVZID == transfer.DestinationPVZID && r.Status == "in_progress" {
+			return nil, errors.New("в ПВЗ назначения есть незакрытая приемка")
+		}
+	}
+
+	reception := &models.Reception{
+		ID:       uuid.New().String(),
+		DateTime: time.Now(),
+		PVZID:    transfer.DestinationPVZID,
+		Status:   "close",
+	}
+	s.receptions[reception.ID] = reception
+	s.audit("reception.create", "reception", reception.ID, nil, reception)
+
+	for _, productID := range transfer.ProductIDs {
+		received := &models.Product{
+			ID:          uuid.New().String(),
+			DateTime:    time.Now(),
+			Type:        s.products[productID].Type,
+			ReceptionID: reception.ID,
+			Sequence:    s.nextProductSequence(reception.ID),
+		}
+		s.products[received.ID] = received
+		s.transferredProducts[received.ID] = true
+		s.addEvent("ProductAdded", received.ID, received)
+		s.audit("product.create", "product", received.ID, nil, received)
+	}
+
+	transfer.Status = "received"
+	transfer.ReceptionID = reception.ID
+	s.addEvent("ReceptionClosed", reception.ID, reception)
+	s.audit("transfer.receive", "transfer", transfer.ID, nil, map[string]string{"status": "received", "receptionId": reception.ID})
+
+	result := *reception
+	return &result, nil
+}
@@ -0,0 +1,172 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// CreateWebhookSubscription создает подписку на события
+func (s *MockStorage) CreateWebhookSubscription(subscription *models.WebhookSubscription) error {
+	subscription.ID = uuid.New().String()
+	subscription.DateTime = time.Now()
+	stored := *subscription
+	s.webhookSubscriptions = append(s.webhookSubscriptions, &stored)
+
+	audited := stored
+	audited.Secret = ""
+	s.audit("webhook.create", "webhook", stored.ID, nil, &audited)
+	return nil
+}
+
+// GetWebhookSubscriptions получает все подписки в порядке создания
+func (s *MockStorage) GetWebhookSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	for _, subscription := range s.webhookSubscriptions {
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return subscriptions, nil
+}
+
+// GetWebhookSubscriptionByID получает подписку по ID
+func (s *MockStorage) GetWebhookSubscriptionByID(id string) (*models.WebhookSubscription, error) {
+	for _, subscription := range s.webhookSubscriptions {
+		if subscription.ID == id {
+			found := *subscription
+			return &found, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+// DeleteWebhookSubscription удаляет подписку вместе с историей доставок
+func (s *MockStorage) DeleteWebhookSubscription(id string) error {
+	for i, subscription := range s.webhookSubscriptions {
+		if subscription.ID != id {
+			continue
+		}
+		s.webhookSubscriptions = append(s.webhookSubscriptions[:i], s.webhookSubscriptions[i+1:]...)
+
+		var deliveries []*models.WebhookDelivery
+		for _, delivery := range s.webhookDeliveries {
+			if delivery.SubscriptionID != id {
+				deliveries = append(deliveries, delivery)
+			}
+		}
+		s.webhookDeliveries = deliveries
+
+		audited := *subscription
+		audited.Secret = ""
+		s.audit("webhook.delete", "webhook", id, &audited, nil)
+		return nil
+	}
+	return storage.ErrNotFound
+}
+
+// CreateWebhookDeliveries создает доставки; повторная доставка того же события по подписке пропускается
+func (s *MockStorage) CreateWebhookDeliveries(deliveries []*models.WebhookDelivery) error {
+	now := time.Now()
+	for _, delivery := range deliveries {
+		delivery.ID = uuid.New().String()
+		delivery.DateTime = now
+		delivery.Status = "pending"
+		delivery.NextAttemptAt = now
+		if s.hasWebhookDelivery(delivery.SubscriptionID, delivery.EventID) {
+			continue
+		}
+		stored := *delivery
+		s.webhookDeliveries = append(s.webhookDeliveries, &stored)
+	}
+	return nil
+}
+
+// hasWebhookDelivery проверяет, создана ли уже доставка события по подписке
+func (s *MockStorage) hasWebhookDelivery(subscriptionID, eventID string) bool {
+	for _, delivery := range s.webhookDeliveries {
+		if delivery.SubscriptionID == subscriptionID && delivery.EventID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+// ClaimWebhookDeliveries выбирает наступившие доставки и переносит их следующую попытку на now+lease
+func (s *MockStorage) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var claimed []models.WebhookDelivery
+	for _, delivery := range s.webhookDeliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status != "pending" || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		delivery.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, *delivery)
+	}
+	return claimed, nil
+}
+
+// UpdateWebhookDelivery сохраняет результат попытки доставки, пока доставка удерживается арендой leasedUntil
+func (s *MockStorage) UpdateWebhookDelivery(delivery *models.WebhookDelivery, leasedUntil time.Time) error {
+	for _, stored := range s.webhookDeliveries {
+		if stored.ID == delivery.ID && stored.NextAttemptAt.Equal(leasedUntil) {
+			*stored = *delivery
+			s.audit("webhook_delivery.update", "webhook_delivery", delivery.ID, nil, delivery)
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
+
+// RedeliverWebhookDelivery возвращает доставленную или недоставленную доставку в pending с новым циклом попыток
+func (s *MockStorage) RedeliverWebhookDelivery(id string) (*models.WebhookDelivery, error) {
+	for _, stored := range s.webhookDeliveries {
+		if stored.ID != id {
+			continue
+		}
+		if stored.Status != "dead" && stored.Status != "delivered" {
+			return nil, storage.ErrWebhookDeliveryPending
+		}
+		stored.Status = "pending"
+		stored.Attempts = 0
+		stored.NextAttemptAt = time.Now()
+		redelivered := *stored
+		s.audit("webhook_delivery.redeliver", "webhook_delivery", id, nil, &redelivered)
+		return &redelivered, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+// GetWebhookDeliveryByID получает доставку по ID
+func (s *MockStorage) GetWebhookDeliveryByID(id string) (*models.WebhookDelivery, error) {
+	for _, delivery := range s.webhookDeliveries {
+		if delivery.ID == id {
+			found := *delivery
+			return &found, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+// GetWebhookDeliveries получает доставки по подписке и статусу, новые первыми
+func (s *MockStorage) GetWebhookDeliveries(filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	var result []models.WebhookDelivery
+	for i := len(s.webhookDeliveries) - 1; i >= 0; i-- {
+		delivery := s.webhookDeliveries[i]
+		if filter.SubscriptionID != "" && delivery.SubscriptionID != filter.SubscriptionID {
+			continue
+		}
+		if filter.Status != "" && delivery.Status != filter.Status {
+			continue
+		}
+		result = append(result, *delivery)
+	}
+
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+
+	return result, nil
+}
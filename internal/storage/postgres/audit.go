@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// querier общий интерфейс *sql.DB и *sql.Tx
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// WithActor возвращает хранилище, которое записывает изменения от имени actor в журнал аудита
+func (s *PostgresStorage) WithActor(actor models.Actor) storage.Storage {
+	scoped := *s
+	scoped.actor = &actor
+	return &scoped
+}
+
+// auditing сообщает, ведется ли журнал: изменения без пользователя (внутренние вызовы) не записываются
+func (s *PostgresStorage) auditing() bool {
+	return s.actor != nil
+}
+
+// mutate выполняет изменение; при ведении журнала изменение и записи журнала выполняются в одной транзакции
+func (s *PostgresStorage) mutate(fn func(q querier) error) error {
+	if !s.auditing() {
+		return fn(s.db)
+	}
+	return s.transact(fn)
+}
+
+// transact выполняет fn в транзакции и фиксирует ее, если fn не вернула ошибку
+func (s *PostgresStorage) transact(fn func(q querier) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// audit добавляет запись в журнал аудита; before и after сохраняются как JSON, nil - как NULL
+func (s *PostgresStorage) audit(q querier, action, entityType, entityID string, before, after interface{}) error {
+	if !s.auditing() {
+		return nil
+	}
+
+	beforeJSON, err := snapshotJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := snapshotJSON(after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_log (id, date_time, user_id, role, request_id, action, entity_type, entity_id, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err = q.Exec(query, uuid.New().String(), time.Now(), s.actor.UserID, s.actor.Role, s.actor.RequestID,
+		action, entityType, entityID, beforeJSON, afterJSON)
+	return err
+}
+
+// snapshotJSON сериализует состояние объекта для журнала
+func snapshotJSON(value interface{}) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
+
+// GetAuditLog получает записи журнала аудита, новые первыми
+func (s *PostgresStorage) GetAuditLog(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	var args queryArgs
+	conditions := []string{`TRUE`}
+	if filter.EntityType != "" {
+		conditions = append(conditions, `entity_type = `+args.add(filter.EntityType))
+	}
+	if filter.EntityID != "" {
+		conditions = append(conditions, `entity_id = `+args.add(filter.EntityID))
+	}
+	if filter.UserID != "" {
+		conditions = append(conditions, `user_id = `+args.add(filter.UserID))
+	}
+	if filter.StartDate != nil {
+		conditions = append(conditions, `date_time >= `+args.add(filter.StartDate))
+	}
+	if filter.EndDate != nil {
+		conditions = append(conditions, `date_time <= `+args.add(filter.EndDate))
+	}
+
+	query := `
+		SELECT id, date_time, user_id, role, request_id, action, entity_type, entity_id, before, after
+		FROM audit_log
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY date_time DESC, id DESC
+		LIMIT ` + args.add(filter.Limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after []byte
+		err := rows.Scan(&entry.ID, &entry.DateTime, &entry.UserID, &entry.Role, &entry.RequestID,
+			&entry.Action, &entry.EntityType, &entry.EntityID, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// lockReception читает приемку с блокировкой строки до конца транзакции
+func lockReception(q querier, id string) (*models.Reception, error) {
+	query := `SELECT id, date_time, pvz_id, status, status_reason FROM receptions WHERE id = $1 FOR UPDATE`
+	var reception models.Reception
+	err := q.QueryRow(query, id).Scan(&reception.ID, &reception.DateTime, &reception.PVZID, &reception.Status, &reception.StatusReason)
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reception, nil
+}
+
+// lockPVZ читает ПВЗ с блокировкой строки до конца транзакции
+func lockPVZ(q querier, id string) (*models.PVZ, error) {
+	query := `SELECT ` + pvzColumns + ` FROM pvz WHERE id = $1 FOR UPDATE`
+	pvz, err := scanPVZ(q.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	return pvz, err
+}
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCloseReception_Audit проверяет запись закрытия приемки в журнал в той же транзакции
+func TestCloseReception_Audit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := (&PostgresStorage{db: db}).WithActor(models.Actor{UserID: "user-id", Role: "employee", RequestID: "request-id"})
+
+	now := time.Now()
+	receptionRows := func(status string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}).
+			AddRow("reception-id", now, "pvz-id", status, "")
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions WHERE id = \\$1 FOR UPDATE").
+		WithArgs("reception-id").
+		WillReturnRows(receptionRows("in_progress"))
+	mock.ExpectExec("UPDATE receptions SET status = 'close'").
+		WithArgs("reception-id", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions WHERE id = \\$1 FOR UPDATE").
+		WithArgs("reception-id").
+		WillReturnRows(receptionRows("close"))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ReceptionClosed", "reception-id", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "user-id", "employee", "request-id", "reception.close",
+			"reception", "reception-id", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = storage.CloseReception("reception-id")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreatePVZ_AuditRollback проверяет, что при ошибке записи в журнал изменение откатывается
+func TestCreatePVZ_AuditRollback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := (&PostgresStorage{db: db}).WithActor(models.Actor{UserID: "user-id", Role: "moderator"})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO pvz").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO audit_log").WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	err = storage.CreatePVZ(&models.PVZ{City: "Москва"})
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetAuditLog проверяет выборку журнала по сущности, пользователю и периоду
+func TestGetAuditLog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+	startDate := now.Add(-time.Hour)
+
+	rows := sqlmock.NewRows([]string{"id", "date_time", "user_id", "role", "request_id", "action",
+		"entity_type", "entity_id", "before", "after"}).
+		AddRow("entry-id", now, "user-id", "employee", "request-id", "product.delete",
+			"product", "product-id", []byte(`{"id":"product-id"}`), nil)
+
+	mock.ExpectQuery("SELECT id, date_time, user_id, role, request_id, action, entity_type, entity_id, before, after "+
+		"FROM audit_log WHERE TRUE AND entity_type = \\$1 AND entity_id = \\$2 AND user_id = \\$3 AND date_time >= \\$4 "+
+		"ORDER BY date_time DESC, id DESC LIMIT \\$5").
+		WithArgs("product", "product-id", "user-id", startDate, 10).
+		WillReturnRows(rows)
+
+	entries, err := storage.GetAuditLog(models.AuditFilter{
+		EntityType: "product",
+		EntityID:   "product-id",
+		UserID:     "user-id",
+		StartDate:  &startDate,
+		Limit:      10,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "product.delete", entries[0].Action)
+	assert.JSONEq(t, `{"id":"product-id"}`, string(entries[0].Before))
+	assert.Empty(t, entries[0].After)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCloseReturnBatch_Audit проверяет запись закрытия партии возвратов в журнал в той же транзакции
+func TestCloseReturnBatch_Audit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := (&PostgresStorage{db: db}).WithActor(models.Actor{UserID: "user-id", Role: "employee", RequestID: "request-id"})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE return_batches SET status = 'close' WHERE id = \\$1 AND status = 'in_progress'").
+		WithArgs("batch-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "user-id", "employee", "request-id", "return_batch.close",
+			"return_batch", "batch-id", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = storage.CloseReturnBatch("batch-id")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestShipTransfer_Audit проверяет запись отправки перемещения в журнал в той же транзакции
+func TestShipTransfer_Audit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := (&PostgresStorage{db: db}).WithActor(models.Actor{UserID: "user-id", Role: "employee", RequestID: "request-id"})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE transfers SET status = 'in_transit' WHERE id = \\$1 AND status = 'created'").
+		WithArgs("transfer-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM product_cells WHERE product_id IN").
+		WithArgs("transfer-id").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "user-id", "employee", "request-id", "transfer.ship",
+			"transfer", "transfer-id", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = storage.ShipTransfer("transfer-id")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// CreateCell создает ячейку хранения в ПВЗ
+func (s *PostgresStorage) CreateCell(cell *models.StorageCell) error {
+	cell.ID = uuid.New().String()
+	cell.Occupied = 0
+
+	query := `INSERT INTO storage_cells (id, pvz_id, code, capacity) VALUES ($1, $2, $3, $4)`
+	return s.mutate(func(q querier) error {
+		if _, err := q.Exec(query, cell.ID, cell.PVZID, cell.Code, cell.Capacity); err != nil {
+			return err
+		}
+		return s.audit(q, "cell.create", "cell", cell.ID, nil, cell)
+	})
+}
+
+// GetCellByID получает ячейку хранения по ID вместе с текущей заполненностью
+func (s *PostgresStorage) GetCellByID(id string) (*models.StorageCell, error) {
+	query := `
+		SELECT c.id, c.pvz_id, c.code, c.capacity, COUNT(pc.product_id)
+		FROM storage_cells c
+		LEFT JOIN product_cells pc ON pc.cell_id = c.id
+		WHERE c.id = $1
+		GROUP BY c.id, c.pvz_id, c.code, c.capacity
+	`
+	var cell models.StorageCell
+	err := s.db.QueryRow(query, id).Scan(&cell.ID, &cell.PVZID, &cell.Code, &cell.Capacity, &cell.Occupied)
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cell, nil
+}
+
+// GetCellsByPVZID получает ячейки хранения ПВЗ вместе с текущей заполненностью
+func (s *PostgresStorage) GetCellsByPVZID(pvzID string) ([]models.StorageCell, error) {
+	query := `
+		SELECT c.id, c.pvz_id, c.code, c.capacity, COUNT(pc.product_id)
+		FROM storage_cells c
+		LEFT JOIN product_cells pc ON pc.cell_id = c.id
+		WHERE c.pvz_id = $1
+		GROUP BY c.id, c.pvz_id, c.code, c.capacity
+		ORDER BY c.code ASC
+	`
+	rows, err := s.db.Query(query, pvzID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cells := []models.StorageCell{}
+	for rows.Next() {
+		var cell models.StorageCell
+		if err := rows.Scan(&cell.ID, &cell.PVZID, &cell.Code, &cell.Capacity, &cell.Occupied); err != nil {
+			return nil, err
+		}
+		cells = append(cells, cell)
+	}
+
+	return cells, rows.Err()
+}
+
+// SuggestCell подбирает ячейку ПВЗ с наибольшим свободным местом
+func (s *PostgresStorage) SuggestCell(pvzID string) (*models.StorageCell, error) {
+	query := `
+		SELECT c.id, c.pvz_id, c.code, c.capacity, COUNT(pc.product_id) AS occupied
+		FROM storage_cells c
+		LEFT JOIN product_cells pc ON pc.cell_id = c.id
+		WHERE c.pvz_id = $1
+		GROUP BY c.id, c.pvz_id, c.code, c.capacity
+		HAVING COUNT(pc.product_id) < c.capacity
+		ORDER BY c.capacity - COUNT(pc.product_id) DESC, c.code ASC
+		LIMIT 1
+	`
+	var cell models.StorageCell
+	err := s.db.QueryRow(query, pvzID).Scan(&cell.ID, &cell.PVZID, &cell.Code, &cell.Capacity, &cell.Occupied)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("нет свободных ячеек")
+		}
+		return nil, err
+	}
+	return &cell, nil
+}
+
+// AssignProductToCell размещает товар в ячейке, при необходимости перемещая его из другой ячейки
+func (s *PostgresStorage) AssignProductToCell(productID, cellID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := placeProductInCell(tx, productID, cellID); err != nil {
+		return err
+	}
+
+	if err := s.audit(tx, "product.place", "product", productID, nil, map[string]string{"cellId": cellID}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// placeProductInCell размещает товар в ячейке в рамках транзакции. Ячейка блокируется, чтобы параллельные
+// размещения не превысили вместимость
+func placeProductInCell(tx *sql.Tx, productID, cellID string) error {
+	var cellPVZID string
+	var capacity int
+	err := tx.QueryRow(`SELECT pvz_id, capacity FROM storage_cells WHERE id = $1 FOR UPDATE`, cellID).Scan(&cellPVZID, &capacity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.ErrCellNotFound
+		}
+		return err
+	}
+
+	// Товар можно разместить только в ячейке того ПВЗ, куда он был принят
+	var productPVZID string
+	query := `
+		SELECT r.pvz_id
+		FROM products p
+		INNER JOIN receptions r ON r.id = p.reception_id
+		WHERE p.id = $1
+	`
+	err = tx.QueryRow(query, productID).Scan(&productPVZID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		return err
+	}
+	if productPVZID != cellPVZID {
+		return storage.ErrCellOtherPVZ
+	}
+
+	var occupied int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM product_cells WHERE cell_id = $1 AND product_id <> $2`, cellID, productID).Scan(&occupied)
+	if err != nil {
+		return err
+	}
+	if occupied >= capacity {
+		return storage.ErrCellFull
+	}
+
+	query = `
+		INSERT INTO product_cells (product_id, cell_id, assigned_at) VALUES ($1, $2, $3)
+		ON CONFLICT (product_id) DO UPDATE SET cell_id = EXCLUDED.cell_id, assigned_at = EXCLUDED.assigned_at
+	`
+	_, err = tx.Exec(query, productID, cellID, time.Now())
+	return err
+}
+
+// GetProductCell получает ячейку, в которой размещен товар, или nil, если товар не размещен
+func (s *PostgresStorage) GetProductCell(productID string) (*models.StorageCell, error) {
+	var cellID string
+	err := s.db.QueryRow(`SELECT cell_id FROM product_cells WHERE product_id = $1`, productID).Scan(&cellID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCellByID(cellID)
+}
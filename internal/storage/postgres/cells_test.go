@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	storagepkg "github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSuggestCell проверяет подбор ячейки с наибольшим свободным местом
+func TestSuggestCell(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectQuery("SELECT c.id, c.pvz_id, c.code, c.capacity, COUNT\\(pc.product_id\\) AS occupied FROM storage_cells c").
+		WithArgs("pvz-id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pvz_id", "code", "capacity", "occupied"}).
+			AddRow("cell-id", "pvz-id", "B-01", 3, 1))
+
+	cell, err := storage.SuggestCell("pvz-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "B-01", cell.Code)
+	assert.Equal(t, 1, cell.Occupied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestAssignProductToCell_Full проверяет запрет размещения товара в заполненной ячейке
+func TestAssignProductToCell_Full(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT pvz_id, capacity FROM storage_cells WHERE id = \\$1 FOR UPDATE").
+		WithArgs("cell-id").
+		WillReturnRows(sqlmock.NewRows([]string{"pvz_id", "capacity"}).AddRow("pvz-id", 2))
+	mock.ExpectQuery("SELECT r.pvz_id FROM products p").
+		WithArgs("product-id").
+		WillReturnRows(sqlmock.NewRows([]string{"pvz_id"}).AddRow("pvz-id"))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM product_cells").
+		WithArgs("cell-id", "product-id").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	err = storage.AssignProductToCell("product-id", "cell-id")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ячейка заполнена")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestAssignProductToCell_OtherPVZ проверяет запрет размещения товара в ячейке чужого ПВЗ
+func TestAssignProductToCell_OtherPVZ(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT pvz_id, capacity FROM storage_cells WHERE id = \\$1 FOR UPDATE").
+		WithArgs("cell-id").
+		WillReturnRows(sqlmock.NewRows([]string{"pvz_id", "capacity"}).AddRow("pvz-id", 2))
+	mock.ExpectQuery("SELECT r.pvz_id FROM products p").
+		WithArgs("product-id").
+		WillReturnRows(sqlmock.NewRows([]string{"pvz_id"}).AddRow("other-pvz-id"))
+	mock.ExpectRollback()
+
+	err = storage.AssignProductToCell("product-id", "cell-id")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "другому ПВЗ")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateProduct_CellFull проверяет, что товар не сохраняется, если ячейка при добавлении заполнена
+func TestCreateProduct_CellFull(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	product := &models.Product{Type: "обувь", ReceptionID: "reception-id", CellID: "cell-id"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE receptions SET last_product_sequence").
+		WithArgs(product.ReceptionID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"last_product_sequence"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO products").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), product.Type, product.ReceptionID, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT pvz_id, capacity FROM storage_cells WHERE id = \\$1 FOR UPDATE").
+		WithArgs("cell-id").
+		WillReturnRows(sqlmock.NewRows([]string{"pvz_id", "capacity"}).AddRow("pvz-id", 1))
+	mock.ExpectQuery("SELECT r.pvz_id FROM products p").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"pvz_id"}).AddRow("pvz-id"))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM product_cells").
+		WithArgs("cell-id", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	err = storage.CreateProduct(product)
+	assert.EqualError(t, err, "ячейка заполнена")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetCellByID_NotFound проверяет, что отсутствующая ячейка возвращает storage.ErrNotFound
+func TestGetCellByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectQuery("SELECT c.id, c.pvz_id, c.code, c.capacity, COUNT\\(pc.product_id\\) FROM storage_cells c").
+		WithArgs("cell-id").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = storage.GetCellByID("cell-id")
+
+	assert.ErrorIs(t, err, storagepkg.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// idempotencyReserveAttempts число попыток резервирования, если занятый ключ исчез до чтения его записи
+const idempotencyReserveAttempts = 3
+
+// ReserveIdempotencyKey резервирует ключ; действующий ключ не перезаписывается, а истекший ключ и ключ, резерв
+// которого истек до сохранения ответа, занимаются заново
+func (s *PostgresStorage) ReserveIdempotencyKey(record *models.IdempotencyRecord) (*models.IdempotencyRecord, bool, error) {
+	reserve := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, status_code, content_type, body, created_at, expires_at, locked_until)
+		VALUES ($1, $2, $3, 0, '', NULL, $4, $5, $6)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, status_code = 0, content_type = '', body = NULL,
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at, locked_until = EXCLUDED.locked_until
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+			OR (idempotency_keys.status_code = 0 AND idempotency_keys.locked_until <= EXCLUDED.created_at)
+		RETURNING user_id
+	`
+	find := `
+		SELECT user_id, idempotency_key, request_hash, status_code, content_type, body, created_at, expires_at, locked_until
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+	`
+	for attempt := 0; attempt < idempotencyReserveAttempts; attempt++ {
+		var userID string
+		err := s.db.QueryRow(reserve, record.UserID, record.Key, record.RequestHash, record.CreatedAt, record.ExpiresAt,
+			record.LockedUntil).Scan(&userID)
+		if err == nil {
+			return nil, true, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, false, err
+		}
+
+		// Ключ уже занят действующим запросом
+		var existing models.IdempotencyRecord
+		err = s.db.QueryRow(find, record.UserID, record.Key).Scan(&existing.UserID, &existing.Key, &existing.RequestHash,
+			&existing.StatusCode, &existing.ContentType, &existing.Body, &existing.CreatedAt, &existing.ExpiresAt, &existing.LockedUntil)
+		if err == sql.ErrNoRows {
+			// Ключ освободили или удалили как истекший между запросами: пробуем зарезервировать снова
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		return &existing, false, nil
+	}
+
+	return nil, false, fmt.Errorf("не удалось зарезервировать ключ идемпотентности")
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом, если ключ еще зарезервирован за этим запросом
+func (s *PostgresStorage) CompleteIdempotencyKey(record *models.IdempotencyRecord) error {
+	query := `
+		UPDATE idempotency_keys SET status_code = $3, content_type = $4, body = $5
+		WHERE user_id = $1 AND idempotency_key = $2 AND created_at = $6
+	`
+	_, err := s.db.Exec(query, record.UserID, record.Key, record.StatusCode, record.ContentType, record.Body, record.CreatedAt)
+	return err
+}
+
+// DeleteIdempotencyKey освобождает ключ, например после ошибки сервера, чтобы запрос можно было повторить.
+// Резерв, который после истечения занял другой запрос, не освобождается
+func (s *PostgresStorage) DeleteIdempotencyKey(record *models.IdempotencyRecord) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2 AND created_at = $3 AND status_code = 0`
+	_, err := s.db.Exec(query, record.UserID, record.Key, record.CreatedAt)
+	return err
+}
+
+// DeleteExpiredIdempotencyKeys удаляет истекшие ключи и возвращает их число
+func (s *PostgresStorage) DeleteExpiredIdempotencyKeys(now time.Time) (int, error) {
+	result, err := s.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
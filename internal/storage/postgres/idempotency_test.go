@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReserveIdempotencyKey проверяет резервирование свободного ключа и возврат уже занятого
+func TestReserveIdempotencyKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+	record := &models.IdempotencyRecord{UserID: "user-id", Key: "key-1", RequestHash: "hash", CreatedAt: now,
+		ExpiresAt: now.Add(24 * time.Hour), LockedUntil: now.Add(time.Minute)}
+
+	// Свободный ключ резервируется
+	mock.ExpectQuery("INSERT INTO idempotency_keys .* ON CONFLICT \\(user_id, idempotency_key\\) DO UPDATE .* WHERE idempotency_keys.expires_at <= EXCLUDED.created_at "+
+		"OR \\(idempotency_keys.status_code = 0 AND idempotency_keys.locked_until <= EXCLUDED.created_at\\)").
+		WithArgs("user-id", "key-1", "hash", now, record.ExpiresAt, record.LockedUntil).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-id"))
+
+	existing, reserved, err := storage.ReserveIdempotencyKey(record)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Nil(t, existing)
+
+	// Занятый ключ возвращается с сохраненным ответом
+	// Если занятый ключ удалили до чтения его записи, резервирование повторяется
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WithArgs("user-id", "key-1", "hash", now, record.ExpiresAt, record.LockedUntil).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery("SELECT user_id, idempotency_key, request_hash, status_code, content_type, body, created_at, expires_at, locked_until FROM idempotency_keys").
+		WithArgs("user-id", "key-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "idempotency_key", "request_hash", "status_code", "content_type", "body", "created_at", "expires_at", "locked_until"}))
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WithArgs("user-id", "key-1", "hash", now, record.ExpiresAt, record.LockedUntil).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery("SELECT user_id, idempotency_key, request_hash, status_code, content_type, body, created_at, expires_at, locked_until FROM idempotency_keys").
+		WithArgs("user-id", "key-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "idempotency_key", "request_hash", "status_code", "content_type", "body", "created_at", "expires_at", "locked_until"}).
+			AddRow("user-id", "key-1", "hash", 201, "application/json", []byte(`{"id":"pvz-id"}`), now, record.ExpiresAt, record.LockedUntil))
+
+	existing, reserved, err = storage.ReserveIdempotencyKey(record)
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, 201, existing.StatusCode)
+	assert.Equal(t, `{"id":"pvz-id"}`, string(existing.Body))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCompleteIdempotencyKey проверяет сохранение ответа и удаление ключей
+func TestCompleteIdempotencyKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+	record := &models.IdempotencyRecord{UserID: "user-id", Key: "key-1", StatusCode: 201, ContentType: "application/json", Body: []byte(`{}`), CreatedAt: now}
+	mock.ExpectExec("UPDATE idempotency_keys SET status_code = \\$3, content_type = \\$4, body = \\$5 WHERE user_id = \\$1 AND idempotency_key = \\$2 AND created_at = \\$6").
+		WithArgs("user-id", "key-1", 201, "application/json", []byte(`{}`), now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, storage.CompleteIdempotencyKey(record))
+
+	// Освобождается только резерв этого запроса
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE user_id = \\$1 AND idempotency_key = \\$2 AND created_at = \\$3 AND status_code = 0").
+		WithArgs("user-id", "key-1", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, storage.DeleteIdempotencyKey(record))
+
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE expires_at <= \\$1").
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	deleted, err := storage.DeleteExpiredIdempotencyKeys(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
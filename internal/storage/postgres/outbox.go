@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// addEvent записывает доменное событие в outbox; вызывается в транзакции изменения
+func addEvent(q querier, eventType, entityID string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO outbox (id, date_time, event_type, entity_id, payload) VALUES ($1, $2, $3, $4, $5)`
+	_, err = q.Exec(query, uuid.New().String(), time.Now(), eventType, entityID, payloadJSON)
+	return err
+}
+
+// ClaimPendingEvents выбирает неопубликованные события и скрывает их от других реплик до now+lease.
+// Строки, захваченные параллельной транзакцией, пропускаются, поэтому реплики не публикуют одно событие одновременно
+func (s *PostgresStorage) ClaimPendingEvents(now time.Time, lease time.Duration, limit int) ([]models.Event, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE outbox SET locked_until = $2
+			WHERE id IN (
+				SELECT id FROM outbox
+				WHERE published_at IS NULL AND dead_at IS NULL AND (locked_until IS NULL OR locked_until <= $1)
+				ORDER BY position
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING position, id, date_time, event_type, entity_id, payload
+		)
+		SELECT id, date_time, event_type, entity_id, payload FROM claimed ORDER BY position
+	`
+	rows, err := s.db.Query(query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+// scanEvents читает события из результата запроса
+func scanEvents(rows *sql.Rows) ([]models.Event, error) {
+	var events []models.Event
+	for rows.Next() {
+		var event models.Event
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.DateTime, &event.Type, &event.EntityID, &payload); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkEventPublished отмечает событие опубликованным
+func (s *PostgresStorage) MarkEventPublished(id string, publishedAt time.Time) error {
+	_, err := s.db.Exec(`UPDATE outbox SET published_at = $2 WHERE id = $1`, id, publishedAt)
+	return err
+}
+
+// MarkEventFailed сохраняет причину неудачной публикации и увеличивает счетчик попыток. Захват события не
+// снимается, поэтому повтор выполняется после истечения аренды; после maxAttempts попыток событие переносится
+// в недоставленные
+func (s *PostgresStorage) MarkEventFailed(id, reason string, now time.Time, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $2,
+			dead_at = CASE WHEN attempts + 1 >= $4 THEN $3::timestamp END
+		WHERE id = $1
+		RETURNING dead_at IS NOT NULL
+	`
+	var dead bool
+	err := s.db.QueryRow(query, id, reason, now, maxAttempts).Scan(&dead)
+	if err == sql.ErrNoRows {
+		return false, storage.ErrNotFound
+	}
+	return dead, err
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClaimPendingEvents проверяет захват событий с пропуском заблокированных строк и арендой
+func TestClaimPendingEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "date_time", "event_type", "entity_id", "payload"}).
+		AddRow("event-1", now, "ReceptionOpened", "reception-id", []byte(`{"id":"reception-id"}`))
+	mock.ExpectQuery("UPDATE outbox SET locked_until = \\$2 .*locked_until IS NULL OR locked_until <= \\$1.*"+
+		"FOR UPDATE SKIP LOCKED.*ORDER BY position").
+		WithArgs(now, now.Add(time.Minute), 50).
+		WillReturnRows(rows)
+
+	events, err := storage.ClaimPendingEvents(now, time.Minute, 50)
+
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "event-1", events[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestMarkEvent проверяет отметку об успешной и неудачной публикации
+func TestMarkEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+	mock.ExpectQuery("UPDATE outbox SET attempts = attempts \\+ 1, last_error = \\$2, "+
+		"dead_at = CASE WHEN attempts \\+ 1 >= \\$4 THEN \\$3::timestamp END WHERE id = \\$1 RETURNING dead_at IS NOT NULL").
+		WithArgs("event-id", "connection refused", now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"dead"}).AddRow(true))
+	mock.ExpectExec("UPDATE outbox SET published_at = \\$2 WHERE id = \\$1").
+		WithArgs("event-id", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	dead, err := storage.MarkEventFailed("event-id", "connection refused", now, 10)
+	assert.NoError(t, err)
+	assert.True(t, dead)
+	assert.NoError(t, storage.MarkEventPublished("event-id", now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
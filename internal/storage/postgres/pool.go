@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pingTimeout сколько ждать ответа базы при одной попытке подключения
+const pingTimeout = 5 * time.Second
+
+// PoolConfig настройки пула соединений и подключения; нулевые значения оставляют настройки database/sql по умолчанию
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// StatementTimeout ограничивает выполнение одного запроса на стороне PostgreSQL (statement_timeout),
+	// чтобы медленный запрос не занимал соединение из пула бесконечно
+	StatementTimeout time.Duration
+
+	// ConnectAttempts число попыток подключения при запуске; пауза между ними начинается с ConnectBackoff
+	// и удваивается до ConnectMaxBackoff
+	ConnectAttempts   int
+	ConnectBackoff    time.Duration
+	ConnectMaxBackoff time.Duration
+}
+
+// apply применяет настройки пула
+func (p PoolConfig) apply(db *sql.DB) {
+	if p.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if p.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+	if p.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+}
+
+// withStatementTimeout добавляет statement_timeout в строку подключения; lib/pq передает
+// незнакомые параметры серверу как параметры сеанса, поэтому ограничение действует на каждое соединение пула
+func withStatementTimeout(connStr string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return connStr, nil
+	}
+	milliseconds := strconv.FormatInt(timeout.Milliseconds(), 10)
+
+	if !strings.HasPrefix(connStr, "postgres://") && !strings.HasPrefix(connStr, "postgresql://") {
+		// Строка вида "host=... dbname=..."
+		return connStr + " statement_timeout=" + milliseconds, nil
+	}
+
+	u, err := url.Parse(connStr)
+	if err != nil {
+		return "", fmt.Errorf("некорректный адрес базы данных: %w", err)
+	}
+	query := u.Query()
+	query.Set("statement_timeout", milliseconds)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// pingWithRetry проверяет подключение, повторяя попытки с удвоением паузы
+func pingWithRetry(ping func(ctx context.Context) error, pool PoolConfig, sleep func(time.Duration)) error {
+	attempts := pool.ConnectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := pool.ConnectBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		err = ping(ctx)
+		cancel()
+		if err == nil || attempt >= attempts {
+			break
+		}
+
+		log.Printf("База данных недоступна (попытка %d из %d), повтор через %s: %v", attempt, attempts, backoff, err)
+		sleep(backoff)
+		backoff *= 2
+		if pool.ConnectMaxBackoff > 0 && backoff > pool.ConnectMaxBackoff {
+			backoff = pool.ConnectMaxBackoff
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к базе данных за %d попыток: %w", attempts, err)
+	}
+	return nil
+}
+
+// Stats возвращает статистику пула соединений
+func (s *PostgresStorage) Stats() sql.DBStats {
+	return s.db.Stats()
+}
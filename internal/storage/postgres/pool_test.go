@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWithStatementTimeout проверяет добавление statement_timeout в обе формы строки подключения
+func TestWithStatementTimeout(t *testing.T) {
+	connStr, err := withStatementTimeout("postgres://user:pass@db:5432/postgres?sslmode=disable", 30*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://user:pass@db:5432/postgres?sslmode=disable&statement_timeout=30000", connStr)
+
+	connStr, err = withStatementTimeout("host=db dbname=postgres", 1500*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "host=db dbname=postgres statement_timeout=1500", connStr)
+
+	// Без ограничения строка не меняется
+	connStr, err = withStatementTimeout("postgres://db/postgres", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://db/postgres", connStr)
+}
+
+// TestPingWithRetry проверяет повтор подключения с удвоением паузы до максимума
+func TestPingWithRetry(t *testing.T) {
+	pool := PoolConfig{ConnectAttempts: 5, ConnectBackoff: time.Second, ConnectMaxBackoff: 3 * time.Second}
+
+	calls := 0
+	ping := func(ctx context.Context) error {
+		calls++
+		if calls < 4 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	var pauses []time.Duration
+	sleep := func(d time.Duration) { pauses = append(pauses, d) }
+
+	assert.NoError(t, pingWithRetry(ping, pool, sleep))
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, pauses)
+
+	// После последней попытки возвращается ошибка без лишней паузы
+	calls, pauses = 0, nil
+	pool.ConnectAttempts = 2
+	err := pingWithRetry(ping, pool, sleep)
+	assert.ErrorContains(t, err, "за 2 попыток")
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Equal(t, 2, calls)
+	assert.Len(t, pauses, 1)
+}
+
+// TestPoolConfigApply проверяет применение настроек пула
+func TestPoolConfigApply(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	PoolConfig{MaxOpenConns: 7, ConnMaxLifetime: time.Minute}.apply(db)
+
+	storage := &PostgresStorage{db: db}
+	assert.Equal(t, 7, storage.Stats().MaxOpenConnections)
+}
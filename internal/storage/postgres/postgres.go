@@ -3,26 +3,37 @@ package postgres
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
 )
 
 // PostgresStorage реализация интерфейса Storage для PostgreSQL
 type PostgresStorage struct {
-	db *sql.DB
+	db    *sql.DB
+	actor *models.Actor // пользователь для журнала аудита, см. WithActor
 }
 
-// New создает новый экземпляр PostgresStorage
-func New(connStr string) (*PostgresStorage, error) {
+// New создает новый экземпляр PostgresStorage. Если база еще недоступна, например запускается
+// одновременно с сервисом, подключение повторяется pool.ConnectAttempts раз с растущей паузой
+func New(connStr string, pool PoolConfig) (*PostgresStorage, error) {
+	connStr, err := withStatementTimeout(connStr, pool.StatementTimeout)
+	if err != nil {
+		return nil, err
+	}
+
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		return nil, err
 	}
+	pool.apply(db)
 
-	if err := db.Ping(); err != nil {
+	if err := pingWithRetry(db.PingContext, pool, time.Sleep); err != nil {
+		db.Close()
 		return nil, err
 	}
 
@@ -32,68 +43,226 @@ func New(connStr string) (*PostgresStorage, error) {
 // CreateUser создает нового пользователя в базе данных
 func (s *PostgresStorage) CreateUser(user *models.User) error {
 	user.ID = uuid.New().String()
-	query := `INSERT INTO users (id, email, password, role) VALUES ($1, $2, $3, $4)`
-	_, err := s.db.Exec(query, user.ID, user.Email, user.Password, user.Role)
-	return err
+	return s.mutate(func(q querier) error {
+		query := `INSERT INTO users (id, email, password, role, pvz_id) VALUES ($1, $2, $3, $4, $5)`
+		if _, err := q.Exec(query, user.ID, user.Email, user.Password, user.Role, nullString(user.PVZID)); err != nil {
+			return err
+		}
+		return s.audit(q, "user.create", "user", user.ID, nil, user)
+	})
 }
 
 // GetUserByEmail получает пользователя по email
 func (s *PostgresStorage) GetUserByEmail(email string) (*models.User, error) {
-	query := `SELECT id, email, password, role FROM users WHERE email = $1`
+	query := `SELECT id, email, password, role, COALESCE(pvz_id::text, '') FROM users WHERE email = $1`
 	var user models.User
-	err := s.db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.Password, &user.Role)
+	err := s.db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.Password, &user.Role, &user.PVZID)
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
+// pvzColumns колонки таблицы pvz в порядке, ожидаемом scanPVZ
+const pvzColumns = `id, registration_date, city, status, status_reason, address, latitude, longitude, opening_hours`
+
+// rowScanner общий интерфейс для *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPVZ сканирует строку с колонками pvzColumns
+func scanPVZ(row rowScanner) (*models.PVZ, error) {
+	var pvz models.PVZ
+	var latitude, longitude sql.NullFloat64
+	err := row.Scan(&pvz.ID, &pvz.RegistrationDate, &pvz.City, &pvz.Status, &pvz.StatusReason,
+		&pvz.Address, &latitude, &longitude, &pvz.OpeningHours)
+	if err != nil {
+		return nil, err
+	}
+	if latitude.Valid && longitude.Valid {
+		pvz.Latitude = &latitude.Float64
+		pvz.Longitude = &longitude.Float64
+	}
+	return &pvz, nil
+}
+
 // CreatePVZ создает новый ПВЗ в базе данных
 func (s *PostgresStorage) CreatePVZ(pvz *models.PVZ) error {
 	pvz.ID = uuid.New().String()
 	pvz.RegistrationDate = time.Now()
-	query := `INSERT INTO pvz (id, registration_date, city) VALUES ($1, $2, $3)`
-	_, err := s.db.Exec(query, pvz.ID, pvz.RegistrationDate, pvz.City)
-	return err
+	pvz.Status = "active"
+	pvz.StatusReason = ""
+	query := `
+		INSERT INTO pvz (id, registration_date, city, status, status_reason, address, latitude, longitude, opening_hours)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	return s.transact(func(q querier) error {
+		_, err := q.Exec(query, pvz.ID, pvz.RegistrationDate, pvz.City, pvz.Status, pvz.StatusReason,
+			pvz.Address, pvz.Latitude, pvz.Longitude, pvz.OpeningHours)
+		if err != nil {
+			return err
+		}
+		if err := addEvent(q, "PVZCreated", pvz.ID, pvz); err != nil {
+			return err
+		}
+		return s.audit(q, "pvz.create", "pvz", pvz.ID, nil, pvz)
+	})
 }
 
 // GetPVZByID получает ПВЗ по ID
 func (s *PostgresStorage) GetPVZByID(id string) (*models.PVZ, error) {
-	query := `SELECT id, registration_date, city FROM pvz WHERE id = $1`
-	var pvz models.PVZ
-	err := s.db.QueryRow(query, id).Scan(&pvz.ID, &pvz.RegistrationDate, &pvz.City)
+	query := `SELECT ` + pvzColumns + ` FROM pvz WHERE id = $1`
+	pvz, err := scanPVZ(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	return pvz, err
+}
+
+// UpdatePVZ обновляет изменяемые поля ПВЗ: город, адрес, координаты и часы работы
+func (s *PostgresStorage) UpdatePVZ(pvz *models.PVZ) error {
+	query := `
+		UPDATE pvz
+		SET city = $1, address = $2, latitude = $3, longitude = $4, opening_hours = $5
+		WHERE id = $6
+	`
+	return s.changePVZ("pvz.update", pvz.ID, storage.ErrNotFound,
+		query, pvz.City, pvz.Address, pvz.Latitude, pvz.Longitude, pvz.OpeningHours, pvz.ID)
+}
+
+// changePVZ выполняет query над ПВЗ; если ни одна строка не изменилась, возвращает failure.
+// При ведении журнала состояния до и после читаются в той же транзакции
+func (s *PostgresStorage) changePVZ(action, id string, failure error, query string, args ...interface{}) error {
+	return s.mutate(func(q querier) error {
+		var before *models.PVZ
+		if s.auditing() {
+			var err error
+			if before, err = lockPVZ(q, id); err != nil {
+				return err
+			}
+		}
+
+		result, err := q.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return failure
+		}
+
+		if !s.auditing() {
+			return nil
+		}
+		after, err := lockPVZ(q, id)
+		if err != nil {
+			return err
+		}
+		return s.audit(q, action, "pvz", id, before, after)
+	})
+}
+
+// GetNearbyPVZ ищет работающие ПВЗ в радиусе radiusKm от точки, ближайшие первыми
+func (s *PostgresStorage) GetNearbyPVZ(lat, lon, radiusKm float64, limit int) ([]models.PVZWithDistance, error) {
+	// Расстояние по большому кругу считается по формуле гаверсинусов. Из-за погрешности вычислений аргумент
+	// ASIN для совпадающих и противоположных точек может немного превысить 1, поэтому он ограничивается
+	query := `
+		SELECT ` + pvzColumns + `, distance
+		FROM (
+			SELECT *, 6371 * 2 * ASIN(LEAST(1, SQRT(
+				POWER(SIN(RADIANS(latitude - $1) / 2), 2) +
+				COS(RADIANS($1)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - $2) / 2), 2)
+			))) AS distance
+			FROM pvz
+			WHERE latitude IS NOT NULL AND longitude IS NOT NULL AND status <> 'decommissioned'
+		) d
+		WHERE distance <= $3
+		ORDER BY distance ASC, id ASC
+		LIMIT $4
+	`
+	rows, err := s.db.Query(query, lat, lon, radiusKm, limit)
 	if err != nil {
 		return nil, err
 	}
-	return &pvz, nil
+	defer rows.Close()
+
+	result := []models.PVZWithDistance{}
+	for rows.Next() {
+		var pvz models.PVZ
+		var latitude, longitude sql.NullFloat64
+		var distance float64
+		err := rows.Scan(&pvz.ID, &pvz.RegistrationDate, &pvz.City, &pvz.Status, &pvz.StatusReason,
+			&pvz.Address, &latitude, &longitude, &pvz.OpeningHours, &distance)
+		if err != nil {
+			return nil, err
+		}
+		pvz.Latitude = &latitude.Float64
+		pvz.Longitude = &longitude.Float64
+
+		result = append(result, models.PVZWithDistance{PVZ: pvz, DistanceKm: distance})
+	}
+
+	return result, rows.Err()
 }
 
-// GetPVZList получает список ПВЗ с фильтрацией по дате приемки и пагинацией
-func (s *PostgresStorage) GetPVZList(startDate, endDate *time.Time, page, limit int) ([]models.PVZListItem, error) {
-	offset := (page - 1) * limit
-
-	var query string
-	var args []interface{}
-
-	if startDate != nil && endDate != nil {
-		query = `
-			SELECT p.id, p.registration_date, p.city 
-			FROM pvz p
-			INNER JOIN receptions r ON p.id = r.pvz_id
-			WHERE r.date_time BETWEEN $1 AND $2
-			GROUP BY p.id, p.registration_date, p.city
-			ORDER BY p.registration_date DESC
-			LIMIT $3 OFFSET $4
-		`
-		args = []interface{}{startDate, endDate, limit, offset}
-	} else {
-		query = `
-			SELECT id, registration_date, city 
-			FROM pvz
-			ORDER BY registration_date DESC
-			LIMIT $1 OFFSET $2
-		`
-		args = []interface{}{limit, offset}
+// UpdatePVZStatus меняет статус ПВЗ. ПВЗ с незакрытой приемкой нельзя приостановить или закрыть;
+// строка ПВЗ блокируется, чтобы проверка и смена статуса выполнялись согласованно
+func (s *PostgresStorage) UpdatePVZStatus(id, status, reason string) error {
+	return s.transact(func(q querier) error {
+		before, err := lockPVZ(q, id)
+		if err != nil {
+			return err
+		}
+
+		if status != "active" {
+			var hasOpenReception bool
+			query := `SELECT EXISTS (SELECT 1 FROM receptions WHERE pvz_id = $1 AND status = 'in_progress')`
+			if err := q.QueryRow(query, id).Scan(&hasOpenReception); err != nil {
+				return err
+			}
+			if hasOpenReception {
+				return storage.ErrPVZHasOpenReception
+			}
+		}
+
+		query := `UPDATE pvz SET status = $1, status_reason = $2 WHERE id = $3`
+		if _, err := q.Exec(query, status, reason, id); err != nil {
+			return err
+		}
+
+		after := *before
+		after.Status = status
+		after.StatusReason = reason
+		return s.audit(q, "pvz.status", "pvz", id, before, &after)
+	})
+}
+
+// GetPVZList получает список ПВЗ с фильтрацией по дате приемки, статусу и пагинацией
+func (s *PostgresStorage) GetPVZList(filter models.PVZListFilter) ([]models.PVZListItem, error) {
+	offset := (filter.Page - 1) * filter.Limit
+
+	var args queryArgs
+	conditions := pvzListConditions(filter, &args)
+
+	// Курсор задает позицию по ключу (registration_date, id), поэтому новые ПВЗ не сдвигают страницы
+	if filter.After != nil {
+		conditions = append(conditions, `(p.registration_date, p.id) < (`+args.add(filter.After.RegistrationDate)+`, `+args.add(filter.After.ID)+`)`)
+	}
+
+	query := `
+		SELECT ` + pvzColumns + `
+		FROM pvz p
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY p.registration_date DESC, p.id DESC
+		LIMIT ` + args.add(filter.Limit)
+	if filter.After == nil {
+		query += ` OFFSET ` + args.add(offset)
 	}
 
 	rows, err := s.db.Query(query, args...)
@@ -104,18 +273,18 @@ func (s *PostgresStorage) GetPVZList(startDate, endDate *time.Time, page, limit
 
 	var result []models.PVZListItem
 	for rows.Next() {
-		var pvz models.PVZ
-		if err := rows.Scan(&pvz.ID, &pvz.RegistrationDate, &pvz.City); err != nil {
+		pvz, err := scanPVZ(rows)
+		if err != nil {
 			return nil, err
 		}
 
-		receptions, err := s.getReceptionsWithProductsByPVZID(pvz.ID)
+		receptions, err := s.getReceptionsWithProductsByPVZID(pvz.ID, filter)
 		if err != nil {
 			return nil, err
 		}
 
 		result = append(result, models.PVZListItem{
-			PVZ:        pvz,
+			PVZ:        *pvz,
 			Receptions: receptions,
 		})
 	}
@@ -123,15 +292,84 @@ func (s *PostgresStorage) GetPVZList(startDate, endDate *time.Time, page, limit
 	return result, nil
 }
 
-// getReceptionsWithProductsByPVZID получает приемки с товарами для ПВЗ
-func (s *PostgresStorage) getReceptionsWithProductsByPVZID(pvzID string) ([]models.ReceptionWithProducts, error) {
+// CountPVZList считает ПВЗ, подходящие под фильтр, без учета пагинации и курсора
+func (s *PostgresStorage) CountPVZList(filter models.PVZListFilter) (int, error) {
+	var args queryArgs
+	conditions := pvzListConditions(filter, &args)
+
+	query := `SELECT COUNT(*) FROM pvz p WHERE ` + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := s.db.QueryRow(query, args...).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// queryArgs накапливает аргументы запроса и выдает для них плейсхолдеры
+type queryArgs []interface{}
+
+// add добавляет аргумент и возвращает его плейсхолдер
+func (a *queryArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// pvzListConditions строит условия отбора ПВЗ, общие для списка и подсчета
+func pvzListConditions(filter models.PVZListFilter, args *queryArgs) []string {
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{"active", "suspended", "decommissioned"}
+	}
+
+	var conditions []string
+	if filter.City != "" {
+		conditions = append(conditions, `p.city = `+args.add(filter.City))
+	}
+	if hasReceptionFilter(filter) {
+		receptionConditions := append([]string{`r.pvz_id = p.id`}, receptionFilterConditions(filter, args)...)
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM receptions r WHERE `+strings.Join(receptionConditions, " AND ")+`)`)
+	}
+	conditions = append(conditions, `p.status = ANY(`+args.add(pq.Array(statuses))+`)`)
+
+	return conditions
+}
+
+// hasReceptionFilter проверяет, заданы ли в фильтре условия на приемки или товары
+func hasReceptionFilter(filter models.PVZListFilter) bool {
+	return filter.StartDate != nil || filter.EndDate != nil || filter.ReceptionStatus != "" || filter.ProductType != ""
+}
+
+// receptionFilterConditions строит условия отбора приемок r; диапазон дат может быть открыт с любой стороны
+func receptionFilterConditions(filter models.PVZListFilter, args *queryArgs) []string {
+	var conditions []string
+	if filter.StartDate != nil {
+		conditions = append(conditions, `r.date_time >= `+args.add(filter.StartDate))
+	}
+	if filter.EndDate != nil {
+		conditions = append(conditions, `r.date_time <= `+args.add(filter.EndDate))
+	}
+	if filter.ReceptionStatus != "" {
+		conditions = append(conditions, `r.status = `+args.add(filter.ReceptionStatus))
+	}
+	if filter.ProductType != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM products pr WHERE pr.reception_id = r.id AND pr.type = `+args.add(filter.ProductType)+`)`)
+	}
+	return conditions
+}
+
+// getReceptionsWithProductsByPVZID получает приемки ПВЗ с товарами, подходящие под фильтр списка
+func (s *PostgresStorage) getReceptionsWithProductsByPVZID(pvzID string, filter models.PVZListFilter) ([]models.ReceptionWithProducts, error) {
+	args := queryArgs{pvzID}
+	conditions := append([]string{`r.pvz_id = $1`}, receptionFilterConditions(filter, &args)...)
+
 	query := `
-		SELECT id, date_time, pvz_id, status
-		FROM receptions
-		WHERE pvz_id = $1
+		SELECT id, date_time, pvz_id, status, status_reason
+		FROM receptions r
+		WHERE ` + strings.Join(conditions, " AND ") + `
 		ORDER BY date_time DESC
 	`
-	rows, err := s.db.Query(query, pvzID)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -140,24 +378,54 @@ func (s *PostgresStorage) getReceptionsWithProductsByPVZID(pvzID string) ([]mode
 	var result []models.ReceptionWithProducts
 	for rows.Next() {
 		var reception models.Reception
-		if err := rows.Scan(&reception.ID, &reception.DateTime, &reception.PVZID, &reception.Status); err != nil {
+		if err := rows.Scan(&reception.ID, &reception.DateTime, &reception.PVZID, &reception.Status, &reception.StatusReason); err != nil {
 			return nil, err
 		}
 
-		products, err := s.GetProductsByReceptionID(reception.ID)
-		if err != nil {
-			return nil, err
+		item := models.ReceptionWithProducts{Reception: reception}
+		if !filter.OmitProducts {
+			item.Products, err = s.getProductsByType(reception.ID, filter.ProductType)
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		result = append(result, models.ReceptionWithProducts{
-			Reception: reception,
-			Products:  products,
-		})
+		result = append(result, item)
 	}
 
 	return result, nil
 }
 
+// getProductsByType получает товары приемки; пустой тип означает все товары
+func (s *PostgresStorage) getProductsByType(receptionID, productType string) ([]models.Product, error) {
+	if productType == "" {
+		return s.GetProductsByReceptionID(receptionID)
+	}
+
+	query := `
+		SELECT id, date_time, type, reception_id, sequence
+		FROM products
+		WHERE reception_id = $1 AND type = $2
+		ORDER BY sequence ASC, date_time ASC
+	`
+	rows, err := s.db.Query(query, receptionID, productType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		var product models.Product
+		if err := rows.Scan(&product.ID, &product.DateTime, &product.Type, &product.ReceptionID, &product.Sequence); err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+
+	return products, rows.Err()
+}
+
 // CreateReception создает новую приемку в базе данных
 func (s *PostgresStorage) CreateReception(reception *models.Reception) error {
 	reception.ID = uuid.New().String()
@@ -174,22 +442,43 @@ func (s *PostgresStorage) CreateReception(reception *models.Reception) error {
 		return fmt.Errorf("уже есть незакрытая приемка для этого ПВЗ")
 	}
 
-	query := `INSERT INTO receptions (id, date_time, pvz_id, status) VALUES ($1, $2, $3, $4)`
-	_, err = s.db.Exec(query, reception.ID, reception.DateTime, reception.PVZID, reception.Status)
-	return err
+	return s.transact(func(q querier) error {
+		query := `INSERT INTO receptions (id, date_time, pvz_id, status) VALUES ($1, $2, $3, $4)`
+		if _, err := q.Exec(query, reception.ID, reception.DateTime, reception.PVZID, reception.Status); err != nil {
+			return err
+		}
+		if err := addEvent(q, "ReceptionOpened", reception.ID, reception); err != nil {
+			return err
+		}
+		return s.audit(q, "reception.create", "reception", reception.ID, nil, reception)
+	})
 }
 
 // GetLastReceptionByPVZID получает последнюю приемку для ПВЗ
 func (s *PostgresStorage) GetLastReceptionByPVZID(pvzID string) (*models.Reception, error) {
 	query := `
-		SELECT id, date_time, pvz_id, status
+		SELECT id, date_time, pvz_id, status, status_reason
 		FROM receptions
 		WHERE pvz_id = $1
 		ORDER BY date_time DESC
 		LIMIT 1
 	`
 	var reception models.Reception
-	err := s.db.QueryRow(query, pvzID).Scan(&reception.ID, &reception.DateTime, &reception.PVZID, &reception.Status)
+	err := s.db.QueryRow(query, pvzID).Scan(&reception.ID, &reception.DateTime, &reception.PVZID, &reception.Status, &reception.StatusReason)
+	if err != nil {
+		return nil, err
+	}
+	return &reception, nil
+}
+
+// GetReceptionByID получает приемку по ID
+func (s *PostgresStorage) GetReceptionByID(id string) (*models.Reception, error) {
+	query := `SELECT id, date_time, pvz_id, status, status_reason FROM receptions WHERE id = $1`
+	var reception models.Reception
+	err := s.db.QueryRow(query, id).Scan(&reception.ID, &reception.DateTime, &reception.PVZID, &reception.Status, &reception.StatusReason)
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -198,41 +487,136 @@ func (s *PostgresStorage) GetLastReceptionByPVZID(pvzID string) (*models.Recepti
 
 // CloseReception закрывает приемку
 func (s *PostgresStorage) CloseReception(receptionID string) error {
-	query := `UPDATE receptions SET status = 'close' WHERE id = $1 AND status = 'in_progress'`
-	result, err := s.db.Exec(query, receptionID)
+	query := `UPDATE receptions SET status = 'close', closed_at = $2 WHERE id = $1 AND status = 'in_progress'`
+	return s.changeReception("reception.close", "ReceptionClosed", receptionID, fmt.Errorf("приемка уже закрыта или не существует"),
+		query, receptionID, time.Now())
+}
+
+// changeReception выполняет query над приемкой; если ни одна строка не изменилась, возвращает failure.
+// При ведении журнала состояния до и после читаются в той же транзакции; непустой event записывается
+// в outbox с состоянием приемки после изменения
+func (s *PostgresStorage) changeReception(action, event, receptionID string, failure error, query string, args ...interface{}) error {
+	run := s.mutate
+	if event != "" {
+		run = s.transact
+	}
+	return run(func(q querier) error {
+		var before *models.Reception
+		if s.auditing() {
+			var err error
+			if before, err = lockReception(q, receptionID); err != nil {
+				return err
+			}
+		}
+
+		result, err := q.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return failure
+		}
+
+		if !s.auditing() && event == "" {
+			return nil
+		}
+		after, err := lockReception(q, receptionID)
+		if err != nil {
+			return err
+		}
+		if event != "" {
+			if err := addEvent(q, event, receptionID, after); err != nil {
+				return err
+			}
+		}
+		return s.audit(q, action, "reception", receptionID, before, after)
+	})
+}
+
+// ReopenReception снова открывает закрытую приемку, если она последняя в ПВЗ
+func (s *PostgresStorage) ReopenReception(receptionID string) error {
+	// Условия проверяются в самом обновлении, чтобы между проверкой и записью ПВЗ не успели приостановить
+	query := `
+		UPDATE receptions SET status = 'in_progress', closed_at = NULL, status_reason = ''
+		WHERE id = $1 AND status = 'close'
+			AND id = (SELECT r.id FROM receptions r WHERE r.pvz_id = receptions.pvz_id ORDER BY r.date_time DESC LIMIT 1)
+			AND EXISTS (SELECT 1 FROM pvz p WHERE p.id = receptions.pvz_id AND p.status = 'active')
+			AND NOT EXISTS (SELECT 1 FROM transfers t WHERE t.reception_id = receptions.id)
+	`
+	return s.changeReception("reception.reopen", "", receptionID,
+		fmt.Errorf("открыть можно только последнюю закрытую приемку работающего ПВЗ, не принятую по перемещению"), query, receptionID)
+}
+
+// CancelReception отменяет незакрытую приемку с указанием причины. Товары отмененной приемки не остаются в ПВЗ,
+// поэтому их ячейки освобождаются тем же запросом
+func (s *PostgresStorage) CancelReception(receptionID, reason string) error {
+	query := `
+		WITH released AS (
+			DELETE FROM product_cells
+			WHERE product_id IN (
+				SELECT p.id FROM products p
+				INNER JOIN receptions r ON r.id = p.reception_id
+				WHERE r.id = $1 AND r.status = 'in_progress'
+			)
+		)
+		UPDATE receptions SET status = 'cancelled', status_reason = $2 WHERE id = $1 AND status = 'in_progress'
+	`
+	return s.changeReception("reception.cancel", "", receptionID, fmt.Errorf("приемка уже закрыта или не существует"),
+		query, receptionID, reason)
+}
+
+// CreateProduct создает новый товар в базе данных
+func (s *PostgresStorage) CreateProduct(product *models.Product) error {
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	sequence, err := nextProductSequence(tx, product.ReceptionID, 1)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("приемка уже закрыта или не существует")
+	product.ID = uuid.New().String()
+	product.DateTime = time.Now()
+	product.Sequence = sequence
+
+	query := `INSERT INTO products (id, date_time, type, reception_id, sequence) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.Exec(query, product.ID, product.DateTime, product.Type, product.ReceptionID, product.Sequence); err != nil {
+		return err
 	}
 
-	return nil
-}
+	// Размещение в ячейке выполняется в той же транзакции: если ячейка занята, товар не сохраняется
+	if product.CellID != "" {
+		if err := placeProductInCell(tx, product.ID, product.CellID); err != nil {
+			return err
+		}
+	}
 
-// CreateProduct создает новый товар в базе данных
-func (s *PostgresStorage) CreateProduct(product *models.Product) error {
-	product.ID = uuid.New().String()
-	product.DateTime = time.Now()
+	if err := addEvent(tx, "ProductAdded", product.ID, product); err != nil {
+		return err
+	}
+	if err := s.audit(tx, "product.create", "product", product.ID, nil, product); err != nil {
+		return err
+	}
 
-	query := `INSERT INTO products (id, date_time, type, reception_id) VALUES ($1, $2, $3, $4)`
-	_, err := s.db.Exec(query, product.ID, product.DateTime, product.Type, product.ReceptionID)
-	return err
+	return tx.Commit()
 }
 
 // GetProductsByReceptionID получает товары по ID приемки
 func (s *PostgresStorage) GetProductsByReceptionID(receptionID string) ([]models.Product, error) {
 	query := `
-		SELECT id, date_time, type, reception_id
+		SELECT id, date_time, type, reception_id, sequence
 		FROM products
 		WHERE reception_id = $1
-		ORDER BY date_time ASC
+		ORDER BY sequence ASC, date_time ASC
 	`
 	rows, err := s.db.Query(query, receptionID)
 	if err != nil {
@@ -243,7 +627,7 @@ func (s *PostgresStorage) GetProductsByReceptionID(receptionID string) ([]models
 	var products []models.Product
 	for rows.Next() {
 		var product models.Product
-		if err := rows.Scan(&product.ID, &product.DateTime, &product.Type, &product.ReceptionID); err != nil {
+		if err := rows.Scan(&product.ID, &product.DateTime, &product.Type, &product.ReceptionID, &product.Sequence); err != nil {
 			return nil, err
 		}
 		products = append(products, product)
@@ -252,38 +636,72 @@ func (s *PostgresStorage) GetProductsByReceptionID(receptionID string) ([]models
 	return products, nil
 }
 
-// DeleteLastProductInReception удаляет последний добавленный товар в приемке
-func (s *PostgresStorage) DeleteLastProductInReception(receptionID string) error {
+// DeleteLastProductInReception удаляет последний добавленный товар в приемке и возвращает его
+func (s *PostgresStorage) DeleteLastProductInReception(receptionID string) (*models.Product, error) {
 	tx, err := s.db.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	// Получаем ID последнего добавленного товара
+	// Получаем последний добавленный товар; товары до введения номеров имеют номер 0 и упорядочены по времени
 	query := `
-		SELECT id
+		SELECT id, date_time, type, reception_id, sequence
 		FROM products
 		WHERE reception_id = $1
-		ORDER BY date_time DESC
+		ORDER BY sequence DESC, date_time DESC
 		LIMIT 1
+		FOR UPDATE
 	`
-	var productID string
-	err = tx.QueryRow(query, receptionID).Scan(&productID)
+	var product models.Product
+	err = tx.QueryRow(query, receptionID).Scan(&product.ID, &product.DateTime, &product.Type, &product.ReceptionID, &product.Sequence)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("нет товаров для удаления")
+			return nil, fmt.Errorf("нет товаров для удаления")
 		}
-		return err
+		return nil, err
+	}
+
+	if err := checkProductNotTransferred(tx, product.ID); err != nil {
+		return nil, err
+	}
+
+	if err := checkProductNotReturned(tx, product.ID); err != nil {
+		return nil, err
 	}
 
 	// Удаляем товар
-	_, err = tx.Exec(`DELETE FROM products WHERE id = $1`, productID)
+	_, err = tx.Exec(`DELETE FROM products WHERE id = $1`, product.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return tx.Commit()
+	if err := addEvent(tx, "ProductDeleted", product.ID, &product); err != nil {
+		return nil, err
+	}
+	if err := s.audit(tx, "product.delete", "product", product.ID, &product, nil); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// GetProductByID получает товар по ID
+func (s *PostgresStorage) GetProductByID(id string) (*models.Product, error) {
+	query := `SELECT id, date_time, type, reception_id, sequence FROM products WHERE id = $1`
+	var product models.Product
+	err := s.db.QueryRow(query, id).Scan(&product.ID, &product.DateTime, &product.Type, &product.ReceptionID, &product.Sequence)
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
 }
 
 // InitDB инициализирует базу данных
@@ -314,6 +732,159 @@ func (s *PostgresStorage) InitDB() error {
 			reception_id UUID NOT NULL,
 			FOREIGN KEY (reception_id) REFERENCES receptions (id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS return_batches (
+			id UUID PRIMARY KEY,
+			date_time TIMESTAMP NOT NULL,
+			pvz_id UUID NOT NULL,
+			status TEXT NOT NULL,
+			FOREIGN KEY (pvz_id) REFERENCES pvz (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS returns (
+			id UUID PRIMARY KEY,
+			date_time TIMESTAMP NOT NULL,
+			product_id UUID UNIQUE,
+			type TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			batch_id UUID NOT NULL,
+			FOREIGN KEY (product_id) REFERENCES products (id),
+			FOREIGN KEY (batch_id) REFERENCES return_batches (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS storage_cells (
+			id UUID PRIMARY KEY,
+			pvz_id UUID NOT NULL,
+			code TEXT NOT NULL,
+			capacity INTEGER NOT NULL CHECK (capacity > 0),
+			UNIQUE (pvz_id, code),
+			FOREIGN KEY (pvz_id) REFERENCES pvz (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS product_cells (
+			product_id UUID PRIMARY KEY,
+			cell_id UUID NOT NULL,
+			assigned_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE,
+			FOREIGN KEY (cell_id) REFERENCES storage_cells (id)
+		)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS pvz_id UUID REFERENCES pvz (id)`,
+		`CREATE TABLE IF NOT EXISTS transfers (
+			id UUID PRIMARY KEY,
+			date_time TIMESTAMP NOT NULL,
+			source_pvz_id UUID NOT NULL,
+			destination_pvz_id UUID NOT NULL,
+			status TEXT NOT NULL,
+			reception_id UUID,
+			FOREIGN KEY (source_pvz_id) REFERENCES pvz (id),
+			FOREIGN KEY (destination_pvz_id) REFERENCES pvz (id),
+			FOREIGN KEY (reception_id) REFERENCES receptions (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS transfer_products (
+			transfer_id UUID NOT NULL,
+			product_id UUID NOT NULL UNIQUE,
+			position INTEGER NOT NULL,
+			received_product_id UUID,
+			PRIMARY KEY (transfer_id, product_id),
+			FOREIGN KEY (transfer_id) REFERENCES transfers (id),
+			FOREIGN KEY (product_id) REFERENCES products (id),
+			FOREIGN KEY (received_product_id) REFERENCES products (id)
+		)`,
+		`ALTER TABLE pvz ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'`,
+		`ALTER TABLE pvz ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE pvz ADD COLUMN IF NOT EXISTS address TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE pvz ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION`,
+		`ALTER TABLE pvz ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION`,
+		`ALTER TABLE pvz ADD COLUMN IF NOT EXISTS opening_hours TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE receptions ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP`,
+		`ALTER TABLE receptions ADD COLUMN IF NOT EXISTS last_product_sequence INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS sequence INTEGER NOT NULL DEFAULT 0`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS products_reception_sequence_idx ON products (reception_id, sequence) WHERE sequence > 0`,
+		`CREATE TABLE IF NOT EXISTS product_changes (
+			id UUID PRIMARY KEY,
+			date_time TIMESTAMP NOT NULL,
+			product_id UUID NOT NULL,
+			reception_id UUID NOT NULL,
+			action TEXT NOT NULL,
+			old_type TEXT NOT NULL,
+			new_type TEXT NOT NULL DEFAULT '',
+			user_id TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (reception_id) REFERENCES receptions (id)
+		)`,
+		`ALTER TABLE receptions ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id UUID PRIMARY KEY,
+			date_time TIMESTAMP NOT NULL,
+			user_id TEXT NOT NULL DEFAULT '',
+			role TEXT NOT NULL DEFAULT '',
+			request_id TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			before JSONB,
+			after JSONB
+		)`,
+		`CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity_type, entity_id, date_time)`,
+		`CREATE INDEX IF NOT EXISTS audit_log_user_idx ON audit_log (user_id, date_time)`,
+		// Журнал только дополняется: изменение и удаление записей игнорируются
+		`CREATE OR REPLACE RULE audit_log_no_update AS ON UPDATE TO audit_log DO INSTEAD NOTHING`,
+		`CREATE OR REPLACE RULE audit_log_no_delete AS ON DELETE TO audit_log DO INSTEAD NOTHING`,
+		`CREATE TABLE IF NOT EXISTS outbox (
+			id UUID PRIMARY KEY,
+			position BIGSERIAL NOT NULL,
+			date_time TIMESTAMP NOT NULL,
+			event_type TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			published_at TIMESTAMP,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			locked_until TIMESTAMP,
+			dead_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (position) WHERE published_at IS NULL AND dead_at IS NULL`,
+		`CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+			id UUID PRIMARY KEY,
+			date_time TIMESTAMP NOT NULL,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			event_types TEXT[] NOT NULL DEFAULT '{}',
+			pvz_id TEXT NOT NULL DEFAULT '',
+			city TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id UUID PRIMARY KEY,
+			date_time TIMESTAMP NOT NULL,
+			subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
+			response_status INTEGER NOT NULL DEFAULT 0,
+			delivered_at TIMESTAMP,
+			UNIQUE (subscription_id, event_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending'`,
+		`CREATE INDEX IF NOT EXISTS webhook_deliveries_history_idx ON webhook_deliveries (subscription_id, date_time)`,
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+			user_id TEXT NOT NULL,
+			idempotency_key TEXT NOT NULL,
+			request_hash TEXT NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			content_type TEXT NOT NULL DEFAULT '',
+			body BYTEA,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			locked_until TIMESTAMP NOT NULL,
+			PRIMARY KEY (user_id, idempotency_key)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idempotency_keys_expires_idx ON idempotency_keys (expires_at)`,
+		`CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+			bucket_key TEXT PRIMARY KEY,
+			tokens DOUBLE PRECISION NOT NULL,
+			allowed BOOLEAN NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS rate_limit_buckets_updated_idx ON rate_limit_buckets (updated_at)`,
 	}
 
 	for _, query := range queries {
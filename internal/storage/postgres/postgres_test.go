@@ -7,9 +7,17 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/aventhis/avito_pvz_service/internal/models"
+	storagepkg "github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
+// newPVZRows создает набор строк с колонками pvzColumns
+func newPVZRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "registration_date", "city", "status", "status_reason",
+		"address", "latitude", "longitude", "opening_hours"})
+}
+
 // TestNew проверяет создание нового экземпляра PostgresStorage
 func TestNew(t *testing.T) {
 	db, _, err := sqlmock.New()
@@ -44,7 +52,7 @@ func TestCreateUser(t *testing.T) {
 
 	// ID будет сгенерирован автоматически, поэтому используем регулярное выражение для проверки
 	mock.ExpectExec("INSERT INTO users").
-		WithArgs(sqlmock.AnyArg(), user.Email, user.Password, user.Role).
+		WithArgs(sqlmock.AnyArg(), user.Email, user.Password, user.Role, sql.NullString{}).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	err = storage.CreateUser(user)
@@ -71,10 +79,10 @@ func TestGetUserByEmail(t *testing.T) {
 		Role:     "employee",
 	}
 
-	mock.ExpectQuery("SELECT id, email, password, role FROM users WHERE email = \\$1").
+	mock.ExpectQuery("SELECT id, email, password, role, COALESCE\\(pvz_id::text, ''\\) FROM users WHERE email = \\$1").
 		WithArgs(expectedUser.Email).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "role"}).
-			AddRow(expectedUser.ID, expectedUser.Email, expectedUser.Password, expectedUser.Role))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "role", "pvz_id"}).
+			AddRow(expectedUser.ID, expectedUser.Email, expectedUser.Password, expectedUser.Role, ""))
 
 	// Вызываем тестируемый метод
 	user, err := storage.GetUserByEmail(expectedUser.Email)
@@ -100,7 +108,7 @@ func TestGetUserByEmail_NotFound(t *testing.T) {
 
 	email := "nonexistent@example.com"
 
-	mock.ExpectQuery("SELECT id, email, password, role FROM users WHERE email = \\$1").
+	mock.ExpectQuery("SELECT id, email, password, role, COALESCE\\(pvz_id::text, ''\\) FROM users WHERE email = \\$1").
 		WithArgs(email).
 		WillReturnError(sql.ErrNoRows)
 
@@ -127,14 +135,20 @@ func TestCreatePVZ(t *testing.T) {
 		City: "Москва",
 	}
 
+	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO pvz").
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), pvz.City).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), pvz.City, "active", "", "", nil, nil, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "PVZCreated", sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	err = storage.CreatePVZ(pvz)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, pvz.ID)
 	assert.NotEmpty(t, pvz.RegistrationDate)
+	assert.Equal(t, "active", pvz.Status)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -156,10 +170,10 @@ func TestGetPVZByID(t *testing.T) {
 		City:             "Москва",
 	}
 
-	mock.ExpectQuery("SELECT id, registration_date, city FROM pvz WHERE id = \\$1").
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz WHERE id = \\$1").
 		WithArgs(expectedPVZ.ID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "registration_date", "city"}).
-			AddRow(expectedPVZ.ID, expectedPVZ.RegistrationDate, expectedPVZ.City))
+		WillReturnRows(newPVZRows().
+			AddRow(expectedPVZ.ID, expectedPVZ.RegistrationDate, expectedPVZ.City, "active", "", "", nil, nil, ""))
 
 	// Вызываем тестируемый метод
 	pvz, err := storage.GetPVZByID(expectedPVZ.ID)
@@ -184,7 +198,7 @@ func TestGetPVZByID_NotFound(t *testing.T) {
 
 	pvzID := "nonexistent-id"
 
-	mock.ExpectQuery("SELECT id, registration_date, city FROM pvz WHERE id = \\$1").
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz WHERE id = \\$1").
 		WithArgs(pvzID).
 		WillReturnError(sql.ErrNoRows)
 
@@ -192,7 +206,7 @@ func TestGetPVZByID_NotFound(t *testing.T) {
 	pvz, err := storage.GetPVZByID(pvzID)
 
 	// Проверяем результаты
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, storagepkg.ErrNotFound)
 	assert.Nil(t, pvz)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -213,31 +227,31 @@ func TestGetPVZList(t *testing.T) {
 	limit := 10
 	
 	// Запрос на получение ПВЗ без фильтрации по дате
-	mock.ExpectQuery("SELECT id, registration_date, city FROM pvz").
-		WithArgs(limit, (page-1)*limit).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "registration_date", "city"}).
-			AddRow("pvz-id-1", now, "Москва").
-			AddRow("pvz-id-2", now, "Санкт-Петербург"))
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz p WHERE p.status = ANY\\(\\$1\\) ORDER BY p.registration_date DESC, p.id DESC LIMIT \\$2 OFFSET \\$3").
+		WithArgs(pq.Array([]string{"active", "suspended", "decommissioned"}), limit, (page-1)*limit).
+		WillReturnRows(newPVZRows().
+			AddRow("pvz-id-1", now, "Москва", "active", "", "", nil, nil, "").
+			AddRow("pvz-id-2", now, "Санкт-Петербург", "active", "", "", nil, nil, ""))
 			
 	// Запрос на получение приемок для первого ПВЗ
-	mock.ExpectQuery("SELECT id, date_time, pvz_id, status FROM receptions").
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions").
 		WithArgs("pvz-id-1").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
-			AddRow("reception-id-1", now, "pvz-id-1", "in_progress"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}).
+			AddRow("reception-id-1", now, "pvz-id-1", "in_progress", ""))
 	
 	// Запрос на получение товаров для приемки первого ПВЗ
-	mock.ExpectQuery("SELECT id, date_time, type, reception_id FROM products").
+	mock.ExpectQuery("SELECT id, date_time, type, reception_id, sequence FROM products").
 		WithArgs("reception-id-1").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id"}).
-			AddRow("product-id-1", now, "электроника", "reception-id-1"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "sequence"}).
+			AddRow("product-id-1", now, "электроника", "reception-id-1", 1))
 			
 	// Запрос на получение приемок для второго ПВЗ
-	mock.ExpectQuery("SELECT id, date_time, pvz_id, status FROM receptions").
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions").
 		WithArgs("pvz-id-2").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}))
 
 	// Вызываем тестируемый метод
-	pvzList, err := storage.GetPVZList(nil, nil, page, limit)
+	pvzList, err := storage.GetPVZList(models.PVZListFilter{Page: page, Limit: limit})
 
 	// Проверяем результаты
 	assert.NoError(t, err)
@@ -270,24 +284,30 @@ func TestGetPVZList_WithDateFilter(t *testing.T) {
 	limit := 10
 	
 	// Запрос на получение ПВЗ с фильтрацией по дате
-	mock.ExpectQuery("SELECT p.id, p.registration_date, p.city FROM pvz p").
-		WithArgs(startDate, endDate, limit, (page-1)*limit).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "registration_date", "city"}).
-			AddRow("pvz-id-1", now, "Москва"))
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz p WHERE EXISTS").
+		WithArgs(startDate, endDate, pq.Array([]string{"active"}), limit, (page-1)*limit).
+		WillReturnRows(newPVZRows().
+			AddRow("pvz-id-1", now, "Москва", "active", "", "", nil, nil, ""))
 			
-	// Запрос на получение приемок для ПВЗ
-	mock.ExpectQuery("SELECT id, date_time, pvz_id, status FROM receptions").
-		WithArgs("pvz-id-1").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
-			AddRow("reception-id-1", now, "pvz-id-1", "in_progress"))
+	// Запрос на получение приемок для ПВЗ в том же диапазоне дат
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions r WHERE r.pvz_id = \\$1 AND r.date_time >= \\$2 AND r.date_time <= \\$3").
+		WithArgs("pvz-id-1", startDate, endDate).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}).
+			AddRow("reception-id-1", now, "pvz-id-1", "in_progress", ""))
 	
 	// Запрос на получение товаров для приемки
-	mock.ExpectQuery("SELECT id, date_time, type, reception_id FROM products").
+	mock.ExpectQuery("SELECT id, date_time, type, reception_id, sequence FROM products").
 		WithArgs("reception-id-1").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id"}))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "sequence"}))
 
 	// Вызываем тестируемый метод
-	pvzList, err := storage.GetPVZList(&startDate, &endDate, page, limit)
+	pvzList, err := storage.GetPVZList(models.PVZListFilter{
+		StartDate: &startDate,
+		EndDate:   &endDate,
+		Statuses:  []string{"active"},
+		Page:      page,
+		Limit:     limit,
+	})
 
 	// Проверяем результаты
 	assert.NoError(t, err)
@@ -314,13 +334,18 @@ func TestCreateReception(t *testing.T) {
 	}
 
 	// Получение последней приемки для проверки, нет ли незакрытой
-	mock.ExpectQuery("SELECT id, date_time, pvz_id, status FROM receptions").
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions").
 		WithArgs(reception.PVZID).
 		WillReturnError(sql.ErrNoRows)
 
+	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO receptions").
 		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), reception.PVZID, "in_progress").
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ReceptionOpened", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	err = storage.CreateReception(reception)
 	assert.NoError(t, err)
@@ -346,10 +371,10 @@ func TestCreateReception_ExistingOpenReception(t *testing.T) {
 
 	// Получение последней приемки - уже есть незакрытая
 	lastDateTime := time.Now()
-	mock.ExpectQuery("SELECT id, date_time, pvz_id, status FROM receptions").
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions").
 		WithArgs(reception.PVZID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
-			AddRow("last-reception-id", lastDateTime, reception.PVZID, "in_progress"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}).
+			AddRow("last-reception-id", lastDateTime, reception.PVZID, "in_progress", ""))
 
 	err = storage.CreateReception(reception)
 	assert.Error(t, err)
@@ -370,10 +395,10 @@ func TestGetLastReceptionByPVZID(t *testing.T) {
 	pvzID := "pvz-id"
 	now := time.Now()
 
-	mock.ExpectQuery("SELECT id, date_time, pvz_id, status FROM receptions").
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions").
 		WithArgs(pvzID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
-			AddRow("reception-id", now, pvzID, "in_progress"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}).
+			AddRow("reception-id", now, pvzID, "in_progress", ""))
 
 	reception, err := storage.GetLastReceptionByPVZID(pvzID)
 	assert.NoError(t, err)
@@ -395,7 +420,7 @@ func TestGetLastReceptionByPVZID_NotFound(t *testing.T) {
 
 	pvzID := "pvz-id"
 
-	mock.ExpectQuery("SELECT id, date_time, pvz_id, status FROM receptions").
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions").
 		WithArgs(pvzID).
 		WillReturnError(sql.ErrNoRows)
 
@@ -417,9 +442,18 @@ func TestCloseReception(t *testing.T) {
 
 	receptionID := "reception-id"
 
-	mock.ExpectExec("UPDATE receptions SET status = 'close' WHERE id = \\$1 AND status = 'in_progress'").
-		WithArgs(receptionID).
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE receptions SET status = 'close', closed_at = \\$2 WHERE id = \\$1 AND status = 'in_progress'").
+		WithArgs(receptionID, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions WHERE id = \\$1 FOR UPDATE").
+		WithArgs(receptionID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}).
+			AddRow(receptionID, time.Now(), "pvz-id", "close", ""))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ReceptionClosed", receptionID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	err = storage.CloseReception(receptionID)
 	assert.NoError(t, err)
@@ -438,9 +472,11 @@ func TestCloseReception_AlreadyClosed(t *testing.T) {
 
 	receptionID := "reception-id"
 
-	mock.ExpectExec("UPDATE receptions SET status = 'close' WHERE id = \\$1 AND status = 'in_progress'").
-		WithArgs(receptionID).
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE receptions SET status = 'close', closed_at = \\$2 WHERE id = \\$1 AND status = 'in_progress'").
+		WithArgs(receptionID, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	err = storage.CloseReception(receptionID)
 	assert.Error(t, err)
@@ -463,14 +499,71 @@ func TestCreateProduct(t *testing.T) {
 		ReceptionID: "reception-id",
 	}
 
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE receptions SET last_product_sequence = last_product_sequence \\+ \\$2 WHERE id = \\$1 AND status = 'in_progress' RETURNING last_product_sequence").
+		WithArgs(product.ReceptionID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"last_product_sequence"}).AddRow(3))
 	mock.ExpectExec("INSERT INTO products").
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), product.Type, product.ReceptionID).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), product.Type, product.ReceptionID, 3).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ProductAdded", sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	err = storage.CreateProduct(product)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, product.ID)
 	assert.NotEmpty(t, product.DateTime)
+	assert.Equal(t, 3, product.Sequence)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateProduct_ReceptionClosed проверяет отказ в добавлении товара в приемку, закрытую после проверки
+func TestCreateProduct_ReceptionClosed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE receptions SET last_product_sequence").
+		WithArgs("reception-id", 1).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM receptions WHERE id = \\$1\\)").
+		WithArgs("reception-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	err = storage.CreateProduct(&models.Product{Type: "обувь", ReceptionID: "reception-id"})
+	assert.ErrorIs(t, err, storagepkg.ErrReceptionClosed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateProduct_ReceptionNotFound проверяет ошибку добавления товара в несуществующую приемку
+func TestCreateProduct_ReceptionNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE receptions SET last_product_sequence").
+		WithArgs("reception-id", 1).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM receptions WHERE id = \\$1\\)").
+		WithArgs("reception-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	err = storage.CreateProduct(&models.Product{Type: "обувь", ReceptionID: "reception-id"})
+	assert.ErrorIs(t, err, storagepkg.ErrNotFound)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -487,11 +580,11 @@ func TestGetProductsByReceptionID(t *testing.T) {
 	receptionID := "reception-id"
 	dateTime := time.Now()
 
-	mock.ExpectQuery("SELECT id, date_time, type, reception_id FROM products").
+	mock.ExpectQuery("SELECT id, date_time, type, reception_id, sequence FROM products").
 		WithArgs(receptionID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id"}).
-			AddRow("product-id-1", dateTime, "электроника", receptionID).
-			AddRow("product-id-2", dateTime, "одежда", receptionID))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "sequence"}).
+			AddRow("product-id-1", dateTime, "электроника", receptionID, 1).
+			AddRow("product-id-2", dateTime, "одежда", receptionID, 2))
 
 	products, err := storage.GetProductsByReceptionID(receptionID)
 	assert.NoError(t, err)
@@ -519,22 +612,39 @@ func TestDeleteLastProductInReception(t *testing.T) {
 	// Начало транзакции
 	mock.ExpectBegin()
 	
-	// Получаем ID последнего добавленного товара
-	rows := sqlmock.NewRows([]string{"id"}).AddRow(productID)
-	mock.ExpectQuery("SELECT id FROM products WHERE reception_id = \\$1 ORDER BY date_time DESC LIMIT 1").
+	// Получаем последний добавленный товар
+	rows := sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "sequence"}).
+		AddRow(productID, time.Now(), "обувь", receptionID, 2)
+	mock.ExpectQuery("SELECT id, date_time, type, reception_id, sequence FROM products WHERE reception_id = \\$1 " +
+		"ORDER BY sequence DESC, date_time DESC LIMIT 1 FOR UPDATE").
 		WithArgs(receptionID).
 		WillReturnRows(rows)
 
+	// Товар не участвует в перемещениях и не возвращен
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM transfer_products").
+		WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM returns").
+		WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
 	// Удаляем товар
 	mock.ExpectExec("DELETE FROM products WHERE id = \\$1").
 		WithArgs(productID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Событие об удалении в outbox
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ProductDeleted", productID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 		
 	// Коммит транзакции
 	mock.ExpectCommit()
 
-	err = storage.DeleteLastProductInReception(receptionID)
+	product, err := storage.DeleteLastProductInReception(receptionID)
 	assert.NoError(t, err)
+	assert.Equal(t, productID, product.ID)
+	assert.Equal(t, 2, product.Sequence)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -554,19 +664,74 @@ func TestDeleteLastProductInReception_NoProducts(t *testing.T) {
 	mock.ExpectBegin()
 	
 	// Пустой результат запроса - товаров нет
-	mock.ExpectQuery("SELECT id FROM products WHERE reception_id = \\$1 ORDER BY date_time DESC LIMIT 1").
+	mock.ExpectQuery("SELECT id, date_time, type, reception_id, sequence FROM products WHERE reception_id = \\$1").
 		WithArgs(receptionID).
 		WillReturnError(sql.ErrNoRows)
 		
 	// Откат транзакции при ошибке
 	mock.ExpectRollback()
 
-	err = storage.DeleteLastProductInReception(receptionID)
+	_, err = storage.DeleteLastProductInReception(receptionID)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "нет товаров для удаления")
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+// TestDeleteLastProductInReception_Transferred проверяет, что товар из перемещения не удаляется:
+// вместо ошибки внешнего ключа возвращается storage.ErrProductInTransfer
+func TestDeleteLastProductInReception_Transferred(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, date_time, type, reception_id, sequence FROM products WHERE reception_id = \\$1").
+		WithArgs("reception-id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "sequence"}).
+			AddRow("product-id", time.Now(), "обувь", "reception-id", 1))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM transfer_products WHERE product_id = \\$1 OR received_product_id = \\$1\\)").
+		WithArgs("product-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, err = storage.DeleteLastProductInReception("reception-id")
+	assert.ErrorIs(t, err, storagepkg.ErrProductInTransfer)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestDeleteLastProductInReception_Returned проверяет, что возвращенный товар не удаляется:
+// вместо ошибки внешнего ключа возвращается storage.ErrProductReturned
+func TestDeleteLastProductInReception_Returned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, date_time, type, reception_id, sequence FROM products WHERE reception_id = \\$1").
+		WithArgs("reception-id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "sequence"}).
+			AddRow("product-id", time.Now(), "обувь", "reception-id", 1))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM transfer_products").
+		WithArgs("product-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM returns WHERE product_id = \\$1\\)").
+		WithArgs("product-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, err = storage.DeleteLastProductInReception("reception-id")
+	assert.ErrorIs(t, err, storagepkg.ErrProductReturned)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 // TestInitDB проверяет инициализацию базы данных
 func TestInitDB(t *testing.T) {
 	db, mock, err := sqlmock.New()
@@ -582,6 +747,40 @@ func TestInitDB(t *testing.T) {
 	mock.ExpectExec("CREATE TABLE IF NOT EXISTS pvz").WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec("CREATE TABLE IF NOT EXISTS receptions").WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec("CREATE TABLE IF NOT EXISTS products").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS return_batches").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS returns").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS storage_cells").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS product_cells").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE users ADD COLUMN IF NOT EXISTS pvz_id").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS transfers").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS transfer_products").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE pvz ADD COLUMN IF NOT EXISTS status ").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE pvz ADD COLUMN IF NOT EXISTS status_reason").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE pvz ADD COLUMN IF NOT EXISTS address").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE pvz ADD COLUMN IF NOT EXISTS latitude").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE pvz ADD COLUMN IF NOT EXISTS longitude").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE pvz ADD COLUMN IF NOT EXISTS opening_hours").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE receptions ADD COLUMN IF NOT EXISTS closed_at").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE receptions ADD COLUMN IF NOT EXISTS last_product_sequence").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE products ADD COLUMN IF NOT EXISTS sequence").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE UNIQUE INDEX IF NOT EXISTS products_reception_sequence_idx").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS product_changes").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE receptions ADD COLUMN IF NOT EXISTS status_reason").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS audit_log").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX IF NOT EXISTS audit_log_entity_idx").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX IF NOT EXISTS audit_log_user_idx").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE OR REPLACE RULE audit_log_no_update").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE OR REPLACE RULE audit_log_no_delete").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS outbox").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX IF NOT EXISTS outbox_pending_idx").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS webhook_subscriptions").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS webhook_deliveries").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX IF NOT EXISTS webhook_deliveries_history_idx").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX IF NOT EXISTS idempotency_keys_expires_idx").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX IF NOT EXISTS rate_limit_buckets_updated_idx").WillReturnResult(sqlmock.NewResult(0, 0))
 
 	err = storage.InitDB()
 	assert.NoError(t, err)
@@ -603,4 +802,332 @@ func TestClose(t *testing.T) {
 	err = storage.Close()
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
-} 
\ No newline at end of file
+}
+
+// TestUpdatePVZStatus проверяет смену статуса ПВЗ
+func TestUpdatePVZStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz WHERE id = \\$1 FOR UPDATE").
+		WithArgs("pvz-id").
+		WillReturnRows(newPVZRows().AddRow("pvz-id", time.Now(), "Москва", "active", "", "", nil, nil, ""))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM receptions WHERE pvz_id = \\$1 AND status = 'in_progress'\\)").
+		WithArgs("pvz-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("UPDATE pvz SET status = \\$1, status_reason = \\$2 WHERE id = \\$3").
+		WithArgs("suspended", "ремонт", "pvz-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = storage.UpdatePVZStatus("pvz-id", "suspended", "ремонт")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestUpdatePVZStatus_OpenReception проверяет запрет приостановки ПВЗ с незакрытой приемкой
+func TestUpdatePVZStatus_OpenReception(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz WHERE id = \\$1 FOR UPDATE").
+		WithArgs("pvz-id").
+		WillReturnRows(newPVZRows().AddRow("pvz-id", time.Now(), "Москва", "active", "", "", nil, nil, ""))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM receptions").
+		WithArgs("pvz-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	err = storage.UpdatePVZStatus("pvz-id", "decommissioned", "закрытие")
+	assert.ErrorIs(t, err, storagepkg.ErrPVZHasOpenReception)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestUpdatePVZStatus_NotFound проверяет ошибку для несуществующего ПВЗ
+func TestUpdatePVZStatus_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz WHERE id = \\$1 FOR UPDATE").
+		WithArgs("pvz-id").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err = storage.UpdatePVZStatus("pvz-id", "active", "")
+	assert.ErrorIs(t, err, storagepkg.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetNearbyPVZ проверяет поиск ближайших ПВЗ
+func TestGetNearbyPVZ(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "registration_date", "city", "status", "status_reason",
+		"address", "latitude", "longitude", "opening_hours", "distance"}).
+		AddRow("pvz-id-1", now, "Москва", "active", "", "Тверская, 1", 55.757, 37.615, "09:00-21:00", 0.4).
+		AddRow("pvz-id-2", now, "Москва", "suspended", "ремонт", "Арбат, 10", 55.751, 37.593, "", 1.7)
+
+	mock.ExpectQuery("SELECT " + pvzColumns + ", distance FROM \\(").
+		WithArgs(55.755, 37.617, 5.0, 20).
+		WillReturnRows(rows)
+
+	result, err := storage.GetNearbyPVZ(55.755, 37.617, 5, 20)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "pvz-id-1", result[0].PVZ.ID)
+	assert.Equal(t, 0.4, result[0].DistanceKm)
+	assert.Equal(t, 55.757, *result[0].PVZ.Latitude)
+	assert.Equal(t, "Тверская, 1", result[0].PVZ.Address)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestUpdatePVZ проверяет изменение полей ПВЗ
+func TestUpdatePVZ(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	lat, lon := 55.757, 37.615
+	pvz := &models.PVZ{
+		ID:           "pvz-id",
+		City:         "Москва",
+		Address:      "Тверская, 1",
+		Latitude:     &lat,
+		Longitude:    &lon,
+		OpeningHours: "09:00-21:00",
+	}
+
+	query := "UPDATE pvz SET city = \\$1, address = \\$2, latitude = \\$3, longitude = \\$4, opening_hours = \\$5 WHERE id = \\$6"
+	mock.ExpectExec(query).
+		WithArgs("Москва", "Тверская, 1", &lat, &lon, "09:00-21:00", "pvz-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, storage.UpdatePVZ(pvz))
+
+	// Несуществующий ПВЗ
+	mock.ExpectExec(query).
+		WithArgs("Москва", "Тверская, 1", &lat, &lon, "09:00-21:00", "pvz-id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.ErrorIs(t, storage.UpdatePVZ(pvz), storagepkg.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetReceptionByID проверяет получение приемки по ID
+func TestGetReceptionByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}).
+		AddRow("reception-id", now, "pvz-id", "in_progress", "")
+
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions WHERE id = \\$1").
+		WithArgs("reception-id").
+		WillReturnRows(rows)
+
+	reception, err := storage.GetReceptionByID("reception-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "pvz-id", reception.PVZID)
+	assert.Equal(t, "in_progress", reception.Status)
+
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions WHERE id = \\$1").
+		WithArgs("unknown-id").
+		WillReturnError(sql.ErrNoRows)
+
+	reception, err = storage.GetReceptionByID("unknown-id")
+	assert.ErrorIs(t, err, storagepkg.ErrNotFound)
+	assert.Nil(t, reception)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetPVZList_WithCursor проверяет получение списка ПВЗ после позиции курсора
+func TestGetPVZList_WithCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+	cursor := &models.PVZCursor{RegistrationDate: now, ID: "pvz-id-5"}
+
+	// При курсоре OFFSET не используется
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz p WHERE p.status = ANY\\(\\$1\\) " +
+		"AND \\(p.registration_date, p.id\\) < \\(\\$2, \\$3\\) ORDER BY p.registration_date DESC, p.id DESC LIMIT \\$4$").
+		WithArgs(pq.Array([]string{"active"}), now, "pvz-id-5", 3).
+		WillReturnRows(newPVZRows().
+			AddRow("pvz-id-4", now, "Казань", "active", "", "", nil, nil, ""))
+
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions").
+		WithArgs("pvz-id-4").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}))
+
+	pvzList, err := storage.GetPVZList(models.PVZListFilter{
+		Statuses: []string{"active"},
+		After:    cursor,
+		Page:     1,
+		Limit:    3,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, pvzList, 1)
+	assert.Equal(t, "pvz-id-4", pvzList[0].PVZ.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCountPVZList проверяет подсчет ПВЗ под тем же фильтром, что и список
+func TestCountPVZList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+	startDate := now.AddDate(0, -1, 0)
+
+	// Курсор и пагинация на подсчет не влияют
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM pvz p WHERE EXISTS \\(.+r.date_time >= \\$1 AND r.date_time <= \\$2\\) AND p.status = ANY\\(\\$3\\)$").
+		WithArgs(startDate, now, pq.Array([]string{"active"})).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+	total, err := storage.CountPVZList(models.PVZListFilter{
+		StartDate: &startDate,
+		EndDate:   &now,
+		Statuses:  []string{"active"},
+		After:     &models.PVZCursor{RegistrationDate: now, ID: "pvz-id"},
+		Page:      3,
+		Limit:     10,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 42, total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetPVZList_OpenDateRange проверяет фильтр по дате, заданной только с одной стороны
+func TestGetPVZList_OpenDateRange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	startDate := time.Now().AddDate(0, 0, -7)
+
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz p WHERE EXISTS \\(SELECT 1 FROM receptions r " +
+		"WHERE r.pvz_id = p.id AND r.date_time >= \\$1\\) AND p.status = ANY\\(\\$2\\)").
+		WithArgs(startDate, pq.Array([]string{"active"}), 10, 0).
+		WillReturnRows(newPVZRows())
+
+	pvzList, err := storage.GetPVZList(models.PVZListFilter{
+		StartDate: &startDate,
+		Statuses:  []string{"active"},
+		Page:      1,
+		Limit:     10,
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, pvzList)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetPVZList_NestedFilters проверяет фильтры по городу, статусу приемки и типу товара
+func TestGetPVZList_NestedFilters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz p WHERE p.city = \\$1 AND EXISTS \\(SELECT 1 FROM receptions r " +
+		"WHERE r.pvz_id = p.id AND r.status = \\$2 AND EXISTS \\(SELECT 1 FROM products pr WHERE pr.reception_id = r.id AND pr.type = \\$3\\)\\) " +
+		"AND p.status = ANY\\(\\$4\\)").
+		WithArgs("Казань", "close", "обувь", pq.Array([]string{"active"}), 10, 0).
+		WillReturnRows(newPVZRows().
+			AddRow("pvz-id-1", now, "Казань", "active", "", "", nil, nil, ""))
+
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions r WHERE r.pvz_id = \\$1 AND r.status = \\$2 AND EXISTS").
+		WithArgs("pvz-id-1", "close", "обувь").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}).
+			AddRow("reception-id-1", now, "pvz-id-1", "close", ""))
+
+	mock.ExpectQuery("SELECT id, date_time, type, reception_id, sequence FROM products WHERE reception_id = \\$1 AND type = \\$2 ORDER BY sequence ASC").
+		WithArgs("reception-id-1", "обувь").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "sequence"}).
+			AddRow("product-id-1", now, "обувь", "reception-id-1", 1))
+
+	filter := models.PVZListFilter{
+		City:            "Казань",
+		ReceptionStatus: "close",
+		ProductType:     "обувь",
+		Statuses:        []string{"active"},
+		Page:            1,
+		Limit:           10,
+	}
+	pvzList, err := storage.GetPVZList(filter)
+
+	assert.NoError(t, err)
+	assert.Len(t, pvzList, 1)
+	assert.Len(t, pvzList[0].Receptions, 1)
+	assert.Len(t, pvzList[0].Receptions[0].Products, 1)
+
+	// Без товаров запросы к products не выполняются
+	filter.OmitProducts = true
+	mock.ExpectQuery("SELECT " + pvzColumns + " FROM pvz p").
+		WillReturnRows(newPVZRows().
+			AddRow("pvz-id-1", now, "Казань", "active", "", "", nil, nil, ""))
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status, status_reason FROM receptions r").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}).
+			AddRow("reception-id-1", now, "pvz-id-1", "close", ""))
+
+	pvzList, err = storage.GetPVZList(filter)
+	assert.NoError(t, err)
+	assert.Nil(t, pvzList[0].Receptions[0].Products)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
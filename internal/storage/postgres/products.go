@@ -0,0 +1,270 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// nextProductSequence резервирует count порядковых номеров товаров в открытой приемке и возвращает первый из них.
+// Счетчик хранится в строке приемки, поэтому параллельные добавления в одну приемку выполняются по очереди,
+// а добавление в приемку, закрытую после проверки в обработчике, отклоняется
+func nextProductSequence(tx *sql.Tx, receptionID string, count int) (int, error) {
+	query := `
+		UPDATE receptions SET last_product_sequence = last_product_sequence + $2
+		WHERE id = $1 AND status = 'in_progress'
+		RETURNING last_product_sequence
+	`
+	var last int
+	err := tx.QueryRow(query, receptionID, count).Scan(&last)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM receptions WHERE id = $1)`, receptionID).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, storage.ErrNotFound
+		}
+		return 0, storage.ErrReceptionClosed
+	}
+	if err != nil {
+		return 0, err
+	}
+	return last - count + 1, nil
+}
+
+// CreateProducts добавляет товары в открытую приемку в одной транзакции: либо все, либо ни одного
+func (s *PostgresStorage) CreateProducts(receptionID string, products []*models.Product) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Блокируем приемку, чтобы ее не закрыли, пока добавляются товары
+	var status string
+	err = tx.QueryRow(`SELECT status FROM receptions WHERE id = $1 FOR UPDATE`, receptionID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		return err
+	}
+	if status != "in_progress" {
+		return storage.ErrReceptionClosed
+	}
+
+	// Номера идут подряд в порядке пакета, что сохраняет порядок для удаления по LIFO
+	first, err := nextProductSequence(tx, receptionID, len(products))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `INSERT INTO products (id, date_time, type, reception_id, sequence) VALUES ($1, $2, $3, $4, $5)`
+	for i, product := range products {
+		product.ID = uuid.New().String()
+		product.DateTime = now
+		product.ReceptionID = receptionID
+		product.Sequence = first + i
+
+		if _, err := tx.Exec(query, product.ID, product.DateTime, product.Type, product.ReceptionID, product.Sequence); err != nil {
+			return err
+		}
+		if err := addEvent(tx, "ProductAdded", product.ID, product); err != nil {
+			return err
+		}
+		if err := s.audit(tx, "product.create", "product", product.ID, nil, product); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// lockProductInOpenReception блокирует товар вместе с приемкой; менять можно только товары открытой приемки
+func lockProductInOpenReception(tx *sql.Tx, productID string) (*models.Product, error) {
+	query := `
+		SELECT p.id, p.date_time, p.type, p.reception_id, p.sequence, r.status
+		FROM products p
+		INNER JOIN receptions r ON r.id = p.reception_id
+		WHERE p.id = $1
+		FOR UPDATE
+	`
+	var product models.Product
+	var status string
+	err := tx.QueryRow(query, productID).Scan(&product.ID, &product.DateTime, &product.Type, &product.ReceptionID, &product.Sequence, &status)
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != "in_progress" {
+		return nil, storage.ErrReceptionClosed
+	}
+	return &product, nil
+}
+
+// checkProductNotTransferred запрещает удалять товар, на который ссылается перемещение: отправленный товар
+// и товар, принятый по перемещению, остаются в его истории
+func checkProductNotTransferred(tx *sql.Tx, productID string) error {
+	var transferred bool
+	query := `SELECT EXISTS (SELECT 1 FROM transfer_products WHERE product_id = $1 OR received_product_id = $1)`
+	if err := tx.QueryRow(query, productID).Scan(&transferred); err != nil {
+		return err
+	}
+	if transferred {
+		return storage.ErrProductInTransfer
+	}
+	return nil
+}
+
+// checkProductNotReturned запрещает удалять возвращенный товар: на него ссылается возврат в партии
+func checkProductNotReturned(tx *sql.Tx, productID string) error {
+	var returned bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM returns WHERE product_id = $1)`, productID).Scan(&returned); err != nil {
+		return err
+	}
+	if returned {
+		return storage.ErrProductReturned
+	}
+	return nil
+}
+
+// insertProductChange записывает исправление товара в журнал в рамках транзакции
+func insertProductChange(tx *sql.Tx, change *models.ProductChange) error {
+	change.ID = uuid.New().String()
+	change.DateTime = time.Now()
+
+	query := `
+		INSERT INTO product_changes (id, date_time, product_id, reception_id, action, old_type, new_type, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := tx.Exec(query, change.ID, change.DateTime, change.ProductID, change.ReceptionID,
+		change.Action, change.OldType, change.NewType, change.UserID)
+	return err
+}
+
+// DeleteProduct удаляет товар из открытой приемки и записывает удаление в журнал
+func (s *PostgresStorage) DeleteProduct(productID, userID string) (*models.Product, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	product, err := lockProductInOpenReception(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkProductNotTransferred(tx, product.ID); err != nil {
+		return nil, err
+	}
+
+	if err := checkProductNotReturned(tx, product.ID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM products WHERE id = $1`, product.ID); err != nil {
+		return nil, err
+	}
+
+	if err := addEvent(tx, "ProductDeleted", product.ID, product); err != nil {
+		return nil, err
+	}
+	if err := s.audit(tx, "product.delete", "product", product.ID, product, nil); err != nil {
+		return nil, err
+	}
+
+	err = insertProductChange(tx, &models.ProductChange{
+		ProductID:   product.ID,
+		ReceptionID: product.ReceptionID,
+		Action:      "delete",
+		OldType:     product.Type,
+		UserID:      userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// UpdateProductType исправляет тип товара в открытой приемке и записывает исправление в журнал
+func (s *PostgresStorage) UpdateProductType(productID, productType, userID string) (*models.Product, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	product, err := lockProductInOpenReception(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE products SET type = $1 WHERE id = $2`, productType, product.ID); err != nil {
+		return nil, err
+	}
+
+	updated := *product
+	updated.Type = productType
+	if err := s.audit(tx, "product.update", "product", product.ID, product, &updated); err != nil {
+		return nil, err
+	}
+
+	err = insertProductChange(tx, &models.ProductChange{
+		ProductID:   product.ID,
+		ReceptionID: product.ReceptionID,
+		Action:      "change_type",
+		OldType:     product.Type,
+		NewType:     productType,
+		UserID:      userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// GetProductChanges получает журнал исправлений товаров приемки
+func (s *PostgresStorage) GetProductChanges(receptionID string) ([]models.ProductChange, error) {
+	query := `
+		SELECT id, date_time, product_id, reception_id, action, old_type, new_type, user_id
+		FROM product_changes
+		WHERE reception_id = $1
+		ORDER BY date_time ASC
+	`
+	rows, err := s.db.Query(query, receptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.ProductChange
+	for rows.Next() {
+		var change models.ProductChange
+		err := rows.Scan(&change.ID, &change.DateTime, &change.ProductID, &change.ReceptionID,
+			&change.Action, &change.OldType, &change.NewType, &change.UserID)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	storagepkg "github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCreateProducts проверяет добавление пакета товаров в одной транзакции с сохранением порядка
+func TestCreateProducts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM receptions WHERE id = \\$1 FOR UPDATE").
+		WithArgs("reception-id").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("in_progress"))
+	mock.ExpectQuery("UPDATE receptions SET last_product_sequence = last_product_sequence \\+ \\$2").
+		WithArgs("reception-id", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"last_product_sequence"}).AddRow(7))
+	mock.ExpectExec("INSERT INTO products").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "электроника", "reception-id", 6).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ProductAdded", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO products").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "обувь", "reception-id", 7).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ProductAdded", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	products := []*models.Product{{Type: "электроника"}, {Type: "обувь"}}
+	err = storage.CreateProducts("reception-id", products)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, products[0].ID)
+	assert.Equal(t, "reception-id", products[1].ReceptionID)
+	assert.Equal(t, 6, products[0].Sequence)
+	assert.Equal(t, 7, products[1].Sequence)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateProducts_ClosedReception проверяет, что в закрытую приемку не добавляется ни один товар
+func TestCreateProducts_ClosedReception(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM receptions WHERE id = \\$1 FOR UPDATE").
+		WithArgs("reception-id").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("close"))
+	mock.ExpectRollback()
+
+	err = storage.CreateProducts("reception-id", []*models.Product{{Type: "одежда"}})
+
+	assert.ErrorIs(t, err, storagepkg.ErrReceptionClosed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestDeleteProduct проверяет удаление произвольного товара с записью в журнал
+func TestDeleteProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT p.id, p.date_time, p.type, p.reception_id, p.sequence, r.status FROM products p " +
+		"INNER JOIN receptions r ON r.id = p.reception_id WHERE p.id = \\$1 FOR UPDATE").
+		WithArgs("product-id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "sequence", "status"}).
+			AddRow("product-id", time.Now(), "одежда", "reception-id", 2, "in_progress"))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM transfer_products").
+		WithArgs("product-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM returns").
+		WithArgs("product-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("DELETE FROM products WHERE id = \\$1").
+		WithArgs("product-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ProductDeleted", "product-id", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO product_changes").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "product-id", "reception-id", "delete", "одежда", "", "user-id").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	product, err := storage.DeleteProduct("product-id", "user-id")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "одежда", product.Type)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestUpdateProductType_ClosedReception проверяет запрет исправления товара в закрытой приемке
+func TestUpdateProductType_ClosedReception(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT p.id, p.date_time, p.type, p.reception_id, p.sequence, r.status FROM products p").
+		WithArgs("product-id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "sequence", "status"}).
+			AddRow("product-id", time.Now(), "одежда", "reception-id", 2, "close"))
+	mock.ExpectRollback()
+
+	_, err = storage.UpdateProductType("product-id", "обувь", "user-id")
+
+	assert.ErrorIs(t, err, storagepkg.ErrReceptionClosed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
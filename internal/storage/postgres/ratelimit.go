@@ -0,0 +1,39 @@
+package postgres
+
+import "time"
+
+// TakeRateLimitToken пополняет корзину на прошедшее время и забирает из нее токен одним запросом,
+// чтобы реплики не могли одновременно выдать один и тот же токен
+func (s *PostgresStorage) TakeRateLimitToken(key string, rate float64, burst int, now time.Time) (float64, bool, error) {
+	// Часы реплик могут расходиться: время назад не уменьшает корзину
+	refilled := `LEAST($2::DOUBLE PRECISION, b.tokens + GREATEST(EXTRACT(EPOCH FROM ($4 - b.updated_at)), 0) * $3)`
+	query := `
+		INSERT INTO rate_limit_buckets AS b (bucket_key, tokens, allowed, updated_at)
+		VALUES ($1, $2::DOUBLE PRECISION - 1, TRUE, $4)
+		ON CONFLICT (bucket_key) DO UPDATE
+		SET tokens = CASE WHEN ` + refilled + ` >= 1 THEN ` + refilled + ` - 1 ELSE ` + refilled + ` END,
+			allowed = ` + refilled + ` >= 1,
+			updated_at = GREATEST(b.updated_at, $4)
+		RETURNING tokens, allowed
+	`
+	var tokens float64
+	var allowed bool
+	if err := s.db.QueryRow(query, key, burst, rate, now).Scan(&tokens, &allowed); err != nil {
+		return 0, false, err
+	}
+	return tokens, allowed, nil
+}
+
+// DeleteIdleRateLimitBuckets удаляет корзины, не использованные с idleSince
+func (s *PostgresStorage) DeleteIdleRateLimitBuckets(idleSince time.Time) (int, error) {
+	result, err := s.db.Exec(`DELETE FROM rate_limit_buckets WHERE updated_at < $1`, idleSince)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTakeRateLimitToken проверяет атомарное пополнение и расход корзины
+func TestTakeRateLimitToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("INSERT INTO rate_limit_buckets AS b .* ON CONFLICT \\(bucket_key\\) DO UPDATE .* RETURNING tokens, allowed").
+		WithArgs("user:1|employee| ", 10, 5.0, now).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "allowed"}).AddRow(0.4, false))
+
+	tokens, allowed, err := storage.TakeRateLimitToken("user:1|employee| ", 5, 10, now)
+
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 0.4, tokens)
+
+	mock.ExpectExec("DELETE FROM rate_limit_buckets WHERE updated_at < \\$1").
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	deleted, err := storage.DeleteIdleRateLimitBuckets(now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReopenReception проверяет повторное открытие последней закрытой приемки
+func TestReopenReception(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	query := "UPDATE receptions SET status = 'in_progress', closed_at = NULL, status_reason = '' WHERE id = \\$1 AND status = 'close' " +
+		"AND id = \\(SELECT r.id FROM receptions r WHERE r.pvz_id = receptions.pvz_id ORDER BY r.date_time DESC LIMIT 1\\) " +
+		"AND EXISTS \\(SELECT 1 FROM pvz p WHERE p.id = receptions.pvz_id AND p.status = 'active'\\) " +
+		"AND NOT EXISTS \\(SELECT 1 FROM transfers t WHERE t.reception_id = receptions.id\\)"
+
+	mock.ExpectExec(query).
+		WithArgs("reception-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, storage.ReopenReception("reception-id"))
+
+	// Приемка не последняя или не закрыта
+	mock.ExpectExec(query).
+		WithArgs("old-reception-id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	err = storage.ReopenReception("old-reception-id")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "последнюю закрытую приемку")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCancelReception проверяет отмену незакрытой приемки с причиной и освобождение ячеек ее товаров
+func TestCancelReception(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	query := "WITH released AS \\( DELETE FROM product_cells .* WHERE r.id = \\$1 AND r.status = 'in_progress' \\) \\) " +
+		"UPDATE receptions SET status = 'cancelled', status_reason = \\$2 WHERE id = \\$1 AND status = 'in_progress'"
+
+	mock.ExpectExec(query).
+		WithArgs("reception-id", "открыта по ошибке").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, storage.CancelReception("reception-id", "открыта по ошибке"))
+
+	mock.ExpectExec(query).
+		WithArgs("closed-id", "открыта по ошибке").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.Error(t, storage.CancelReception("closed-id", "открыта по ошибке"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// CreateReturnBatch открывает новую партию возвратов для ПВЗ
+func (s *PostgresStorage) CreateReturnBatch(batch *models.ReturnBatch) error {
+	batch.ID = uuid.New().String()
+	batch.DateTime = time.Now()
+	batch.Status = "in_progress"
+
+	// Проверяем, нет ли незакрытой партии
+	lastBatch, err := s.GetLastReturnBatchByPVZID(batch.PVZID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if lastBatch != nil && lastBatch.Status == "in_progress" {
+		return fmt.Errorf("уже есть незакрытая партия возвратов для этого ПВЗ")
+	}
+
+	query := `INSERT INTO return_batches (id, date_time, pvz_id, status) VALUES ($1, $2, $3, $4)`
+	return s.mutate(func(q querier) error {
+		if _, err := q.Exec(query, batch.ID, batch.DateTime, batch.PVZID, batch.Status); err != nil {
+			return err
+		}
+		return s.audit(q, "return_batch.create", "return_batch", batch.ID, nil, batch)
+	})
+}
+
+// GetLastReturnBatchByPVZID получает последнюю партию возвратов для ПВЗ
+func (s *PostgresStorage) GetLastReturnBatchByPVZID(pvzID string) (*models.ReturnBatch, error) {
+	query := `
+		SELECT id, date_time, pvz_id, status
+		FROM return_batches
+		WHERE pvz_id = $1
+		ORDER BY date_time DESC
+		LIMIT 1
+	`
+	var batch models.ReturnBatch
+	err := s.db.QueryRow(query, pvzID).Scan(&batch.ID, &batch.DateTime, &batch.PVZID, &batch.Status)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// CloseReturnBatch закрывает партию возвратов, после чего она ожидает курьера
+func (s *PostgresStorage) CloseReturnBatch(batchID string) error {
+	query := `UPDATE return_batches SET status = 'close' WHERE id = $1 AND status = 'in_progress'`
+	return s.mutate(func(q querier) error {
+		result, err := q.Exec(query, batchID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("партия возвратов уже закрыта или не существует")
+		}
+
+		before := map[string]string{"status": "in_progress"}
+		after := map[string]string{"status": "close"}
+		return s.audit(q, "return_batch.close", "return_batch", batchID, before, after)
+	})
+}
+
+// CreateReturn регистрирует возврат в партии
+func (s *PostgresStorage) CreateReturn(ret *models.Return) error {
+	ret.ID = uuid.New().String()
+	ret.DateTime = time.Now()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Повторный возврат одного и того же товара недопустим
+	if ret.ProductID != "" {
+		var exists bool
+		err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM returns WHERE product_id = $1)`, ret.ProductID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return storage.ErrProductReturned
+		}
+
+		if err := checkProductNotTransferred(tx, ret.ProductID); err != nil {
+			return err
+		}
+
+		// Возвращенный товар освобождает ячейку хранения
+		if _, err := tx.Exec(`DELETE FROM product_cells WHERE product_id = $1`, ret.ProductID); err != nil {
+			return err
+		}
+	}
+
+	query := `INSERT INTO returns (id, date_time, product_id, type, reason, batch_id) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = tx.Exec(query, ret.ID, ret.DateTime, nullString(ret.ProductID), ret.Type, ret.Reason, ret.BatchID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.audit(tx, "return.create", "return", ret.ID, nil, ret); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetReturnBatches получает партии возвратов с указанным статусом вместе с возвратами
+func (s *PostgresStorage) GetReturnBatches(status string) ([]models.ReturnBatchWithReturns, error) {
+	query := `
+		SELECT id, date_time, pvz_id, status
+		FROM return_batches
+		WHERE status = $1
+		ORDER BY date_time ASC
+	`
+	rows, err := s.db.Query(query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []models.ReturnBatch
+	for rows.Next() {
+		var batch models.ReturnBatch
+		if err := rows.Scan(&batch.ID, &batch.DateTime, &batch.PVZID, &batch.Status); err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.ReturnBatchWithReturns, 0, len(batches))
+	for _, batch := range batches {
+		returns, err := s.getReturnsByBatchID(batch.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, models.ReturnBatchWithReturns{
+			Batch:   batch,
+			Returns: returns,
+		})
+	}
+
+	return result, nil
+}
+
+// getReturnsByBatchID получает возвраты партии
+func (s *PostgresStorage) getReturnsByBatchID(batchID string) ([]models.Return, error) {
+	query := `
+		SELECT id, date_time, COALESCE(product_id::text, ''), type, reason, batch_id
+		FROM returns
+		WHERE batch_id = $1
+		ORDER BY date_time ASC
+	`
+	rows, err := s.db.Query(query, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returns []models.Return
+	for rows.Next() {
+		var ret models.Return
+		if err := rows.Scan(&ret.ID, &ret.DateTime, &ret.ProductID, &ret.Type, &ret.Reason, &ret.BatchID); err != nil {
+			return nil, err
+		}
+		returns = append(returns, ret)
+	}
+
+	return returns, nil
+}
+
+// nullString преобразует пустую строку в NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
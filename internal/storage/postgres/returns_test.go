@@ -0,0 +1,218 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	storagepkg "github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCreateReturnBatch проверяет открытие партии возвратов
+func TestCreateReturnBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	batch := &models.ReturnBatch{
+		PVZID: "pvz-id",
+	}
+
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status FROM return_batches").
+		WithArgs(batch.PVZID).
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectExec("INSERT INTO return_batches").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), batch.PVZID, "in_progress").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = storage.CreateReturnBatch(batch)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, batch.ID)
+	assert.Equal(t, "in_progress", batch.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateReturnBatch_ExistingOpenBatch проверяет невозможность открыть вторую партию возвратов
+func TestCreateReturnBatch_ExistingOpenBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	batch := &models.ReturnBatch{
+		PVZID: "pvz-id",
+	}
+
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status FROM return_batches").
+		WithArgs(batch.PVZID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
+			AddRow("batch-id", time.Now(), batch.PVZID, "in_progress"))
+
+	err = storage.CreateReturnBatch(batch)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "уже есть незакрытая партия возвратов")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateReturn_AlreadyReturned проверяет запрет повторного возврата товара
+func TestCreateReturn_AlreadyReturned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	ret := &models.Return{
+		ProductID: "product-id",
+		Type:      "обувь",
+		BatchID:   "batch-id",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(ret.ProductID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	err = storage.CreateReturn(ret)
+	assert.ErrorIs(t, err, storagepkg.ErrProductReturned)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateReturn_Linked проверяет, что возврат товара освобождает его ячейку в той же транзакции
+func TestCreateReturn_Linked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	ret := &models.Return{
+		ProductID: "product-id",
+		Type:      "обувь",
+		BatchID:   "batch-id",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM returns").
+		WithArgs(ret.ProductID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM transfer_products").
+		WithArgs(ret.ProductID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("DELETE FROM product_cells WHERE product_id = \\$1").
+		WithArgs(ret.ProductID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO returns").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sql.NullString{String: ret.ProductID, Valid: true}, ret.Type, ret.Reason, ret.BatchID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = storage.CreateReturn(ret)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateReturn_Transferred проверяет запрет возврата товара, участвующего в перемещении
+func TestCreateReturn_Transferred(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	ret := &models.Return{
+		ProductID: "product-id",
+		Type:      "обувь",
+		BatchID:   "batch-id",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM returns").
+		WithArgs(ret.ProductID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM transfer_products").
+		WithArgs(ret.ProductID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	err = storage.CreateReturn(ret)
+	assert.ErrorIs(t, err, storagepkg.ErrProductInTransfer)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateReturn_Unlinked проверяет регистрацию возврата без привязки к товару
+func TestCreateReturn_Unlinked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	ret := &models.Return{
+		Type:    "одежда",
+		Reason:  "не подошел размер",
+		BatchID: "batch-id",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO returns").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sql.NullString{}, ret.Type, ret.Reason, ret.BatchID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = storage.CreateReturn(ret)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetReturnBatches проверяет получение партий возвратов, ожидающих курьера
+func TestGetReturnBatches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT id, date_time, pvz_id, status FROM return_batches").
+		WithArgs("close").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
+			AddRow("batch-id", now, "pvz-id", "close"))
+
+	mock.ExpectQuery("SELECT id, date_time, COALESCE\\(product_id::text, ''\\), type, reason, batch_id FROM returns").
+		WithArgs("batch-id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "product_id", "type", "reason", "batch_id"}).
+			AddRow("return-id", now, "", "обувь", "брак", "batch-id"))
+
+	batches, err := storage.GetReturnBatches("close")
+	assert.NoError(t, err)
+	assert.Len(t, batches, 1)
+	assert.Equal(t, "batch-id", batches[0].Batch.ID)
+	assert.Len(t, batches[0].Returns, 1)
+	assert.Equal(t, "брак", batches[0].Returns[0].Reason)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
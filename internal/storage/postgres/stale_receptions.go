@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// staleReceptionsLockKey ключ advisory-блокировки закрытия зависших приемок
+const staleReceptionsLockKey = 4204201
+
+// CloseStaleReceptions закрывает приемки, открытые раньше openedBefore.
+// Блокировка берется на время транзакции: если ее держит другая реплика, ничего не закрывается
+func (s *PostgresStorage) CloseStaleReceptions(openedBefore, closedAt time.Time, reason string) ([]models.Reception, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRow(`SELECT pg_try_advisory_xact_lock($1)`, staleReceptionsLockKey).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, nil
+	}
+
+	query := `
+		UPDATE receptions SET status = 'close', closed_at = $2, status_reason = $3
+		WHERE status = 'in_progress' AND date_time < $1
+		RETURNING id, date_time, pvz_id, status, status_reason
+	`
+	rows, err := tx.Query(query, openedBefore, closedAt, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	var closed []models.Reception
+	for rows.Next() {
+		var reception models.Reception
+		if err := rows.Scan(&reception.ID, &reception.DateTime, &reception.PVZID, &reception.Status, &reception.StatusReason); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		closed = append(closed, reception)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Запрос выбирал только открытые приемки, поэтому состояние до закрытия восстанавливается без повторного чтения
+	for i := range closed {
+		before := closed[i]
+		before.Status = "in_progress"
+		before.StatusReason = ""
+		if err := addEvent(tx, "ReceptionClosed", closed[i].ID, &closed[i]); err != nil {
+			return nil, err
+		}
+		if err := s.audit(tx, "reception.auto_close", "reception", closed[i].ID, &before, &closed[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return closed, nil
+}
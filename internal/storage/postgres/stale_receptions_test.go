@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCloseStaleReceptions проверяет закрытие зависших приемок под advisory-блокировкой
+func TestCloseStaleReceptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+	openedBefore := now.Add(-12 * time.Hour)
+	reason := "закрыта автоматически"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT pg_try_advisory_xact_lock\\(\\$1\\)").
+		WithArgs(staleReceptionsLockKey).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+	mock.ExpectQuery("UPDATE receptions SET status = 'close', closed_at = \\$2, status_reason = \\$3 "+
+		"WHERE status = 'in_progress' AND date_time < \\$1 RETURNING id, date_time, pvz_id, status, status_reason").
+		WithArgs(openedBefore, now, reason).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status", "status_reason"}).
+			AddRow("reception-id", openedBefore.Add(-time.Hour), "pvz-id", "close", reason))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ReceptionClosed", "reception-id", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	closed, err := storage.CloseStaleReceptions(openedBefore, now, reason)
+
+	assert.NoError(t, err)
+	assert.Len(t, closed, 1)
+	assert.Equal(t, "reception-id", closed[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCloseStaleReceptions_Locked проверяет, что реплика без блокировки ничего не закрывает
+func TestCloseStaleReceptions_Locked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT pg_try_advisory_xact_lock\\(\\$1\\)").
+		WithArgs(staleReceptionsLockKey).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+	mock.ExpectRollback()
+
+	closed, err := storage.CloseStaleReceptions(time.Now(), time.Now(), "закрыта автоматически")
+
+	assert.NoError(t, err)
+	assert.Empty(t, closed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// GetReceptionStats считает показатели приемок за период с группировкой по ПВЗ, городу и периоду
+func (s *PostgresStorage) GetReceptionStats(filter models.StatsFilter) ([]models.ReceptionStats, error) {
+	pvzExpr, cityExpr, periodExpr := `''`, `''`, `NULL::timestamp`
+	var groupBy []string
+
+	switch filter.GroupBy {
+	case "pvz":
+		pvzExpr, cityExpr = `p.id::text`, `p.city`
+		groupBy = append(groupBy, `p.id`, `p.city`)
+	case "city":
+		cityExpr = `p.city`
+		groupBy = append(groupBy, `p.city`)
+	}
+
+	// Период подставляется в запрос только из проверенного списка значений
+	switch filter.Period {
+	case "day", "week", "month":
+		periodExpr = `date_trunc('` + filter.Period + `', r.date_time)`
+		groupBy = append(groupBy, periodExpr)
+	}
+
+	var args queryArgs
+	// Отмененные приемки остаются в истории, но не учитываются в показателях
+	conditions := []string{`r.status <> 'cancelled'`}
+	if filter.StartDate != nil {
+		conditions = append(conditions, `r.date_time >= `+args.add(filter.StartDate))
+	}
+	if filter.EndDate != nil {
+		conditions = append(conditions, `r.date_time <= `+args.add(filter.EndDate))
+	}
+
+	// Товары сначала агрегируются по приемкам, чтобы средние считались по приемкам, а не по товарам
+	query := `
+		SELECT ` + pvzExpr + `, ` + cityExpr + `, ` + periodExpr + `,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE r.status = 'close'),
+			COALESCE(SUM(pc.electronics), 0),
+			COALESCE(SUM(pc.clothes), 0),
+			COALESCE(SUM(pc.shoes), 0),
+			COALESCE(AVG(COALESCE(pc.total, 0)), 0),
+			COALESCE(AVG(EXTRACT(EPOCH FROM r.closed_at - r.date_time)) FILTER (WHERE r.closed_at IS NOT NULL), 0)
+		FROM receptions r
+		INNER JOIN pvz p ON p.id = r.pvz_id
+		LEFT JOIN (
+			SELECT reception_id,
+				COUNT(*) AS total,
+				COUNT(*) FILTER (WHERE type = 'электроника') AS electronics,
+				COUNT(*) FILTER (WHERE type = 'одежда') AS clothes,
+				COUNT(*) FILTER (WHERE type = 'обувь') AS shoes
+			FROM products
+			GROUP BY reception_id
+		) pc ON pc.reception_id = r.id
+		WHERE ` + strings.Join(conditions, " AND ")
+	if len(groupBy) > 0 {
+		query += `
+		GROUP BY ` + strings.Join(groupBy, ", ") + `
+		ORDER BY ` + strings.Join(groupBy, ", ")
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.ReceptionStats{}
+	for rows.Next() {
+		var stats models.ReceptionStats
+		var period sql.NullTime
+		var electronics, clothes, shoes int
+		err := rows.Scan(&stats.PVZID, &stats.City, &period, &stats.ReceptionsOpened, &stats.ReceptionsClosed,
+			&electronics, &clothes, &shoes, &stats.AvgProductsPerReception, &stats.AvgReceptionDurationSeconds)
+		if err != nil {
+			return nil, err
+		}
+
+		// Без группировки агрегат по пустой выборке дает одну строку с нулями
+		if stats.ReceptionsOpened == 0 {
+			continue
+		}
+
+		if period.Valid {
+			stats.Period = &period.Time
+		}
+		stats.ProductsByType = map[string]int{
+			"электроника": electronics,
+			"одежда":      clothes,
+			"обувь":       shoes,
+		}
+		result = append(result, stats)
+	}
+
+	return result, rows.Err()
+}
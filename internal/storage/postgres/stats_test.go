@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetReceptionStats проверяет расчет показателей с группировкой по городу и неделе
+func TestGetReceptionStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	startDate := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	week := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"pvz_id", "city", "period", "opened", "closed",
+		"electronics", "clothes", "shoes", "avg_products", "avg_duration"}).
+		AddRow("", "Казань", week, 3, 2, 4, 1, 0, 1.67, 3600.0)
+
+	mock.ExpectQuery("SELECT '', p.city, date_trunc\\('week', r.date_time\\),.+FROM receptions r INNER JOIN pvz p.+" +
+		"WHERE r.status <> 'cancelled' AND r.date_time >= \\$1 GROUP BY p.city, date_trunc\\('week', r.date_time\\)").
+		WithArgs(startDate).
+		WillReturnRows(rows)
+
+	stats, err := storage.GetReceptionStats(models.StatsFilter{
+		StartDate: &startDate,
+		GroupBy:   "city",
+		Period:    "week",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "Казань", stats[0].City)
+	assert.Equal(t, week, *stats[0].Period)
+	assert.Equal(t, 3, stats[0].ReceptionsOpened)
+	assert.Equal(t, 2, stats[0].ReceptionsClosed)
+	assert.Equal(t, 4, stats[0].ProductsByType["электроника"])
+	assert.Equal(t, 3600.0, stats[0].AvgReceptionDurationSeconds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetReceptionStats_Empty проверяет, что пустая выборка без группировки не дает строку с нулями
+func TestGetReceptionStats_Empty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	rows := sqlmock.NewRows([]string{"pvz_id", "city", "period", "opened", "closed",
+		"electronics", "clothes", "shoes", "avg_products", "avg_duration"}).
+		AddRow("", "", nil, 0, 0, 0, 0, 0, 0.0, 0.0)
+
+	mock.ExpectQuery("SELECT '', '', NULL::timestamp").WillReturnRows(rows)
+
+	stats, err := storage.GetReceptionStats(models.StatsFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
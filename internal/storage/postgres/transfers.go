@@ -0,0 +1,274 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// CreateTransfer создает перемещение товаров из одного ПВЗ в другой
+func (s *PostgresStorage) CreateTransfer(transfer *models.Transfer) error {
+	transfer.ID = uuid.New().String()
+	transfer.DateTime = time.Now()
+	transfer.Status = "created"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Каждый товар должен находиться в ПВЗ-отправителе и еще не участвовать в перемещениях
+	query := `
+		SELECT r.pvz_id, EXISTS (SELECT 1 FROM transfer_products tp WHERE tp.product_id = p.id)
+		FROM products p
+		INNER JOIN receptions r ON r.id = p.reception_id
+		WHERE p.id = $1
+	`
+	for _, productID := range transfer.ProductIDs {
+		var pvzID string
+		var transferred bool
+		err := tx.QueryRow(query, productID).Scan(&pvzID, &transferred)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("товар %s не найден", productID)
+			}
+			return err
+		}
+		if pvzID != transfer.SourcePVZID {
+			return fmt.Errorf("товар %s не находится в ПВЗ-отправителе", productID)
+		}
+		if transferred {
+			return fmt.Errorf("товар %s уже перемещался", productID)
+		}
+	}
+
+	query = `INSERT INTO transfers (id, date_time, source_pvz_id, destination_pvz_id, status) VALUES ($1, $2, $3, $4, $5)`
+	_, err = tx.Exec(query, transfer.ID, transfer.DateTime, transfer.SourcePVZID, transfer.DestinationPVZID, transfer.Status)
+	if err != nil {
+		return err
+	}
+
+	for i, productID := range transfer.ProductIDs {
+		_, err := tx.Exec(`INSERT INTO transfer_products (transfer_id, product_id, position) VALUES ($1, $2, $3)`, transfer.ID, productID, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.audit(tx, "transfer.create", "transfer", transfer.ID, nil, transfer); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetTransferByID получает перемещение по ID
+func (s *PostgresStorage) GetTransferByID(id string) (*models.Transfer, error) {
+	query := `
+		SELECT id, date_time, source_pvz_id, destination_pvz_id, status, COALESCE(reception_id::text, '')
+		FROM transfers
+		WHERE id = $1
+	`
+	var transfer models.Transfer
+	err := s.db.QueryRow(query, id).Scan(&transfer.ID, &transfer.DateTime, &transfer.SourcePVZID,
+		&transfer.DestinationPVZID, &transfer.Status, &transfer.ReceptionID)
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT product_id FROM transfer_products WHERE transfer_id = $1 ORDER BY position ASC`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfer.ProductIDs = []string{}
+	for rows.Next() {
+		var productID string
+		if err := rows.Scan(&productID); err != nil {
+			return nil, err
+		}
+		transfer.ProductIDs = append(transfer.ProductIDs, productID)
+	}
+
+	return &transfer, rows.Err()
+}
+
+// GetTransferByReceptionID получает перемещение, принятое приемкой
+func (s *PostgresStorage) GetTransferByReceptionID(receptionID string) (*models.Transfer, error) {
+	var id string
+	err := s.db.QueryRow(`SELECT id FROM transfers WHERE reception_id = $1`, receptionID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTransferByID(id)
+}
+
+// ShipTransfer отправляет перемещение: товары покидают ячейки ПВЗ-отправителя
+func (s *PostgresStorage) ShipTransfer(transferID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE transfers SET status = 'in_transit' WHERE id = $1 AND status = 'created'`, transferID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("перемещение уже отправлено или не существует")
+	}
+
+	query := `DELETE FROM product_cells WHERE product_id IN (SELECT product_id FROM transfer_products WHERE transfer_id = $1)`
+	if _, err := tx.Exec(query, transferID); err != nil {
+		return err
+	}
+
+	before := map[string]string{"status": "created"}
+	after := map[string]string{"status": "in_transit"}
+	if err := s.audit(tx, "transfer.ship", "transfer", transferID, before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReceiveTransfer принимает перемещение в ПВЗ назначения как закрытую приемку. Для подписчиков прием выглядит
+// как добавление товаров (ProductAdded) и закрытие приемки (ReceptionClosed)
+func (s *PostgresStorage) ReceiveTransfer(transferID string) (*models.Reception, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var destinationPVZID, status string
+	err = tx.QueryRow(`SELECT destination_pvz_id, status FROM transfers WHERE id = $1 FOR UPDATE`, transferID).
+		Scan(&destinationPVZID, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("перемещение не найдено")
+		}
+		return nil, err
+	}
+
+	if status != "in_transit" {
+		return nil, fmt.Errorf("перемещение еще не отправлено или уже принято")
+	}
+
+	// Приемка перемещения не должна вклиниваться в открытую приемку ПВЗ
+	var hasOpenReception bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM receptions WHERE pvz_id = $1 AND status = 'in_progress')`, destinationPVZID).
+		Scan(&hasOpenReception)
+	if err != nil {
+		return nil, err
+	}
+	if hasOpenReception {
+		return nil, fmt.Errorf("в ПВЗ назначения есть незакрытая приемка")
+	}
+
+	// Сначала читаем товары целиком: в рамках транзакции нельзя выполнять запросы при открытом курсоре
+	query := `
+		SELECT tp.product_id, p.type
+		FROM transfer_products tp
+		INNER JOIN products p ON p.id = tp.product_id
+		WHERE tp.transfer_id = $1
+		ORDER BY tp.position ASC
+	`
+	rows, err := tx.Query(query, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	var products []models.Product
+	for rows.Next() {
+		var product models.Product
+		if err := rows.Scan(&product.ID, &product.Type); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	reception := &models.Reception{
+		ID:       uuid.New().String(),
+		DateTime: time.Now(),
+		PVZID:    destinationPVZID,
+		Status:   "close",
+	}
+	// Приемка создается уже закрытой, поэтому счетчик номеров товаров сразу выставляется по их количеству
+	query = `INSERT INTO receptions (id, date_time, pvz_id, status, last_product_sequence) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.Exec(query, reception.ID, reception.DateTime, reception.PVZID, reception.Status, len(products)); err != nil {
+		return nil, err
+	}
+	if err := s.audit(tx, "reception.create", "reception", reception.ID, nil, reception); err != nil {
+		return nil, err
+	}
+
+	for i, product := range products {
+		received := &models.Product{
+			ID:          uuid.New().String(),
+			DateTime:    time.Now(),
+			Type:        product.Type,
+			ReceptionID: reception.ID,
+			Sequence:    i + 1,
+		}
+		query := `INSERT INTO products (id, date_time, type, reception_id, sequence) VALUES ($1, $2, $3, $4, $5)`
+		if _, err := tx.Exec(query, received.ID, received.DateTime, received.Type, received.ReceptionID, received.Sequence); err != nil {
+			return nil, err
+		}
+
+		query = `UPDATE transfer_products SET received_product_id = $1 WHERE transfer_id = $2 AND product_id = $3`
+		if _, err := tx.Exec(query, received.ID, transferID, product.ID); err != nil {
+			return nil, err
+		}
+
+		if err := addEvent(tx, "ProductAdded", received.ID, received); err != nil {
+			return nil, err
+		}
+		if err := s.audit(tx, "product.create", "product", received.ID, nil, received); err != nil {
+			return nil, err
+		}
+	}
+
+	query = `UPDATE transfers SET status = 'received', reception_id = $1 WHERE id = $2`
+	if _, err := tx.Exec(query, reception.ID, transferID); err != nil {
+		return nil, err
+	}
+
+	if err := addEvent(tx, "ReceptionClosed", reception.ID, reception); err != nil {
+		return nil, err
+	}
+	after := map[string]string{"status": "received", "receptionId": reception.ID}
+	if err := s.audit(tx, "transfer.receive", "transfer", transferID, nil, after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return reception, nil
+}
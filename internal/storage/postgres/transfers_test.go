@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	storagepkg "github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestShipTransfer проверяет отправку перемещения и освобождение ячеек
+func TestShipTransfer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE transfers SET status = 'in_transit' WHERE id = \\$1 AND status = 'created'").
+		WithArgs("transfer-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM product_cells WHERE product_id IN").
+		WithArgs("transfer-id").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err = storage.ShipTransfer("transfer-id")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestReceiveTransfer проверяет прием перемещения как закрытой приемки
+func TestReceiveTransfer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT destination_pvz_id, status FROM transfers WHERE id = \\$1 FOR UPDATE").
+		WithArgs("transfer-id").
+		WillReturnRows(sqlmock.NewRows([]string{"destination_pvz_id", "status"}).AddRow("pvz-id", "in_transit"))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM receptions").
+		WithArgs("pvz-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("SELECT tp.product_id, p.type FROM transfer_products tp").
+		WithArgs("transfer-id").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "type"}).AddRow("product-id", "обувь"))
+	mock.ExpectExec("INSERT INTO receptions \\(id, date_time, pvz_id, status, last_product_sequence\\)").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "pvz-id", "close", 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO products").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "обувь", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE transfer_products SET received_product_id").
+		WithArgs(sqlmock.AnyArg(), "transfer-id", "product-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ProductAdded", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE transfers SET status = 'received'").
+		WithArgs(sqlmock.AnyArg(), "transfer-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "ReceptionClosed", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	reception, err := storage.ReceiveTransfer("transfer-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "pvz-id", reception.PVZID)
+	assert.Equal(t, "close", reception.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestReceiveTransfer_OpenReception проверяет запрет приема перемещения при открытой приемке
+func TestReceiveTransfer_OpenReception(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT destination_pvz_id, status FROM transfers WHERE id = \\$1 FOR UPDATE").
+		WithArgs("transfer-id").
+		WillReturnRows(sqlmock.NewRows([]string{"destination_pvz_id", "status"}).AddRow("pvz-id", "in_transit"))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM receptions").
+		WithArgs("pvz-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, err = storage.ReceiveTransfer("transfer-id")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "незакрытая приемка")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetTransferByReceptionID_NotFound проверяет ErrNotFound для приемки, не принятой по перемещению
+func TestGetTransferByReceptionID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectQuery("SELECT id FROM transfers WHERE reception_id = \\$1").
+		WithArgs("reception-id").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = storage.GetTransferByReceptionID("reception-id")
+	assert.ErrorIs(t, err, storagepkg.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
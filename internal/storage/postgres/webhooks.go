@@ -0,0 +1,283 @@
+package postgres
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// webhookSubscriptionColumns колонки подписки в порядке сканирования scanWebhookSubscription
+const webhookSubscriptionColumns = `id, date_time, url, secret, event_types, pvz_id, city`
+
+// webhookDeliveryColumns колонки доставки в порядке сканирования scanWebhookDelivery
+const webhookDeliveryColumns = `id, date_time, subscription_id, event_id, event_type, payload, status, attempts, ` +
+	`next_attempt_at, last_error, response_status, delivered_at`
+
+// scanWebhookSubscription сканирует строку с колонками webhookSubscriptionColumns
+func scanWebhookSubscription(row rowScanner) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	var eventTypes pq.StringArray
+	err := row.Scan(&subscription.ID, &subscription.DateTime, &subscription.URL, &subscription.Secret,
+		&eventTypes, &subscription.PVZID, &subscription.City)
+	if err != nil {
+		return nil, err
+	}
+	subscription.EventTypes = []string(eventTypes)
+	return &subscription, nil
+}
+
+// scanWebhookDelivery сканирует строку с колонками webhookDeliveryColumns
+func scanWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload []byte
+	var deliveredAt sql.NullTime
+	err := row.Scan(&delivery.ID, &delivery.DateTime, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType,
+		&payload, &delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastError,
+		&delivery.ResponseStatus, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = payload
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return &delivery, nil
+}
+
+// CreateWebhookSubscription создает подписку на события
+func (s *PostgresStorage) CreateWebhookSubscription(subscription *models.WebhookSubscription) error {
+	subscription.ID = uuid.New().String()
+	subscription.DateTime = time.Now()
+	query := `
+		INSERT INTO webhook_subscriptions (id, date_time, url, secret, event_types, pvz_id, city)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	return s.mutate(func(q querier) error {
+		_, err := q.Exec(query, subscription.ID, subscription.DateTime, subscription.URL, subscription.Secret,
+			pq.Array(subscription.EventTypes), subscription.PVZID, subscription.City)
+		if err != nil {
+			return err
+		}
+		return s.audit(q, "webhook.create", "webhook", subscription.ID, nil, withoutSecret(subscription))
+	})
+}
+
+// withoutSecret возвращает копию подписки без ключа подписи для журнала аудита
+func withoutSecret(subscription *models.WebhookSubscription) *models.WebhookSubscription {
+	copied := *subscription
+	copied.Secret = ""
+	return &copied
+}
+
+// GetWebhookSubscriptions получает все подписки в порядке создания
+func (s *PostgresStorage) GetWebhookSubscriptions() ([]models.WebhookSubscription, error) {
+	rows, err := s.db.Query(`SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions ORDER BY date_time, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.WebhookSubscription
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// GetWebhookSubscriptionByID получает подписку по ID
+func (s *PostgresStorage) GetWebhookSubscriptionByID(id string) (*models.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+	subscription, err := scanWebhookSubscription(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	return subscription, err
+}
+
+// DeleteWebhookSubscription удаляет подписку вместе с историей доставок
+func (s *PostgresStorage) DeleteWebhookSubscription(id string) error {
+	return s.mutate(func(q querier) error {
+		query := `DELETE FROM webhook_subscriptions WHERE id = $1 RETURNING ` + webhookSubscriptionColumns
+		subscription, err := scanWebhookSubscription(q.QueryRow(query, id))
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return s.audit(q, "webhook.delete", "webhook", id, withoutSecret(subscription), nil)
+	})
+}
+
+// CreateWebhookDeliveries создает доставки; повторная доставка того же события по подписке пропускается
+func (s *PostgresStorage) CreateWebhookDeliveries(deliveries []*models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webhook_deliveries (id, date_time, subscription_id, event_id, event_type, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+	now := time.Now()
+	for _, delivery := range deliveries {
+		delivery.ID = uuid.New().String()
+		delivery.DateTime = now
+		delivery.Status = "pending"
+		delivery.NextAttemptAt = now
+		_, err := tx.Exec(query, delivery.ID, delivery.DateTime, delivery.SubscriptionID, delivery.EventID,
+			delivery.EventType, []byte(delivery.Payload), delivery.Status, delivery.NextAttemptAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClaimWebhookDeliveries выбирает наступившие доставки и переносит их следующую попытку на now+lease.
+// Строки, заблокированные другой репликой, пропускаются
+func (s *PostgresStorage) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns
+	rows, err := s.db.Query(query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// UpdateWebhookDelivery сохраняет результат попытки доставки. Запись выполняется, только если next_attempt_at
+// по-прежнему равно аренде leasedUntil: после истечения аренды доставку могли выбрать снова или отправить повторно
+func (s *PostgresStorage) UpdateWebhookDelivery(delivery *models.WebhookDelivery, leasedUntil time.Time) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, response_status = $6, delivered_at = $7
+		WHERE id = $1 AND next_attempt_at = $8
+	`
+	return s.mutate(func(q querier) error {
+		result, err := q.Exec(query, delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt,
+			delivery.LastError, delivery.ResponseStatus, delivery.DeliveredAt, leasedUntil)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return storage.ErrNotFound
+		}
+
+		return s.audit(q, "webhook_delivery.update", "webhook_delivery", delivery.ID, nil, delivery)
+	})
+}
+
+// RedeliverWebhookDelivery возвращает доставленную или недоставленную доставку в pending с новым циклом попыток.
+// Статус проверяется в самом обновлении, чтобы не перезаписать доставку, которую уже отправляет планировщик
+func (s *PostgresStorage) RedeliverWebhookDelivery(id string) (*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE id = $1 AND status IN ('dead', 'delivered')
+		RETURNING ` + webhookDeliveryColumns
+	var delivery *models.WebhookDelivery
+	err := s.mutate(func(q querier) error {
+		var err error
+		delivery, err = scanWebhookDelivery(q.QueryRow(query, id))
+		if err == sql.ErrNoRows {
+			return storage.ErrWebhookDeliveryPending
+		}
+		if err != nil {
+			return err
+		}
+		return s.audit(q, "webhook_delivery.redeliver", "webhook_delivery", id, nil, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// GetWebhookDeliveryByID получает доставку по ID
+func (s *PostgresStorage) GetWebhookDeliveryByID(id string) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	delivery, err := scanWebhookDelivery(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	return delivery, err
+}
+
+// GetWebhookDeliveries получает доставки по подписке и статусу, новые первыми
+func (s *PostgresStorage) GetWebhookDeliveries(filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	var args queryArgs
+	conditions := []string{`TRUE`}
+	if filter.SubscriptionID != "" {
+		conditions = append(conditions, `subscription_id = `+args.add(filter.SubscriptionID))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, `status = `+args.add(filter.Status))
+	}
+
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY date_time DESC, id DESC
+		LIMIT ` + args.add(filter.Limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	storagepkg "github.com/aventhis/avito_pvz_service/internal/storage"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCreateWebhookSubscription проверяет сохранение подписки с типами событий
+func TestCreateWebhookSubscription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	subscription := &models.WebhookSubscription{
+		URL:        "https://partner.example/hooks",
+		Secret:     "secret-key-0123456789",
+		EventTypes: []string{"ReceptionClosed"},
+		City:       "Москва",
+	}
+
+	mock.ExpectExec("INSERT INTO webhook_subscriptions").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), subscription.URL, subscription.Secret,
+			pq.Array(subscription.EventTypes), "", "Москва").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = storage.CreateWebhookSubscription(subscription)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, subscription.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestDeleteWebhookSubscription_NotFound проверяет удаление несуществующей подписки
+func TestDeleteWebhookSubscription_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectQuery("DELETE FROM webhook_subscriptions WHERE id = \\$1 RETURNING").
+		WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "url", "secret", "event_types", "pvz_id", "city"}))
+
+	err = storage.DeleteWebhookSubscription("unknown")
+
+	assert.ErrorIs(t, err, storagepkg.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateWebhookDeliveries проверяет, что повтор доставки того же события пропускается
+func TestCreateWebhookDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO webhook_deliveries .* ON CONFLICT \\(subscription_id, event_id\\) DO NOTHING").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "subscription-id", "event-id", "ReceptionClosed",
+			[]byte(`{"id":"event-id"}`), "pending", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	delivery := &models.WebhookDelivery{SubscriptionID: "subscription-id", EventID: "event-id", EventType: "ReceptionClosed",
+		Payload: json.RawMessage(`{"id":"event-id"}`)}
+	err = storage.CreateWebhookDeliveries([]*models.WebhookDelivery{delivery})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", delivery.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestClaimWebhookDeliveries проверяет выбор наступивших доставок с пропуском заблокированных строк
+func TestClaimWebhookDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	now := time.Now()
+	deliveredAt := now.Add(-time.Hour)
+	rows := sqlmock.NewRows([]string{"id", "date_time", "subscription_id", "event_id", "event_type", "payload", "status",
+		"attempts", "next_attempt_at", "last_error", "response_status", "delivered_at"}).
+		AddRow("delivery-id", now, "subscription-id", "event-id", "ReceptionClosed", []byte(`{"id":"event-id"}`), "pending",
+			2, now.Add(time.Minute), "таймаут", 0, nil).
+		AddRow("delivery-2", now, "subscription-id", "event-2", "ProductAdded", []byte(`{}`), "pending",
+			0, now.Add(time.Minute), "", 0, deliveredAt)
+	mock.ExpectQuery("UPDATE webhook_deliveries SET next_attempt_at = \\$2 WHERE id IN \\( SELECT id FROM webhook_deliveries "+
+		"WHERE status = 'pending' AND next_attempt_at <= \\$1 ORDER BY next_attempt_at LIMIT \\$3 FOR UPDATE SKIP LOCKED \\)").
+		WithArgs(now, now.Add(time.Minute), 50).
+		WillReturnRows(rows)
+
+	deliveries, err := storage.ClaimWebhookDeliveries(now, time.Minute, 50)
+
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Nil(t, deliveries[0].DeliveredAt)
+	assert.Equal(t, deliveredAt, *deliveries[1].DeliveredAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestUpdateWebhookDelivery_LeaseExpired проверяет, что результат попытки не записывается после истечения аренды
+func TestUpdateWebhookDelivery_LeaseExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	leasedUntil := time.Now()
+	delivery := &models.WebhookDelivery{ID: "delivery-id", Status: "delivered", Attempts: 1, NextAttemptAt: leasedUntil}
+	mock.ExpectExec("UPDATE webhook_deliveries SET status = \\$2, .* WHERE id = \\$1 AND next_attempt_at = \\$8").
+		WithArgs("delivery-id", "delivered", 1, leasedUntil, "", 0, sqlmock.AnyArg(), leasedUntil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = storage.UpdateWebhookDelivery(delivery, leasedUntil)
+
+	assert.ErrorIs(t, err, storagepkg.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRedeliverWebhookDelivery_Pending проверяет, что ожидающая доставка не отправляется повторно
+func TestRedeliverWebhookDelivery_Pending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка при создании mock DB: %v", err)
+	}
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+
+	mock.ExpectQuery("UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = now\\(\\) " +
+		"WHERE id = \\$1 AND status IN \\('dead', 'delivered'\\) RETURNING").
+		WithArgs("delivery-id").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = storage.RedeliverWebhookDelivery("delivery-id")
+
+	assert.ErrorIs(t, err, storagepkg.ErrWebhookDeliveryPending)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,13 +1,45 @@
 package storage
 
 import (
+	"errors"
 	"time"
 
 	"github.com/aventhis/avito_pvz_service/internal/models"
 )
 
+// ErrNotFound возвращается, когда запрошенный объект не существует
+var ErrNotFound = errors.New("объект не найден")
+
+// ErrReceptionClosed возвращается при изменении товаров в закрытой приемке
+var ErrReceptionClosed = errors.New("приемка уже закрыта")
+
+// ErrPVZHasOpenReception возвращается при приостановке или закрытии ПВЗ с незакрытой приемкой
+var ErrPVZHasOpenReception = errors.New("в ПВЗ есть незакрытая приемка")
+
+// ErrCellNotFound возвращается при размещении товара в несуществующей ячейке
+var ErrCellNotFound = errors.New("ячейка не найдена")
+
+// ErrCellOtherPVZ возвращается при размещении товара в ячейке другого ПВЗ
+var ErrCellOtherPVZ = errors.New("ячейка принадлежит другому ПВЗ")
+
+// ErrCellFull возвращается при размещении товара в заполненной ячейке
+var ErrCellFull = errors.New("ячейка заполнена")
+
+// ErrProductInTransfer возвращается при удалении товара, который отправлен или принят по перемещению
+var ErrProductInTransfer = errors.New("товар участвует в перемещении")
+
+// ErrProductReturned возвращается при повторном возврате или удалении уже возвращенного товара
+var ErrProductReturned = errors.New("товар уже возвращен")
+
+// ErrWebhookDeliveryPending возвращается при повторной отправке доставки, которая уже ожидает отправки
+var ErrWebhookDeliveryPending = errors.New("доставка уже ожидает отправки")
+
 // Storage интерфейс для работы с хранилищем данных
 type Storage interface {
+	// WithActor возвращает хранилище, которое записывает изменения от имени actor в журнал аудита
+	// в той же транзакции, что и само изменение
+	WithActor(actor models.Actor) Storage
+
 	// Пользователи
 	CreateUser(user *models.User) error
 	GetUserByEmail(email string) (*models.User, error)
@@ -15,15 +47,95 @@ type Storage interface {
 	// ПВЗ
 	CreatePVZ(pvz *models.PVZ) error
 	GetPVZByID(id string) (*models.PVZ, error)
-	GetPVZList(startDate, endDate *time.Time, page, limit int) ([]models.PVZListItem, error)
+	UpdatePVZ(pvz *models.PVZ) error
+	GetPVZList(filter models.PVZListFilter) ([]models.PVZListItem, error)
+	CountPVZList(filter models.PVZListFilter) (int, error)
+	UpdatePVZStatus(id, status, reason string) error
+	GetNearbyPVZ(lat, lon, radiusKm float64, limit int) ([]models.PVZWithDistance, error)
 
 	// Приемки
 	CreateReception(reception *models.Reception) error
 	GetLastReceptionByPVZID(pvzID string) (*models.Reception, error)
+	GetReceptionByID(id string) (*models.Reception, error)
 	CloseReception(receptionID string) error
+	ReopenReception(receptionID string) error
+	CancelReception(receptionID, reason string) error
+	CloseStaleReceptions(openedBefore, closedAt time.Time, reason string) ([]models.Reception, error)
+	GetReceptionStats(filter models.StatsFilter) ([]models.ReceptionStats, error)
 
 	// Товары
+	// CreateProduct добавляет товар в приемку; если задан product.CellID, товар в той же транзакции
+	// размещается в ячейке, и при ошибке размещения не сохраняется
 	CreateProduct(product *models.Product) error
+	CreateProducts(receptionID string, products []*models.Product) error
 	GetProductsByReceptionID(receptionID string) ([]models.Product, error)
-	DeleteLastProductInReception(receptionID string) error
-} 
\ No newline at end of file
+	DeleteLastProductInReception(receptionID string) (*models.Product, error)
+	GetProductByID(id string) (*models.Product, error)
+	DeleteProduct(productID, userID string) (*models.Product, error)
+	UpdateProductType(productID, productType, userID string) (*models.Product, error)
+	GetProductChanges(receptionID string) ([]models.ProductChange, error)
+
+	// Возвраты
+	CreateReturnBatch(batch *models.ReturnBatch) error
+	GetLastReturnBatchByPVZID(pvzID string) (*models.ReturnBatch, error)
+	CloseReturnBatch(batchID string) error
+	CreateReturn(ret *models.Return) error
+	GetReturnBatches(status string) ([]models.ReturnBatchWithReturns, error)
+
+	// Ячейки хранения
+	CreateCell(cell *models.StorageCell) error
+	GetCellByID(id string) (*models.StorageCell, error)
+	GetCellsByPVZID(pvzID string) ([]models.StorageCell, error)
+	SuggestCell(pvzID string) (*models.StorageCell, error)
+	AssignProductToCell(productID, cellID string) error
+	GetProductCell(productID string) (*models.StorageCell, error)
+
+	// Перемещения между ПВЗ
+	CreateTransfer(transfer *models.Transfer) error
+	GetTransferByID(id string) (*models.Transfer, error)
+	// GetTransferByReceptionID получает перемещение, принятое приемкой; если такого нет, возвращает ErrNotFound
+	GetTransferByReceptionID(receptionID string) (*models.Transfer, error)
+	ShipTransfer(transferID string) error
+	ReceiveTransfer(transferID string) (*models.Reception, error)
+
+	// Журнал аудита
+	GetAuditLog(filter models.AuditFilter) ([]models.AuditEntry, error)
+
+	// Исходящие события (outbox); события записываются в той же транзакции, что и изменение
+	// ClaimPendingEvents выбирает неопубликованные события, не захваченные другой репликой, и скрывает их
+	// от других реплик до now+lease
+	ClaimPendingEvents(now time.Time, lease time.Duration, limit int) ([]models.Event, error)
+	MarkEventPublished(id string, publishedAt time.Time) error
+	// MarkEventFailed записывает неудачную попытку публикации; после maxAttempts попыток событие переносится
+	// в недоставленные и больше не выбирается. Возвращает true, если событие перенесено
+	MarkEventFailed(id, reason string, now time.Time, maxAttempts int) (bool, error)
+
+	// Вебхуки
+	CreateWebhookSubscription(subscription *models.WebhookSubscription) error
+	GetWebhookSubscriptions() ([]models.WebhookSubscription, error)
+	GetWebhookSubscriptionByID(id string) (*models.WebhookSubscription, error)
+	DeleteWebhookSubscription(id string) error
+	// CreateWebhookDeliveries пропускает доставки, уже созданные для той же пары подписки и события
+	CreateWebhookDeliveries(deliveries []*models.WebhookDelivery) error
+	// ClaimWebhookDeliveries выбирает доставки, попытка которых наступила к now, и откладывает их на lease,
+	// чтобы другие реплики не отправили их одновременно
+	ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	// UpdateWebhookDelivery сохраняет результат попытки, только пока доставка удерживается арендой leasedUntil,
+	// выданной ClaimWebhookDeliveries; иначе возвращает ErrNotFound
+	UpdateWebhookDelivery(delivery *models.WebhookDelivery, leasedUntil time.Time) error
+	// RedeliverWebhookDelivery возвращает доставленную или недоставленную доставку в pending с новым циклом попыток;
+	// для ожидающей доставки возвращает ErrWebhookDeliveryPending
+	RedeliverWebhookDelivery(id string) (*models.WebhookDelivery, error)
+	GetWebhookDeliveryByID(id string) (*models.WebhookDelivery, error)
+	GetWebhookDeliveries(filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+
+	// Ключи идемпотентности
+	// ReserveIdempotencyKey резервирует ключ пользователя за выполняющимся запросом до record.LockedUntil. Если
+	// действующий ключ уже есть, возвращает его запись и false; истекший ключ и ключ, резерв которого истек
+	// до сохранения ответа, резервируются заново
+	ReserveIdempotencyKey(record *models.IdempotencyRecord) (*models.IdempotencyRecord, bool, error)
+	// CompleteIdempotencyKey и DeleteIdempotencyKey меняют ключ, только если он еще зарезервирован за record
+	CompleteIdempotencyKey(record *models.IdempotencyRecord) error
+	DeleteIdempotencyKey(record *models.IdempotencyRecord) error
+	DeleteExpiredIdempotencyKeys(now time.Time) (int, error)
+}
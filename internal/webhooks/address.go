@@ -0,0 +1,66 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenAddress адрес подписки указывает на внутреннюю сеть
+var ErrForbiddenAddress = errors.New("адрес вебхука указывает на внутреннюю сеть")
+
+// IsPublicIP сообщает, можно ли отправлять вебхуки на адрес: запрещены локальные, частные, link-local
+// (в том числе адрес метаданных облака 169.254.169.254) и групповые адреса
+func IsPublicIP(ip net.IP) bool {
+	return ip != nil &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// CheckURL проверяет, что адрес подписки не указывает на внутреннюю сеть. Имя узла разрешается, и запрещенным
+// считается имя, у которого есть хотя бы один запрещенный адрес; неразрешимое имя проверяется при отправке
+func CheckURL(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	host := target.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// publicOnlyControl отклоняет соединение с запрещенным адресом. Проверяется адрес, к которому выполняется
+// подключение после разрешения имени, поэтому подмена DNS-записи после создания подписки не помогает
+func publicOnlyControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicIP(net.ParseIP(host)) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
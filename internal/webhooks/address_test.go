@@ -0,0 +1,27 @@
+package webhooks
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestIsPublicIP проверяет запрет адресов внутренней сети
+func TestIsPublicIP(t *testing.T) {
+	for _, address := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "fe80::1", "0.0.0.0", "224.0.0.1"} {
+		assert.False(t, IsPublicIP(net.ParseIP(address)), address)
+	}
+	for _, address := range []string{"8.8.8.8", "2a00:1450:4010:c05::64"} {
+		assert.True(t, IsPublicIP(net.ParseIP(address)), address)
+	}
+}
+
+// TestCheckURL проверяет отклонение адресов подписки, указывающих на внутреннюю сеть
+func TestCheckURL(t *testing.T) {
+	for _, rawURL := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest/meta-data", "https://[::1]/hook", "http://localhost/hook"} {
+		assert.ErrorIs(t, CheckURL(context.Background(), rawURL), ErrForbiddenAddress, rawURL)
+	}
+	assert.NoError(t, CheckURL(context.Background(), "https://93.184.216.34/hook"))
+}
@@ -0,0 +1,136 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage"
+)
+
+// DispatchStore хранилище подписок и данных для фильтрации событий по ПВЗ
+type DispatchStore interface {
+	GetWebhookSubscriptions() ([]models.WebhookSubscription, error)
+	CreateWebhookDeliveries(deliveries []*models.WebhookDelivery) error
+	GetPVZByID(id string) (*models.PVZ, error)
+	GetReceptionByID(id string) (*models.Reception, error)
+}
+
+// Dispatcher создает доставки события по подходящим подпискам; сами запросы отправляет фоновая задача.
+// Реализует events.Publisher и подключается к ретранслятору outbox
+type Dispatcher struct {
+	store DispatchStore
+}
+
+// NewDispatcher создает распределитель событий по подпискам
+func NewDispatcher(store DispatchStore) *Dispatcher {
+	return &Dispatcher{store: store}
+}
+
+// Publish создает доставки события для подписок, чьи фильтры ему соответствуют
+func (d *Dispatcher) Publish(ctx context.Context, event models.Event) error {
+	subscriptions, err := d.store.GetWebhookSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// ПВЗ события определяется только при наличии подписок с фильтром по ПВЗ или городу
+	var pvz *models.PVZ
+	pvzResolved := false
+
+	var deliveries []*models.WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !matchesEventType(subscription, event.Type) {
+			continue
+		}
+		if subscription.PVZID != "" || subscription.City != "" {
+			if !pvzResolved {
+				if pvz, err = d.eventPVZ(event); err != nil {
+					return err
+				}
+				pvzResolved = true
+			}
+			if pvz == nil || !matchesPVZ(subscription, pvz) {
+				continue
+			}
+		}
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        body,
+		})
+	}
+
+	return d.store.CreateWebhookDeliveries(deliveries)
+}
+
+// matchesEventType проверяет, подписана ли подписка на тип события
+func matchesEventType(subscription models.WebhookSubscription, eventType string) bool {
+	if len(subscription.EventTypes) == 0 {
+		return true
+	}
+	for _, subscribed := range subscription.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPVZ проверяет фильтры подписки по ПВЗ и городу
+func matchesPVZ(subscription models.WebhookSubscription, pvz *models.PVZ) bool {
+	if subscription.PVZID != "" && subscription.PVZID != pvz.ID {
+		return false
+	}
+	if subscription.City != "" && subscription.City != pvz.City {
+		return false
+	}
+	return true
+}
+
+// eventPVZ находит ПВЗ, к которому относится событие; nil, если ПВЗ уже не существует
+func (d *Dispatcher) eventPVZ(event models.Event) (*models.PVZ, error) {
+	var pvzID string
+	switch event.Type {
+	case "PVZCreated":
+		var pvz models.PVZ
+		if err := json.Unmarshal(event.Payload, &pvz); err != nil {
+			return nil, err
+		}
+		return &pvz, nil
+	case "ReceptionOpened", "ReceptionClosed":
+		var reception models.Reception
+		if err := json.Unmarshal(event.Payload, &reception); err != nil {
+			return nil, err
+		}
+		pvzID = reception.PVZID
+	case "ProductAdded", "ProductDeleted":
+		var product models.Product
+		if err := json.Unmarshal(event.Payload, &product); err != nil {
+			return nil, err
+		}
+		reception, err := d.store.GetReceptionByID(product.ReceptionID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		pvzID = reception.PVZID
+	default:
+		return nil, nil
+	}
+
+	pvz, err := d.store.GetPVZByID(pvzID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	return pvz, err
+}
@@ -0,0 +1,81 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/aventhis/avito_pvz_service/internal/storage/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// dispatchPending передает распределителю все неопубликованные события хранилища
+func dispatchPending(t *testing.T, storage *mock.MockStorage, dispatcher *Dispatcher) {
+	pending, _ := storage.ClaimPendingEvents(time.Now(), time.Minute, 100)
+	for _, event := range pending {
+		assert.NoError(t, dispatcher.Publish(context.Background(), event))
+		storage.MarkEventPublished(event.ID, event.DateTime)
+	}
+}
+
+// TestDispatcher_Filters проверяет отбор подписок по типу события, ПВЗ и городу
+func TestDispatcher_Filters(t *testing.T) {
+	storage := mock.New()
+	dispatcher := NewDispatcher(storage)
+
+	moscow := &models.PVZ{City: "Москва"}
+	storage.CreatePVZ(moscow)
+	kazan := &models.PVZ{City: "Казань"}
+	storage.CreatePVZ(kazan)
+
+	all := &models.WebhookSubscription{URL: "http://all.example"}
+	closedInMoscow := &models.WebhookSubscription{URL: "http://moscow.example", EventTypes: []string{"ReceptionClosed"}, City: "Москва"}
+	kazanPVZ := &models.WebhookSubscription{URL: "http://kazan.example", PVZID: kazan.ID}
+	storage.CreateWebhookSubscription(all)
+	storage.CreateWebhookSubscription(closedInMoscow)
+	storage.CreateWebhookSubscription(kazanPVZ)
+
+	for _, pvz := range []*models.PVZ{moscow, kazan} {
+		reception := &models.Reception{PVZID: pvz.ID}
+		storage.CreateReception(reception)
+		storage.CreateProduct(&models.Product{Type: "обувь", ReceptionID: reception.ID})
+		storage.CloseReception(reception.ID)
+	}
+
+	dispatchPending(t, storage, dispatcher)
+
+	// Все события: 2 ПВЗ, по приемке с товаром и закрытием в каждом
+	allDeliveries, _ := storage.GetWebhookDeliveries(models.WebhookDeliveryFilter{SubscriptionID: all.ID})
+	assert.Len(t, allDeliveries, 8)
+
+	moscowDeliveries, _ := storage.GetWebhookDeliveries(models.WebhookDeliveryFilter{SubscriptionID: closedInMoscow.ID})
+	assert.Len(t, moscowDeliveries, 1)
+	assert.Equal(t, "ReceptionClosed", moscowDeliveries[0].EventType)
+	assert.Equal(t, "pending", moscowDeliveries[0].Status)
+
+	// События ПВЗ в Казани, включая товар, ПВЗ которого определяется через приемку
+	kazanDeliveries, _ := storage.GetWebhookDeliveries(models.WebhookDeliveryFilter{SubscriptionID: kazanPVZ.ID})
+	var types []string
+	for _, delivery := range kazanDeliveries {
+		types = append(types, delivery.EventType)
+	}
+	assert.ElementsMatch(t, []string{"PVZCreated", "ReceptionOpened", "ProductAdded", "ReceptionClosed"}, types)
+}
+
+// TestDispatcher_Duplicate проверяет, что повторная публикация события не создает вторую доставку
+func TestDispatcher_Duplicate(t *testing.T) {
+	storage := mock.New()
+	dispatcher := NewDispatcher(storage)
+
+	subscription := &models.WebhookSubscription{URL: "http://partner.example"}
+	storage.CreateWebhookSubscription(subscription)
+	storage.CreatePVZ(&models.PVZ{City: "Москва"})
+
+	pending, _ := storage.ClaimPendingEvents(time.Now(), time.Minute, 10)
+	assert.NoError(t, dispatcher.Publish(context.Background(), pending[0]))
+	assert.NoError(t, dispatcher.Publish(context.Background(), pending[0]))
+
+	deliveries, _ := storage.GetWebhookDeliveries(models.WebhookDeliveryFilter{SubscriptionID: subscription.ID})
+	assert.Len(t, deliveries, 1)
+}
@@ -0,0 +1,64 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+)
+
+// Sender отправляет подписанные доставки на адреса подписок
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender создает отправителя с таймаутом запроса timeout. Соединения с адресами внутренней сети отклоняются
+func NewSender(timeout time.Duration) *Sender {
+	return newSender(timeout, publicOnlyControl)
+}
+
+// newSender создает отправителя, проверяющего адрес каждого соединения функцией control
+func newSender(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *Sender {
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Запрос идет напрямую, чтобы проверялся адрес получателя, а не прокси
+	transport.Proxy = nil
+	return &Sender{client: &http.Client{Timeout: timeout, Transport: transport}}
+}
+
+// Send отправляет доставку и возвращает код ответа; ответ вне 2xx считается ошибкой
+func (s *Sender) Send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", delivery.EventID)
+	req.Header.Set("X-Event-Type", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("вебхук ответил статусом %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
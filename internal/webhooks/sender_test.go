@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aventhis/avito_pvz_service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSender_Send проверяет отправку подписанного тела доставки
+func TestSender_Send(t *testing.T) {
+	now := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	var verified bool
+	var eventType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		verified = Verify("secret-key-0123456789", timestamp, body, r.Header.Get(SignatureHeader))
+		eventType = r.Header.Get("X-Event-Type")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	subscription := &models.WebhookSubscription{URL: server.URL, Secret: "secret-key-0123456789"}
+	delivery := &models.WebhookDelivery{ID: "delivery-id", EventID: "event-id", EventType: "ReceptionClosed",
+		Payload: json.RawMessage(`{"id":"event-id","type":"ReceptionClosed"}`)}
+
+	status, err := newSender(time.Second, nil).Send(context.Background(), subscription, delivery, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.True(t, verified)
+	assert.Equal(t, "ReceptionClosed", eventType)
+}
+
+// TestSender_ErrorStatus проверяет, что ответ вне 2xx возвращается с ошибкой
+func TestSender_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	subscription := &models.WebhookSubscription{URL: server.URL, Secret: "secret-key-0123456789"}
+	status, err := newSender(time.Second, nil).Send(context.Background(), subscription, &models.WebhookDelivery{}, time.Now())
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, status)
+}
+
+// TestSender_ForbiddenAddress проверяет отказ в соединении с адресом внутренней сети
+func TestSender_ForbiddenAddress(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	subscription := &models.WebhookSubscription{URL: server.URL, Secret: "secret-key-0123456789"}
+	_, err := NewSender(time.Second).Send(context.Background(), subscription, &models.WebhookDelivery{}, time.Now())
+
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+	assert.False(t, called)
+}
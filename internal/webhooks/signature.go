@@ -0,0 +1,41 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	// SignatureHeader заголовок с подписью тела запроса
+	SignatureHeader = "X-Webhook-Signature"
+
+	// TimestampHeader заголовок со временем отправки (Unix-секунды), входящим в подпись
+	TimestampHeader = "X-Webhook-Timestamp"
+)
+
+// Sign подписывает тело запроса: sha256=<hex HMAC-SHA256 от "timestamp.body" с ключом secret>.
+// Время входит в подпись, чтобы получатель мог отклонять повтор старых запросов
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись запроса без утечки времени сравнения
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// GenerateSecret создает случайный ключ подписи
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
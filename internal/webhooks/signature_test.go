@@ -0,0 +1,29 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSignVerify проверяет, что подпись зависит от ключа, времени и тела запроса
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"event-id"}`)
+	signature := Sign("secret-key-0123456789", 1714640400, body)
+
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+	assert.True(t, Verify("secret-key-0123456789", 1714640400, body, signature))
+	assert.False(t, Verify("other-key-0123456789", 1714640400, body, signature))
+	assert.False(t, Verify("secret-key-0123456789", 1714640401, body, signature))
+	assert.False(t, Verify("secret-key-0123456789", 1714640400, []byte(`{"id":"other"}`), signature))
+}
+
+// TestGenerateSecret проверяет создание разных случайных ключей
+func TestGenerateSecret(t *testing.T) {
+	first, err := GenerateSecret()
+	assert.NoError(t, err)
+	second, _ := GenerateSecret()
+
+	assert.Len(t, first, 64)
+	assert.NotEqual(t, first, second)
+}